@@ -0,0 +1,220 @@
+// Package apierror is the error format of every API response:
+//
+//	{"error": "Validation failed", "code": "validation_failed",
+//	 "fields": [{"field": "password", "code": "min", "message": "password must be at least 6 characters"}],
+//	 "request_id": "..."}
+//
+// "error" stays a readable message, as clients have always shown it, in the
+// locale of the request (see i18n); "code" and the field codes are stable for
+// programs. Causes of server errors are logged, never sent.
+package apierror
+
+import (
+	"errors"
+	"net/http"
+	"scandata/i18n"
+	"scandata/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Codes of Error.Code
+const (
+	CodeBadRequest       = "bad_request"
+	CodeValidation       = "validation_failed"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeConflict         = "conflict"
+	CodeDuplicate        = "duplicate"
+	CodePayloadTooLarge  = "payload_too_large"
+	CodeRateLimited      = "rate_limited"
+	CodeInternal         = "internal_error"
+	CodeUnavailable      = "unavailable"
+	CodeRouteNotFound    = "route_not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+)
+
+// FieldError is a problem with one request field or query parameter
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+
+	text i18n.Text
+}
+
+// Field builds a FieldError whose message is translated in the response
+func Field(field, code, format string, args ...interface{}) FieldError {
+	text := i18n.Textf(format, args...)
+	return FieldError{Field: field, Code: code, Message: text.String(), text: text}
+}
+
+type Error struct {
+	Status    int          `json:"-"`
+	Code      string       `json:"code"`
+	Message   string       `json:"error"`
+	Fields    []FieldError `json:"fields,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	// Details is extra data for the code, e.g. the scan a duplicate repeats
+	Details map[string]interface{} `json:"details,omitempty"`
+
+	cause error
+	text  i18n.Text
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.Message + ": " + e.cause.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// WithDetail adds a value to Details
+func (e *Error) WithDetail(key string, value interface{}) *Error {
+	if e.Details == nil {
+		e.Details = make(map[string]interface{})
+	}
+	e.Details[key] = value
+	return e
+}
+
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message, text: i18n.Text{Format: message}}
+}
+
+// Newf is New with a message format; the format is the catalogue key
+func Newf(status int, code, format string, args ...interface{}) *Error {
+	text := i18n.Textf(format, args...)
+	return &Error{Status: status, Code: code, Message: text.String(), text: text}
+}
+
+func BadRequest(message string) *Error {
+	return New(http.StatusBadRequest, CodeBadRequest, message)
+}
+
+func Unauthorized(message string) *Error {
+	return New(http.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+func Forbidden(message string) *Error {
+	return New(http.StatusForbidden, CodeForbidden, message)
+}
+
+func NotFound(message string) *Error {
+	return New(http.StatusNotFound, CodeNotFound, message)
+}
+
+func Conflict(message string) *Error {
+	return New(http.StatusConflict, CodeConflict, message)
+}
+
+// Duplicate reports a unique field that is already taken
+func Duplicate(field, message string) *Error {
+	e := New(http.StatusConflict, CodeDuplicate, message)
+	e.Fields = []FieldError{Field(field, "unique", message)}
+	return e
+}
+
+// Invalid reports one invalid field or query parameter
+func Invalid(field, message string) *Error {
+	return Validation(Field(field, "invalid", message))
+}
+
+// Invalidf is Invalid with a message format
+func Invalidf(field, format string, args ...interface{}) *Error {
+	return Validation(Field(field, "invalid", format, args...))
+}
+
+// Validation reports every invalid field at once
+func Validation(fields ...FieldError) *Error {
+	e := New(http.StatusBadRequest, CodeValidation, "Validation failed")
+	if len(fields) == 1 {
+		e.Message, e.text = fields[0].Message, fields[0].text
+	}
+	e.Fields = fields
+	return e
+}
+
+// notFound is implemented by errors of a row that does not exist, such as
+// repository.ErrNotFound
+type notFound interface {
+	NotFound() bool
+}
+
+// unavailable is implemented by errors of a backend that cannot be reached,
+// such as repository.ErrUnavailable
+type unavailable interface {
+	Unavailable() bool
+}
+
+// Internal is a server error; cause is logged with the request. A database
+// that cannot be reached gives 503 so clients know to retry.
+func Internal(message string, cause error) *Error {
+	e := New(http.StatusInternalServerError, CodeInternal, message)
+	var u unavailable
+	if errors.As(cause, &u) && u.Unavailable() {
+		e = New(http.StatusServiceUnavailable, CodeUnavailable, "Service temporarily unavailable, try again later")
+	}
+	e.cause = cause
+	return e
+}
+
+// Abort writes err as the response and stops the handler chain. An error
+// that is not an *Error becomes a 500.
+func Abort(c *gin.Context, err error) {
+	var e *Error
+	if !errors.As(err, &e) {
+		e = Internal("Internal server error", err)
+	}
+
+	ctx := c.Request.Context()
+	locale := i18n.FromContext(ctx)
+	response := *e
+	response.Message = translate(e.text, locale, e.Message)
+	response.Fields = make([]FieldError, len(e.Fields))
+	for i, f := range e.Fields {
+		f.Message = translate(f.text, locale, f.Message)
+		response.Fields[i] = f
+	}
+	response.RequestID = logging.RequestID(ctx)
+	if e.Status >= http.StatusInternalServerError && e.cause != nil {
+		// Shown in the access log of the request
+		c.Error(e.cause)
+	}
+	c.AbortWithStatusJSON(e.Status, &response)
+}
+
+// translate renders text in locale; messages built without a text, e.g.
+// from configuration errors, stay as they are
+func translate(text i18n.Text, locale i18n.Locale, message string) string {
+	if text.Format == "" {
+		return message
+	}
+	return text.In(locale)
+}
+
+// Lookup maps the error of loading one row: a missing row gives 404 with
+// message, anything else a server error
+func Lookup(err error, message string) *Error {
+	var nf notFound
+	if errors.As(err, &nf) && nf.NotFound() {
+		return NotFound(message)
+	}
+	return Internal("Failed to load data", err)
+}
+
+// NoRoute answers requests for a path without a route
+func NoRoute(c *gin.Context) {
+	Abort(c, New(http.StatusNotFound, CodeRouteNotFound, "Route not found"))
+}
+
+// NoMethod answers requests for a route that exists with another method.
+// It needs gin.Engine.HandleMethodNotAllowed.
+func NoMethod(c *gin.Context) {
+	Abort(c, New(http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed"))
+}
@@ -0,0 +1,95 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// Report fields by their JSON name rather than the Go struct field
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			switch name {
+			case "-":
+				return ""
+			case "":
+				return f.Name
+			}
+			return name
+		})
+	}
+}
+
+// Binding turns an error from c.ShouldBind* into a validation error.
+// Messages name the JSON field and never the Go types behind it.
+func Binding(err error) *Error {
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var tooLarge *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &validationErrs):
+		fields := make([]FieldError, len(validationErrs))
+		for i, fe := range validationErrs {
+			fields[i] = fieldError(fe)
+		}
+		return Validation(fields...)
+	case errors.As(err, &typeErr):
+		return Validation(Field(typeErr.Field, "type", typeFormat(typeErr.Type), typeErr.Field))
+	case errors.As(err, &tooLarge):
+		return Newf(http.StatusRequestEntityTooLarge, CodePayloadTooLarge,
+			"Request body is larger than %d bytes", tooLarge.Limit)
+	case errors.Is(err, io.EOF):
+		return BadRequest("Request body is required")
+	}
+	return BadRequest("Request body is not valid JSON")
+}
+
+func fieldError(fe validator.FieldError) FieldError {
+	field, code, param := fe.Field(), fe.Tag(), fe.Param()
+	length := fe.Kind() == reflect.String || fe.Kind() == reflect.Slice || fe.Kind() == reflect.Map
+
+	switch code {
+	case "required":
+		return Field(field, code, "%s is required", field)
+	case "min":
+		if length {
+			return Field(field, code, "%s must be at least %s characters", field, param)
+		}
+		return Field(field, code, "%s must be at least %s", field, param)
+	case "max":
+		if length {
+			return Field(field, code, "%s must be at most %s characters", field, param)
+		}
+		return Field(field, code, "%s must be at most %s", field, param)
+	case "oneof":
+		return Field(field, code, "%s must be one of %s", field, strings.ReplaceAll(param, " ", ", "))
+	}
+	return Field(field, code, "%s is invalid", field)
+}
+
+// typeFormat names a Go type the way a JSON client sees it
+func typeFormat(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "%s must be a string"
+	case reflect.Bool:
+		return "%s must be true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "%s must be a whole number"
+	case reflect.Float32, reflect.Float64:
+		return "%s must be a number"
+	case reflect.Slice, reflect.Array:
+		return "%s must be a list"
+	}
+	return "%s must be an object"
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"scandata/config"
+	"scandata/database"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+const usage = `Usage:
+  main                       start the server
+  main migrate up            apply all pending migrations
+  main migrate down [steps]  roll back the last migration (or the last N)
+  main migrate status        list migrations and whether they are applied
+  main config check          validate the configuration and exit`
+
+// runCommand handles command line subcommands and returns the exit code.
+// cfgErr is the result of loading the configuration; only `config check`
+// runs with an invalid one.
+func runCommand(cfg *config.Config, cfgErr error, args []string) int {
+	switch args[0] {
+	case "config":
+		return runConfig(cfg, cfgErr, args[1:])
+	case "migrate":
+		if cfgErr != nil {
+			fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", cfgErr)
+			return 1
+		}
+		return runMigrate(cfg, args[1:])
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s\n", args[0], usage)
+	return 2
+}
+
+func runConfig(cfg *config.Config, cfgErr error, args []string) int {
+	if len(args) != 1 || args[0] != "check" {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	if cfgErr != nil {
+		fmt.Fprintf(os.Stderr, "configuration is invalid (mode: %s):\n", cfg.GinMode)
+		for _, line := range strings.Split(cfgErr.Error(), "\n") {
+			fmt.Fprintf(os.Stderr, "  - %s\n", line)
+		}
+		return 1
+	}
+	fmt.Printf("configuration is valid (mode: %s, database: %s)\n", cfg.GinMode, cfg.DBDriver)
+	return 0
+}
+
+func runMigrate(cfg *config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	database.Connect(cfg)
+	defer database.Close()
+
+	migrator, err := database.NewMigrator(database.DB)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load migrations: %v\n", err)
+		return 1
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied  %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("database is up to date")
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				fmt.Fprintln(os.Stderr, "steps must be a positive number")
+				return 2
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(reverted) == 0 {
+			fmt.Println("nothing to roll back")
+		}
+
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range status {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		w.Flush()
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate command %q\n\n%s\n", args[0], usage)
+		return 2
+	}
+	return 0
+}
@@ -1,7 +1,9 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -12,68 +14,235 @@ import (
 
 type Config struct {
 	// Database
+	DBDriver   string
 	DBHost     string
 	DBPort     string
 	DBUser     string
 	DBPassword string
 	DBName     string
+	DBSSLMode  string // postgres only
+	DBPath     string // sqlite only
+
+	// MigrateOnStartup applies pending migrations when the server starts
+	MigrateOnStartup bool
 
 	// JWT
 	JWTSecret      string
 	JWTExpiryHours int
 
 	// Server
-	ServerPort string
-	GinMode    string
-
-	// CORS
-	AllowedOrigins []string
+	ServerPort        string
+	GinMode           string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownDelay     time.Duration
+	ShutdownTimeout   time.Duration
 
 	// Security
-	RateLimitRequests int
-	RateLimitDuration time.Duration
-	MaxBodySize       int64
+	MaxBodySize int64
+
+	// APIKeys maps the id of each machine client to its X-API-Key; a
+	// verified key gets its own rate limit bucket
+	APIKeys map[string]string
+
+	// Client addresses. TrustedProxies lists the proxies (IPs or CIDR
+	// ranges) whose ClientIPHeader is believed; for other peers the
+	// connection address is the client. ProxyProtocol reads the PROXY
+	// protocol header sent by a trusted proxy instead.
+	TrustedProxies []string
+	ClientIPHeader string
+	ProxyProtocol  bool
 
 	// Logging
-	LogLevel          string
-	EnableSecurityLog bool
+	EnableSecurityLog         bool
+	SecurityLogPath           string
+	SecurityLogMaxSizeMB      int64
+	SecurityLogMaxBackups     int
+	SecurityLogMaxAge         time.Duration
+	SecurityLogRotateInterval time.Duration
+
+	// Health checks
+	HealthCheckTimeout time.Duration
+
+	// Metrics
+	MetricsToken string
+	MetricsAddr  string
+
+	// Scans
+	DuplicateScanPolicy string
+	ScanEditWindow      time.Duration
+
+	// Barcodes
+	BarcodeFormats      []string
+	BarcodeTypePatterns map[string]string
+
+	// Reports
+	ShiftIdleThreshold time.Duration
+
+	// DefaultLocale is the language of messages and exports for clients
+	// that send no Accept-Language and users without a preference
+	DefaultLocale string
+
+	// LegacyAPISunset is announced in the Sunset header of the unversioned
+	// /api routes; zero sends none
+	LegacyAPISunset time.Time
+
+	// Settings that can change while the server runs (see Live)
+	Runtime
 }
 
-func LoadConfig() *Config {
+// Supported database drivers
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Duplicate scan policies: reject the repeat, merge it into the original, or store it flagged
+const (
+	DuplicatePolicyReject = "reject"
+	DuplicatePolicyMerge  = "merge"
+	DuplicatePolicyFlag   = "flag"
+)
+
+// Development defaults that must not reach production
+const (
+	defaultJWTSecret  = "scandata-secret-key-2024"
+	defaultDBPassword = "root"
+)
+
+// minJWTSecretLength is the shortest JWT_SECRET accepted in release mode
+const minJWTSecretLength = 32
+
+// placeholderSecrets are the example values shipped in .env.example and the
+// compose files
+var placeholderSecrets = []string{
+	defaultJWTSecret,
+	"your-super-secret-jwt-key-change-in-production",
+	"generate-a-very-long-random-string-here-min-32-chars",
+	"your-very-long-secret-key",
+}
+
+// LoadConfig reads the configuration from the environment (and .env). The
+// returned error lists every unparsable variable and every failed check from
+// Validate at once; the config is returned either way so `config check` can
+// print it.
+func LoadConfig() (*Config, error) {
+	snapshotProcessEnv()
+
 	// Load .env file
 	godotenv.Load()
 
+	return loadEnv(os.Getenv)
+}
+
+// loadEnv builds the configuration from getenv and checks it
+func loadEnv(getenv func(string) string) (*Config, error) {
+	e := &env{getenv: getenv}
+	cfg := e.load()
+	return cfg, errors.Join(append(e.errs, cfg.Validate())...)
+}
+
+// env reads environment variables and collects the ones it cannot parse
+type env struct {
+	getenv func(string) string
+	errs   []error
+}
+
+func (e *env) load() *Config {
+	driver := e.getEnv("DB_DRIVER", DriverMySQL)
+	defaultPort := "3306"
+	if driver == DriverPostgres {
+		defaultPort = "5432"
+	}
+
 	return &Config{
 		// Database
-		DBHost:     getEnv("DB_HOST", "localhost"),
-		DBPort:     getEnv("DB_PORT", "3306"),
-		DBUser:     getEnv("DB_USER", "root"),
-		DBPassword: getEnv("DB_PASSWORD", "root"),
-		DBName:     getEnv("DB_NAME", "scandata"),
+		DBDriver:   driver,
+		DBHost:     e.getEnv("DB_HOST", "localhost"),
+		DBPort:     e.getEnv("DB_PORT", defaultPort),
+		DBUser:     e.getEnv("DB_USER", "root"),
+		DBPassword: e.getEnv("DB_PASSWORD", defaultDBPassword),
+		DBName:     e.getEnv("DB_NAME", "scandata"),
+		DBSSLMode:  e.getEnv("DB_SSLMODE", "disable"),
+		DBPath:     e.getEnv("DB_PATH", "scandata.db"),
+
+		MigrateOnStartup: e.getEnvBool("MIGRATE_ON_STARTUP", true),
 
 		// JWT
-		JWTSecret:      getEnv("JWT_SECRET", "scandata-secret-key-2024"),
-		JWTExpiryHours: getEnvInt("JWT_EXPIRY_HOURS", 24),
+		JWTSecret:      e.getEnv("JWT_SECRET", defaultJWTSecret),
+		JWTExpiryHours: e.getEnvInt("JWT_EXPIRY_HOURS", 24),
 
 		// Server
-		ServerPort: getEnv("SERVER_PORT", "8080"),
-		GinMode:    getEnv("GIN_MODE", "debug"),
-
-		// CORS
-		AllowedOrigins: getEnvSlice("ALLOWED_ORIGINS", []string{"*"}),
+		ServerPort:        e.getEnv("SERVER_PORT", "8080"),
+		GinMode:           e.getEnv("GIN_MODE", "debug"),
+		ReadTimeout:       e.getEnvDuration("SERVER_READ_TIMEOUT", 30*time.Second),
+		ReadHeaderTimeout: e.getEnvDuration("SERVER_READ_HEADER_TIMEOUT", 10*time.Second),
+		WriteTimeout:      e.getEnvDuration("SERVER_WRITE_TIMEOUT", 2*time.Minute), // exports can be slow
+		IdleTimeout:       e.getEnvDuration("SERVER_IDLE_TIMEOUT", 2*time.Minute),
+		ShutdownDelay:     e.getEnvDuration("SHUTDOWN_DELAY", 0),
+		ShutdownTimeout:   e.getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
 
 		// Security
-		RateLimitRequests: getEnvInt("RATE_LIMIT_REQUESTS", 100),
-		RateLimitDuration: getEnvDuration("RATE_LIMIT_DURATION", time.Minute),
-		MaxBodySize:       getEnvInt64("MAX_BODY_SIZE", 10<<20), // 10MB
+		MaxBodySize: e.getEnvInt64("MAX_BODY_SIZE", 10<<20), // 10MB
+		APIKeys:     e.getEnvMap("API_KEYS"),
+
+		// Client addresses (no proxy is trusted by default)
+		TrustedProxies: e.getEnvSlice("TRUSTED_PROXIES", nil),
+		ClientIPHeader: e.getEnv("CLIENT_IP_HEADER", "X-Forwarded-For"),
+		ProxyProtocol:  e.getEnvBool("PROXY_PROTOCOL", false),
 
 		// Logging
-		LogLevel:          getEnv("LOG_LEVEL", "debug"),
-		EnableSecurityLog: getEnvBool("ENABLE_SECURITY_LOG", true),
+		EnableSecurityLog:         e.getEnvBool("ENABLE_SECURITY_LOG", true),
+		SecurityLogPath:           e.getEnv("SECURITY_LOG_PATH", "security.log"),
+		SecurityLogMaxSizeMB:      e.getEnvInt64("SECURITY_LOG_MAX_SIZE_MB", 10),
+		SecurityLogMaxBackups:     e.getEnvInt("SECURITY_LOG_MAX_BACKUPS", 10),
+		SecurityLogMaxAge:         e.getEnvDuration("SECURITY_LOG_MAX_AGE", 30*24*time.Hour),
+		SecurityLogRotateInterval: e.getEnvDuration("SECURITY_LOG_ROTATE_INTERVAL", 24*time.Hour),
+
+		// Health checks
+		HealthCheckTimeout: e.getEnvDuration("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+
+		// Metrics
+		MetricsToken: e.getEnv("METRICS_TOKEN", ""),
+		MetricsAddr:  e.getEnv("METRICS_ADDR", ""),
+
+		// Scans
+		DuplicateScanPolicy: e.getEnv("DUPLICATE_SCAN_POLICY", DuplicatePolicyFlag),
+		ScanEditWindow:      e.getEnvDuration("SCAN_EDIT_WINDOW", 15*time.Minute),
+
+		// Barcodes
+		BarcodeFormats:      e.getEnvSlice("BARCODE_FORMATS", []string{"gs1", "ean13", "upca", "ean8", "code128"}),
+		BarcodeTypePatterns: e.getEnvMap("BARCODE_TYPE_PATTERNS"),
+
+		// Reports
+		ShiftIdleThreshold: e.getEnvDuration("SHIFT_IDLE_THRESHOLD", 10*time.Minute),
+
+		DefaultLocale: e.getEnv("DEFAULT_LOCALE", "en"),
+
+		LegacyAPISunset: e.getEnvDate("LEGACY_API_SUNSET"),
+
+		Runtime: e.loadRuntime(),
 	}
 }
 
 func (c *Config) GetDSN() string {
+	switch c.DBDriver {
+	case DriverPostgres:
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(c.DBUser, c.DBPassword),
+			Host:     c.DBHost + ":" + c.DBPort,
+			Path:     c.DBName,
+			RawQuery: "sslmode=" + url.QueryEscape(c.DBSSLMode),
+		}
+		return dsn.String()
+	case DriverSQLite:
+		// WAL lets report reads run alongside scan inserts
+		return "file:" + c.DBPath + "?_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL"
+	}
 	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
 		c.DBUser, c.DBPassword, c.DBHost, c.DBPort, c.DBName)
 }
@@ -82,52 +251,114 @@ func (c *Config) IsProduction() bool {
 	return c.GinMode == "release"
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
+// lookup returns the value of key, or the contents of the file named by
+// key_FILE (Docker secrets). Setting both is an error.
+func (e *env) lookup(key string) string {
+	value := e.getenv(key)
+	path := e.getenv(key + "_FILE")
+	if path == "" {
+		return value
+	}
+	if value != "" {
+		e.errs = append(e.errs, fmt.Errorf("%s and %s_FILE are both set", key, key))
+		return value
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s_FILE: %w", key, err))
+		return ""
+	}
+	return strings.TrimRight(string(data), "\r\n")
+}
+
+func (e *env) invalid(key, value, kind string) {
+	e.errs = append(e.errs, fmt.Errorf("%s: %q is not a valid %s", key, value, kind))
+}
+
+func (e *env) getEnv(key, defaultValue string) string {
+	if value := e.lookup(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
 
-func getEnvInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if i, err := strconv.Atoi(value); err == nil {
+func (e *env) getEnvInt(key string, defaultValue int) int {
+	if value := e.lookup(key); value != "" {
+		i, err := strconv.Atoi(value)
+		if err == nil {
 			return i
 		}
+		e.invalid(key, value, "number")
 	}
 	return defaultValue
 }
 
-func getEnvInt64(key string, defaultValue int64) int64 {
-	if value := os.Getenv(key); value != "" {
-		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+func (e *env) getEnvInt64(key string, defaultValue int64) int64 {
+	if value := e.lookup(key); value != "" {
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err == nil {
 			return i
 		}
+		e.invalid(key, value, "number")
 	}
 	return defaultValue
 }
 
-func getEnvBool(key string, defaultValue bool) bool {
-	if value := os.Getenv(key); value != "" {
-		if b, err := strconv.ParseBool(value); err == nil {
+func (e *env) getEnvBool(key string, defaultValue bool) bool {
+	if value := e.lookup(key); value != "" {
+		b, err := strconv.ParseBool(value)
+		if err == nil {
 			return b
 		}
+		e.invalid(key, value, "boolean")
 	}
 	return defaultValue
 }
 
-func getEnvSlice(key string, defaultValue []string) []string {
-	if value := os.Getenv(key); value != "" {
+func (e *env) getEnvSlice(key string, defaultValue []string) []string {
+	if value := e.lookup(key); value != "" {
 		return strings.Split(value, ",")
 	}
 	return defaultValue
 }
 
-func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
-	if value := os.Getenv(key); value != "" {
-		if d, err := time.ParseDuration(value); err == nil {
+// getEnvMap parses "key=value;key=value". Semicolons separate entries so
+// values such as regular expressions may contain commas.
+func (e *env) getEnvMap(key string) map[string]string {
+	result := make(map[string]string)
+	for _, entry := range strings.Split(e.lookup(key), ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			e.invalid(key, entry, "key=value entry")
+			continue
+		}
+		result[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return result
+}
+
+// getEnvDate reads a YYYY-MM-DD date as midnight UTC; unset is the zero time
+func (e *env) getEnvDate(key string) time.Time {
+	if value := e.lookup(key); value != "" {
+		t, err := time.Parse(time.DateOnly, value)
+		if err == nil {
+			return t
+		}
+		e.invalid(key, value, "date (YYYY-MM-DD)")
+	}
+	return time.Time{}
+}
+
+func (e *env) getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := e.lookup(key); value != "" {
+		d, err := time.ParseDuration(value)
+		if err == nil {
 			return d
 		}
+		e.invalid(key, value, "duration")
 	}
 	return defaultValue
 }
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// releaseEnv is a valid production configuration
+func releaseEnv() map[string]string {
+	return map[string]string{
+		"GIN_MODE":        "release",
+		"JWT_SECRET":      "k3D9vQ2mX7pL4tR8wZ1nB6yH5sF0cJ3e",
+		"DB_PASSWORD":     "a-real-database-password",
+		"ALLOWED_ORIGINS": "https://scan.example.com",
+	}
+}
+
+func load(vars map[string]string) (*Config, error) {
+	return loadEnv(func(key string) string { return vars[key] })
+}
+
+func TestDevelopmentDefaultsAreValid(t *testing.T) {
+	cfg, err := load(nil)
+	if err != nil {
+		t.Fatalf("defaults rejected outside release mode: %v", err)
+	}
+	if cfg.JWTSecret != defaultJWTSecret || cfg.DBPassword != defaultDBPassword {
+		t.Fatalf("unexpected defaults %+v", cfg)
+	}
+}
+
+func TestReleaseModeRefusesInsecureSettings(t *testing.T) {
+	if _, err := load(releaseEnv()); err != nil {
+		t.Fatalf("valid release configuration rejected: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		key   string
+		value string
+		want  string
+	}{
+		{"default JWT secret", "JWT_SECRET", "", "JWT_SECRET: the default or example secret"},
+		{"example JWT secret", "JWT_SECRET", "your-super-secret-jwt-key-change-in-production", "JWT_SECRET: the default or example secret"},
+		{"short JWT secret", "JWT_SECRET", "short-but-random-9f2c", "JWT_SECRET: must be at least 32 characters"},
+		{"default database password", "DB_PASSWORD", "", "DB_PASSWORD: the default password"},
+		{"root database password", "DB_PASSWORD", "root", "DB_PASSWORD: the default password"},
+		{"short API key", "API_KEYS", "scanner=short-key", "API_KEYS: the key of scanner must be at least 32 characters"},
+		{"wildcard origin", "ALLOWED_ORIGINS", "https://scan.example.com, *", `ALLOWED_ORIGINS: "*" is not allowed`},
+		{"default origins", "ALLOWED_ORIGINS", "", `ALLOWED_ORIGINS: "*" is not allowed`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vars := releaseEnv()
+			vars[tt.key] = tt.value
+			_, err := load(vars)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	// SQLite has no password to protect
+	vars := releaseEnv()
+	vars["DB_DRIVER"] = DriverSQLite
+	delete(vars, "DB_PASSWORD")
+	if _, err := load(vars); err != nil {
+		t.Fatalf("sqlite without password rejected: %v", err)
+	}
+}
+
+func TestReportsEveryInvalidVariable(t *testing.T) {
+	_, err := load(map[string]string{
+		"DB_DRIVER":           "oracle",
+		"JWT_EXPIRY_HOURS":    "a day",
+		"RATE_LIMIT_DURATION": "soon",
+		"ENABLE_SECURITY_LOG": "maybe",
+		"TRUSTED_PROXIES":     "10.0.0.0/33",
+		"LOG_LEVEL":           "verbose",
+	})
+	if err == nil {
+		t.Fatal("invalid configuration accepted")
+	}
+	for _, want := range []string{
+		`DB_DRIVER: "oracle" is not supported`,
+		`JWT_EXPIRY_HOURS: "a day" is not a valid number`,
+		`RATE_LIMIT_DURATION: "soon" is not a valid duration`,
+		`ENABLE_SECURITY_LOG: "maybe" is not a valid boolean`,
+		"TRUSTED_PROXIES:",
+		`LOG_LEVEL: "verbose" is not supported`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "jwt_secret")
+	if err := os.WriteFile(secret, []byte("k3D9vQ2mX7pL4tR8wZ1nB6yH5sF0cJ3e-from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	vars := releaseEnv()
+	delete(vars, "JWT_SECRET")
+	vars["JWT_SECRET_FILE"] = secret
+	cfg, err := load(vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.JWTSecret != "k3D9vQ2mX7pL4tR8wZ1nB6yH5sF0cJ3e-from-file" {
+		t.Fatalf("secret file not read or not trimmed: %q", cfg.JWTSecret)
+	}
+
+	vars["JWT_SECRET"] = "k3D9vQ2mX7pL4tR8wZ1nB6yH5sF0cJ3e"
+	if _, err := load(vars); err == nil || !strings.Contains(err.Error(), "JWT_SECRET and JWT_SECRET_FILE are both set") {
+		t.Fatalf("expected both-set error, got %v", err)
+	}
+
+	vars = releaseEnv()
+	vars["DB_PASSWORD_FILE"] = filepath.Join(dir, "missing")
+	delete(vars, "DB_PASSWORD")
+	if _, err := load(vars); err == nil || !strings.Contains(err.Error(), "DB_PASSWORD_FILE:") {
+		t.Fatalf("expected missing file error, got %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Live holds the current Runtime settings. Get is lock free and returns a
+// consistent snapshot; Set swaps the whole snapshot and runs the listeners.
+type Live struct {
+	current   atomic.Pointer[Runtime]
+	mu        sync.Mutex
+	listeners []func(Runtime)
+}
+
+func NewLive(r Runtime) *Live {
+	l := &Live{}
+	l.current.Store(&r)
+	return l
+}
+
+func (l *Live) Get() Runtime {
+	return *l.current.Load()
+}
+
+// Set publishes r. Listeners run in registration order, one Set at a time.
+func (l *Live) Set(r Runtime) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.current.Store(&r)
+	for _, fn := range l.listeners {
+		fn(r)
+	}
+}
+
+// OnChange registers fn to run after every Set
+func (l *Live) OnChange(fn func(Runtime)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.listeners = append(l.listeners, fn)
+}
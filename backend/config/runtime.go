@@ -0,0 +1,261 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// RuntimeKeys are the variables behind Runtime, in the order the settings
+// API lists them. The same names are used as keys in the settings table.
+var RuntimeKeys = []string{
+	"RATE_LIMIT_REQUESTS",
+	"RATE_LIMIT_DURATION",
+	"RATE_LIMIT_POLICIES",
+	"ALLOWED_ORIGINS",
+	"LOG_LEVEL",
+	"DUPLICATE_SCAN_WINDOW",
+	"REPORT_TIMEZONE",
+}
+
+// DefaultRateLimitPolicies are the route classes with their own limit:
+// login is strict against password guessing, scans is generous because a
+// scanner submits in bursts, export is expensive. Other routes use
+// RATE_LIMIT_REQUESTS per RATE_LIMIT_DURATION.
+var DefaultRateLimitPolicies = map[string]RateLimit{
+	"login":  {Requests: 5, Period: time.Minute},
+	"scans":  {Requests: 600, Period: time.Minute},
+	"export": {Requests: 10, Period: time.Minute},
+}
+
+// RateLimit allows Requests per Period, in bursts of up to Requests
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+func (l RateLimit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+// Log levels accepted by LOG_LEVEL
+var LogLevels = []string{"debug", "info", "warn", "error"}
+
+// Runtime is the part of the configuration that can change without a
+// restart, through SIGHUP or the settings API
+type Runtime struct {
+	// Rate limiting
+	RateLimitRequests int
+	RateLimitDuration time.Duration
+	// RateLimitPolicies are the limits per route class
+	RateLimitPolicies map[string]RateLimit
+
+	// CORS (release mode only; debug allows every origin)
+	AllowedOrigins []string
+
+	// LogLevel is debug, info, warn or error
+	LogLevel string
+
+	// Scans
+	DuplicateScanWindow time.Duration
+
+	// ReportTimezone is an IANA zone name or "Local". Report day boundaries
+	// and bare YYYY-MM-DD dates use it.
+	ReportTimezone string
+	location       *time.Location
+}
+
+// Location returns the report time zone
+func (r Runtime) Location() *time.Location {
+	if r.location != nil {
+		return r.location
+	}
+	if r.ReportTimezone != "" {
+		if loc, err := time.LoadLocation(r.ReportTimezone); err == nil {
+			return loc
+		}
+	}
+	return time.Local
+}
+
+// RateLimitFor returns the limit of a route class; classes without a policy
+// use RATE_LIMIT_REQUESTS per RATE_LIMIT_DURATION
+func (r Runtime) RateLimitFor(class string) RateLimit {
+	if limit, ok := r.RateLimitPolicies[class]; ok {
+		return limit
+	}
+	return RateLimit{Requests: r.RateLimitRequests, Period: r.RateLimitDuration}
+}
+
+// AllowsOrigin reports whether origin is one of AllowedOrigins
+func (r Runtime) AllowsOrigin(origin string) bool {
+	for _, allowed := range r.AllowedOrigins {
+		if strings.TrimSpace(allowed) == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// Values returns the settings in their environment variable format
+func (r Runtime) Values() map[string]string {
+	classes := sortedKeys(r.RateLimitPolicies)
+	policies := make([]string, len(classes))
+	for i, class := range classes {
+		policies[i] = class + "=" + r.RateLimitPolicies[class].String()
+	}
+
+	return map[string]string{
+		"RATE_LIMIT_REQUESTS":   strconv.Itoa(r.RateLimitRequests),
+		"RATE_LIMIT_DURATION":   r.RateLimitDuration.String(),
+		"RATE_LIMIT_POLICIES":   strings.Join(policies, ";"),
+		"ALLOWED_ORIGINS":       strings.Join(r.AllowedOrigins, ","),
+		"LOG_LEVEL":             r.LogLevel,
+		"DUPLICATE_SCAN_WINDOW": r.DuplicateScanWindow.String(),
+		"REPORT_TIMEZONE":       r.ReportTimezone,
+	}
+}
+
+// Validate checks the runtime settings. Release mode refuses wildcard origins.
+func (r Runtime) Validate(production bool) error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if r.RateLimitRequests <= 0 {
+		fail("RATE_LIMIT_REQUESTS: must be positive")
+	}
+	if r.RateLimitDuration <= 0 {
+		fail("RATE_LIMIT_DURATION: must be positive")
+	}
+	for _, class := range sortedKeys(r.RateLimitPolicies) {
+		if limit := r.RateLimitPolicies[class]; limit.Requests <= 0 || limit.Period <= 0 {
+			fail("RATE_LIMIT_POLICIES: %s needs a positive number of requests and period", class)
+		}
+	}
+	if r.DuplicateScanWindow < 0 {
+		fail("DUPLICATE_SCAN_WINDOW: must not be negative")
+	}
+	if !isOneOf(r.LogLevel, LogLevels) {
+		fail("LOG_LEVEL: %q is not supported (use debug, info, warn or error)", r.LogLevel)
+	}
+	if r.ReportTimezone != "" {
+		if _, err := time.LoadLocation(r.ReportTimezone); err != nil {
+			fail("REPORT_TIMEZONE: unknown time zone %q", r.ReportTimezone)
+		}
+	}
+	if production {
+		for _, origin := range r.AllowedOrigins {
+			if strings.TrimSpace(origin) == "*" {
+				fail("ALLOWED_ORIGINS: \"*\" is not allowed in release mode; list the frontend origins")
+				break
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (e *env) loadRuntime() Runtime {
+	r := Runtime{
+		RateLimitRequests:   e.getEnvInt("RATE_LIMIT_REQUESTS", 100),
+		RateLimitDuration:   e.getEnvDuration("RATE_LIMIT_DURATION", time.Minute),
+		RateLimitPolicies:   e.getEnvRateLimits("RATE_LIMIT_POLICIES", DefaultRateLimitPolicies),
+		AllowedOrigins:      e.getEnvSlice("ALLOWED_ORIGINS", []string{"*"}),
+		LogLevel:            e.getEnv("LOG_LEVEL", "debug"),
+		DuplicateScanWindow: e.getEnvDuration("DUPLICATE_SCAN_WINDOW", 5*time.Second),
+		ReportTimezone:      e.getEnv("REPORT_TIMEZONE", "Local"),
+	}
+	// An unknown zone is reported by Validate
+	r.location, _ = time.LoadLocation(r.ReportTimezone)
+	return r
+}
+
+// getEnvRateLimits parses "class=requests/period;...", e.g. "login=5/1m".
+// Listed classes replace their default, the others keep it.
+func (e *env) getEnvRateLimits(key string, defaults map[string]RateLimit) map[string]RateLimit {
+	limits := make(map[string]RateLimit, len(defaults))
+	for class, limit := range defaults {
+		limits[class] = limit
+	}
+	for class, value := range e.getEnvMap(key) {
+		requests, period, ok := strings.Cut(value, "/")
+		n, err := strconv.Atoi(strings.TrimSpace(requests))
+		d, errPeriod := time.ParseDuration(strings.TrimSpace(period))
+		if !ok || err != nil || errPeriod != nil {
+			e.invalid(key, class+"="+value, "requests/period limit")
+			continue
+		}
+		limits[class] = RateLimit{Requests: n, Period: d}
+	}
+	return limits
+}
+
+// processEnv holds the runtime variables that were set before .env was
+// loaded. They keep precedence over the env file on reload, as at startup.
+var processEnv map[string]string
+
+func snapshotProcessEnv() {
+	processEnv = make(map[string]string)
+	for _, key := range RuntimeKeys {
+		for _, name := range []string{key, key + "_FILE"} {
+			if value, ok := os.LookupEnv(name); ok {
+				processEnv[name] = value
+			}
+		}
+	}
+}
+
+// ReloadRuntime re-reads the runtime settings from the environment and the
+// .env file, then applies overrides (the values saved through the settings
+// API). Every invalid value is reported at once.
+func (c *Config) ReloadRuntime(overrides map[string]string) (Runtime, error) {
+	file, err := godotenv.Read()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return Runtime{}, fmt.Errorf("read .env: %w", err)
+	}
+
+	e := &env{getenv: func(key string) string {
+		if value, ok := overrides[key]; ok {
+			return value
+		}
+		// An override replaces key_FILE as well
+		if _, ok := overrides[strings.TrimSuffix(key, "_FILE")]; ok {
+			return ""
+		}
+		if value, ok := processEnv[key]; ok {
+			return value
+		}
+		return file[key]
+	}}
+	r := e.loadRuntime()
+	if err := errors.Join(append(e.errs, r.Validate(c.IsProduction()))...); err != nil {
+		return Runtime{}, err
+	}
+	return r, nil
+}
+
+func sortedKeys(m map[string]RateLimit) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func isOneOf(value string, values []string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"scandata/i18n"
+	"strings"
+	"time"
+)
+
+// Validate checks settings that parse but cannot work. In release mode it
+// also refuses the development defaults: placeholder or short JWT secrets,
+// the default database password and (through Runtime.Validate) wildcard CORS
+// origins.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	switch c.DBDriver {
+	case DriverMySQL, DriverPostgres, DriverSQLite:
+	default:
+		fail("DB_DRIVER: %q is not supported (use mysql, postgres or sqlite)", c.DBDriver)
+	}
+	switch c.GinMode {
+	case "debug", "release", "test":
+	default:
+		fail("GIN_MODE: %q is not supported (use debug, release or test)", c.GinMode)
+	}
+	switch c.DuplicateScanPolicy {
+	case DuplicatePolicyReject, DuplicatePolicyMerge, DuplicatePolicyFlag:
+	default:
+		fail("DUPLICATE_SCAN_POLICY: %q is not supported (use reject, merge or flag)", c.DuplicateScanPolicy)
+	}
+
+	switch c.ClientIPHeader {
+	case "X-Forwarded-For", "X-Real-IP":
+	default:
+		fail("CLIENT_IP_HEADER: %q is not supported (use X-Forwarded-For or X-Real-IP)", c.ClientIPHeader)
+	}
+	if _, ok := i18n.Parse(c.DefaultLocale); !ok {
+		fail("DEFAULT_LOCALE: %q is not supported (use en or id)", c.DefaultLocale)
+	}
+	if _, err := ParseNetworks(c.TrustedProxies); err != nil {
+		fail("TRUSTED_PROXIES: %v", err)
+	}
+	if c.ProxyProtocol && len(c.TrustedProxies) == 0 {
+		fail("PROXY_PROTOCOL: needs TRUSTED_PROXIES, the proxies allowed to send the header")
+	}
+
+	for id, key := range c.APIKeys {
+		if key == "" {
+			fail("API_KEYS: %s has no key", id)
+		}
+	}
+
+	if c.JWTExpiryHours <= 0 {
+		fail("JWT_EXPIRY_HOURS: must be positive")
+	}
+	if c.MaxBodySize <= 0 {
+		fail("MAX_BODY_SIZE: must be positive")
+	}
+	if c.ShutdownTimeout <= 0 {
+		fail("SHUTDOWN_TIMEOUT: must be positive")
+	}
+	if c.HealthCheckTimeout <= 0 {
+		fail("HEALTH_CHECK_TIMEOUT: must be positive")
+	}
+	for _, d := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"SERVER_READ_TIMEOUT", c.ReadTimeout},
+		{"SERVER_READ_HEADER_TIMEOUT", c.ReadHeaderTimeout},
+		{"SERVER_WRITE_TIMEOUT", c.WriteTimeout},
+		{"SERVER_IDLE_TIMEOUT", c.IdleTimeout},
+		{"SHUTDOWN_DELAY", c.ShutdownDelay},
+		{"SCAN_EDIT_WINDOW", c.ScanEditWindow},
+		{"SHIFT_IDLE_THRESHOLD", c.ShiftIdleThreshold},
+	} {
+		if d.value < 0 {
+			fail("%s: must not be negative", d.key)
+		}
+	}
+
+	if c.IsProduction() {
+		switch {
+		case isPlaceholderSecret(c.JWTSecret):
+			fail("JWT_SECRET: the default or example secret cannot be used in release mode")
+		case len(c.JWTSecret) < minJWTSecretLength:
+			fail("JWT_SECRET: must be at least %d characters in release mode", minJWTSecretLength)
+		}
+		for id, key := range c.APIKeys {
+			if key != "" && len(key) < minJWTSecretLength {
+				fail("API_KEYS: the key of %s must be at least %d characters in release mode", id, minJWTSecretLength)
+			}
+		}
+		if c.DBDriver != DriverSQLite && (c.DBPassword == "" || c.DBPassword == defaultDBPassword) {
+			fail("DB_PASSWORD: the default password cannot be used in release mode")
+		}
+	}
+
+	if err := c.Runtime.Validate(c.IsProduction()); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// ParseNetworks parses IP addresses and CIDR ranges; an address is a range
+// of one
+func ParseNetworks(values []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("%q is not an IP address or CIDR range", value)
+			}
+			bits := 128
+			if v4 := ip.To4(); v4 != nil {
+				ip, bits = v4, 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an IP address or CIDR range", value)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+func isPlaceholderSecret(secret string) bool {
+	for _, placeholder := range placeholderSecrets {
+		if secret == placeholder {
+			return true
+		}
+	}
+	return false
+}
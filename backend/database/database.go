@@ -1,36 +1,158 @@
 package database
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"scandata/config"
+	"scandata/logging"
+	"scandata/metrics"
 	"scandata/models"
 
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
 
+// Models are the tables the application reads and writes. Their schema is
+// created by the SQL migrations; CheckSchema compares the two.
+var Models = []interface{}{
+	&models.User{},
+	&models.Unit{},
+	&models.Shift{},
+	&models.ScanLog{},
+	&models.SavedView{},
+	&models.ScanRevision{},
+	&models.AuditLog{},
+	&models.AuditChainHead{},
+	&models.Setting{},
+}
+
 func InitDB(cfg *config.Config) {
+	Connect(cfg)
+
+	migrator, err := NewMigrator(DB)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+
+	if cfg.MigrateOnStartup {
+		applied, err := migrator.Up(context.Background())
+		if err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+		for _, m := range applied {
+			log.Printf("Applied migration %d_%s", m.Version, m.Name)
+		}
+	} else if pending, err := migrator.Pending(context.Background()); err != nil || len(pending) > 0 {
+		log.Printf("Warning: database has %d pending migrations, run \"migrate up\" (%v)", len(pending), err)
+	}
+
+	// Create default admin if not exists
+	createDefaultAdmin()
+
+	log.Println("Database connected and migrated successfully")
+}
+
+// Connect opens the connection pool without touching the schema
+func Connect(cfg *config.Config) {
+	var dialector gorm.Dialector
+	switch cfg.DBDriver {
+	case config.DriverMySQL:
+		dialector = mysql.Open(cfg.GetDSN())
+	case config.DriverPostgres:
+		dialector = postgres.Open(cfg.GetDSN())
+	case config.DriverSQLite:
+		dialector = sqlite.Open(cfg.GetDSN())
+	default:
+		log.Fatalf("Unsupported DB_DRIVER %q (use mysql, postgres or sqlite)", cfg.DBDriver)
+	}
+
+	logging.SetLevel(cfg.LogLevel)
+
 	var err error
-	DB, err = gorm.Open(mysql.Open(cfg.GetDSN()), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+	DB, err = gorm.Open(dialector, &gorm.Config{
+		Logger: sqlLogger{},
+		// Unique and foreign key violations as gorm.ErrDuplicatedKey and
+		// gorm.ErrForeignKeyViolated on every dialect
+		TranslateError: true,
 	})
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
-	// Auto migrate tables
-	err = DB.AutoMigrate(&models.User{}, &models.Unit{}, &models.ScanLog{})
+	sqlDB, err := DB.DB()
 	if err != nil {
-		log.Fatalf("Failed to migrate database: %v", err)
+		log.Fatalf("Failed to get database handle: %v", err)
+	}
+	// SQLite allows a single writer; one connection avoids "database is locked"
+	if cfg.DBDriver == config.DriverSQLite {
+		sqlDB.SetMaxOpenConns(1)
 	}
+	metrics.RegisterDB(sqlDB, cfg.DBName)
+}
 
-	// Create default admin if not exists
-	createDefaultAdmin()
+// Close closes the connection pool
+func Close() error {
+	if DB == nil {
+		return nil
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
 
-	log.Println("Database connected and migrated successfully")
+// CheckSchema reports an error when migrations are pending or a table or
+// column the models expect is missing, e.g. after restoring an old backup
+// underneath a running server.
+func CheckSchema(ctx context.Context) error {
+	db := DB.WithContext(ctx)
+
+	m, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%d migrations pending, latest is %d_%s", len(pending), pending[len(pending)-1].Version, pending[len(pending)-1].Name)
+	}
+
+	migrator := db.Migrator()
+
+	for _, model := range Models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		table := stmt.Schema.Table
+
+		if !migrator.HasTable(table) {
+			return fmt.Errorf("table %s is missing", table)
+		}
+
+		columnTypes, err := migrator.ColumnTypes(table)
+		if err != nil {
+			return err
+		}
+		columns := make(map[string]bool, len(columnTypes))
+		for _, column := range columnTypes {
+			columns[column.Name()] = true
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" && !columns[field.DBName] {
+				return fmt.Errorf("column %s.%s is missing", table, field.DBName)
+			}
+		}
+	}
+	return nil
 }
 
 func createDefaultAdmin() {
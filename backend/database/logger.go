@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"scandata/logging"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// slowQueryThreshold is the duration from which a query is logged as slow
+const slowQueryThreshold = 200 * time.Millisecond
+
+// sqlLogger is the GORM logger. It writes to the JSON log at the level set
+// by LOG_LEVEL: debug logs every statement, info and warn only slow or
+// failed ones, error only failures. Queries run with a request context
+// carry its request_id. "record not found" is not logged as an error; the
+// callers handle it.
+type sqlLogger struct{}
+
+// LogMode is ignored, the level comes from LOG_LEVEL
+func (l sqlLogger) LogMode(logger.LogLevel) logger.Interface {
+	return l
+}
+
+func (sqlLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	logMessage(ctx, slog.LevelInfo, msg, args)
+}
+
+func (sqlLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	logMessage(ctx, slog.LevelWarn, msg, args)
+}
+
+func (sqlLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	logMessage(ctx, slog.LevelError, msg, args)
+}
+
+func logMessage(ctx context.Context, level slog.Level, msg string, args []interface{}) {
+	if log := logging.Logger(); log.Enabled(ctx, level) {
+		log.LogAttrs(ctx, level, fmt.Sprintf(msg, args...), slog.String("caller", caller()))
+	}
+}
+
+func (sqlLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	elapsed := time.Since(begin)
+	log := logging.Logger()
+
+	var level slog.Level
+	msg := "sql"
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		level, msg = slog.LevelError, err.Error()
+	case elapsed > slowQueryThreshold:
+		level, msg = slog.LevelWarn, fmt.Sprintf("slow sql >= %v", slowQueryThreshold)
+	default:
+		level = slog.LevelDebug
+	}
+	if !log.Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+	log.LogAttrs(ctx, level, msg,
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Float64("duration_ms", float64(elapsed.Nanoseconds())/1e6),
+		slog.String("caller", caller()),
+	)
+}
+
+// caller returns the first file:line outside GORM and this file, i.e. the
+// code that ran the query
+func caller() string {
+	pcs := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !strings.Contains(frame.File, "gorm.io/") && !strings.HasSuffix(frame.File, "database/logger.go") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
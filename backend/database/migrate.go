@@ -0,0 +1,381 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migrations live in migrations/<dialect>/ as NNNN_name.up.sql and
+// NNNN_name.down.sql, with the same versions for every dialect. Statements
+// are separated by a semicolon at the end of a line. MySQL commits DDL
+// implicitly, so keep each migration small: if one fails halfway it has to
+// be finished or reverted by hand before running again.
+//
+// An up script with the line "-- adopt: record" only creates what
+// AutoMigrate of the models creates too. Adopting a legacy database records
+// such a migration as applied instead of running it; every other migration,
+// e.g. a backfill or a change AutoMigrate cannot make, runs as usual.
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+const (
+	migrationLockTimeout = 60 * time.Second
+	// migrationLockKey is the Postgres advisory lock id ("scandata" in ASCII)
+	migrationLockKey = 0x73_63_61_6e_64_61_74_61
+)
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+var adoptRecord = regexp.MustCompile(`(?m)^--\s*adopt:\s*record\s*$`)
+
+// dialectIndex is an index the migrations create that AutoMigrate cannot
+// express from the model tags
+type dialectIndex struct {
+	Table string
+	Name  string
+	SQL   string
+}
+
+// dialectIndexes are added to adopted legacy databases when missing. Keep
+// them in line with the baseline; the notes search depends on them.
+var dialectIndexes = map[string][]dialectIndex{
+	"mysql": {
+		{Table: "scan_logs", Name: "idx_scan_logs_notes", SQL: "CREATE FULLTEXT INDEX `idx_scan_logs_notes` ON `scan_logs` (`notes`)"},
+	},
+	"postgres": {
+		{Table: "scan_logs", Name: "idx_scan_logs_notes", SQL: "CREATE INDEX idx_scan_logs_notes ON scan_logs USING GIN (to_tsvector('simple', coalesce(notes, '')))"},
+	},
+}
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+	// Recorded on adoption of a legacy database instead of run
+	AdoptRecord bool
+}
+
+// SchemaMigration is a row of schema_migrations, one per applied migration
+type SchemaMigration struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:255;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+type MigrationStatus struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	dialect    string
+	migrations []Migration
+}
+
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	dialect := db.Dialector.Name()
+	migrations, err := loadMigrations(dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+func loadMigrations(dialect string) ([]Migration, error) {
+	dir := path.Join("migrations", dialect)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for %s: %w", dialect, err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		m := migrationName.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("migration %s: name must be NNNN_name.up.sql or NNNN_name.down.sql", entry.Name())
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+
+		content, err := migrationFiles.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		} else if migration.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, m[2])
+		}
+		if m[3] == "up" {
+			migration.Up = string(content)
+			migration.AdoptRecord = adoptRecord.Match(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Up applies every pending migration in order and returns the ones applied
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(ctx, func(conn *gorm.DB) error {
+		done, err := m.appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if err := execScript(conn, migration.Up); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			if err := conn.Create(&SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error; err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the last steps applied migrations, newest first
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.locked(ctx, func(conn *gorm.DB) error {
+		done, err := m.appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s cannot be rolled back (no down script)", migration.Version, migration.Name)
+			}
+			if err := execScript(conn, migration.Down); err != nil {
+				return fmt.Errorf("rollback %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			if err := conn.Delete(&SchemaMigration{}, migration.Version).Error; err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every known migration and whether it has been applied
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	db := m.db.WithContext(ctx)
+	done := make(map[int64]time.Time)
+	if db.Migrator().HasTable(&SchemaMigration{}) {
+		var err error
+		if done, err = m.appliedVersions(db); err != nil {
+			return nil, err
+		}
+	}
+
+	status := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		s := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if at, ok := done[migration.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = &at
+		}
+		status = append(status, s)
+	}
+	return status, nil
+}
+
+// Pending returns the migrations that have not been applied yet
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for i, s := range status {
+		if !s.Applied {
+			pending = append(pending, m.migrations[i])
+		}
+	}
+	return pending, nil
+}
+
+func (m *Migrator) appliedVersions(db *gorm.DB) (map[int64]time.Time, error) {
+	var rows []SchemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	done := make(map[int64]time.Time, len(rows))
+	for _, row := range rows {
+		done[row.Version] = row.AppliedAt
+	}
+	return done, nil
+}
+
+// locked runs fn on a single connection holding the migration lock, so
+// replicas starting at the same time migrate one after another.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		// Without a new session the implicit transaction of the first Create
+		// moves later statements, the unlock included, off the pinned
+		// connection, and the session lock would never be released
+		conn = conn.Session(&gorm.Session{})
+
+		unlock, err := m.lock(conn)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		if err := m.adoptLegacySchema(conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+// lock takes a session-level advisory lock. SQLite has a single writer and
+// is never shared between replicas, so it needs none.
+func (m *Migrator) lock(conn *gorm.DB) (func(), error) {
+	switch m.dialect {
+	case "mysql":
+		var got *int
+		if err := conn.Raw("SELECT GET_LOCK(CONCAT('schema_migrations.', DATABASE()), ?)", int(migrationLockTimeout.Seconds())).Scan(&got).Error; err != nil {
+			return nil, err
+		}
+		if got == nil || *got != 1 {
+			return nil, errors.New("timed out waiting for the migration lock")
+		}
+		return func() { conn.Exec("SELECT RELEASE_LOCK(CONCAT('schema_migrations.', DATABASE()))") }, nil
+
+	case "postgres":
+		deadline := time.Now().Add(migrationLockTimeout)
+		for {
+			var got bool
+			if err := conn.Raw("SELECT pg_try_advisory_lock(?)", migrationLockKey).Scan(&got).Error; err != nil {
+				return nil, err
+			}
+			if got {
+				return func() { conn.Exec("SELECT pg_advisory_unlock(?)", migrationLockKey) }, nil
+			}
+			if time.Now().After(deadline) {
+				return nil, errors.New("timed out waiting for the migration lock")
+			}
+			time.Sleep(500 * time.Millisecond)
+		}
+	}
+	return func() {}, nil
+}
+
+// adoptLegacySchema handles databases created by AutoMigrate before
+// versioned migrations existed. Their tables may lag behind the models if
+// the last deploy was older, so AutoMigrate runs one final time and adds the
+// dialectIndexes it cannot create. The migrations marked "adopt: record" are
+// then recorded as applied; Up runs the others.
+func (m *Migrator) adoptLegacySchema(conn *gorm.DB) error {
+	migrator := conn.Migrator()
+	if migrator.HasTable(&SchemaMigration{}) {
+		return nil
+	}
+	legacy := migrator.HasTable("users")
+
+	if err := migrator.CreateTable(&SchemaMigration{}); err != nil {
+		return err
+	}
+	if !legacy || len(m.migrations) == 0 {
+		return nil
+	}
+
+	if err := conn.AutoMigrate(Models...); err != nil {
+		return fmt.Errorf("upgrade legacy schema: %w", err)
+	}
+	for _, index := range dialectIndexes[m.dialect] {
+		if migrator.HasIndex(index.Table, index.Name) {
+			continue
+		}
+		if err := conn.Exec(index.SQL).Error; err != nil {
+			return fmt.Errorf("upgrade legacy schema: index %s: %w", index.Name, err)
+		}
+	}
+	for _, migration := range m.migrations {
+		if !migration.AdoptRecord {
+			continue
+		}
+		if err := conn.Create(&SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func execScript(conn *gorm.DB, script string) error {
+	for _, statement := range splitStatements(script) {
+		if err := conn.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitStatements splits a script on semicolons that end a line and drops
+// "--" comment lines
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+
+		if strings.HasSuffix(trimmed, ";") {
+			statement := strings.TrimSuffix(strings.TrimSpace(current.String()), ";")
+			statements = append(statements, statement)
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
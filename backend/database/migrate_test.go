@@ -0,0 +1,207 @@
+package database
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"scandata/models"
+	"scandata/repository"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB opens an empty SQLite database with the default connection
+// pool, so statements can land on different connections
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// The advisory locks belong to a session, so everything from taking the lock
+// to releasing it has to run on one connection
+func TestLockedKeepsOneConnection(t *testing.T) {
+	db := openTestDB(t)
+	m, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.locked(context.Background(), func(conn *gorm.DB) error {
+		// A TEMP table is only visible to the connection that created it
+		if err := conn.Exec("CREATE TEMP TABLE lock_probe (id integer)").Error; err != nil {
+			return err
+		}
+		// Create runs in an implicit transaction, as recording a migration does
+		if err := conn.Create(&SchemaMigration{Version: 9999, Name: "probe", AppliedAt: time.Now()}).Error; err != nil {
+			return err
+		}
+		return conn.Exec("INSERT INTO lock_probe VALUES (1)").Error
+	})
+	if err != nil {
+		t.Fatalf("statement left the locked connection: %v", err)
+	}
+}
+
+// A database last deployed before versioned migrations is adopted: the
+// schema is brought up to date, the indexes AutoMigrate cannot create are
+// added and the notes search works on it
+func TestAdoptLegacySchema(t *testing.T) {
+	db := openTestDB(t)
+	if err := db.AutoMigrate(Models...); err != nil {
+		t.Fatal(err)
+	}
+	user := models.User{Username: "scanner", Name: "Scanner", Role: models.RoleUser}
+	db.Create(&user)
+	db.Create(&models.ScanLog{Barcode: "4006381333931", UserID: user.ID, Notes: "damaged pallet", ScannedAt: time.Now()})
+	db.Create(&models.ScanLog{Barcode: "5901234123457", UserID: user.ID, Notes: "ok", ScannedAt: time.Now()})
+
+	// SQLite needs no search index; stand one in to check adoption adds it
+	saved := dialectIndexes["sqlite"]
+	dialectIndexes["sqlite"] = []dialectIndex{{Table: "scan_logs", Name: "idx_scan_logs_notes_test", SQL: "CREATE INDEX idx_scan_logs_notes_test ON scan_logs (notes)"}}
+	t.Cleanup(func() { dialectIndexes["sqlite"] = saved })
+
+	m, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("adopt: %v", err)
+	}
+	if !db.Migrator().HasIndex("scan_logs", "idx_scan_logs_notes_test") {
+		t.Fatal("adoption did not create the dialect index")
+	}
+	if pending, err := m.Pending(context.Background()); err != nil || len(pending) > 0 {
+		t.Fatalf("migrations pending after adoption: %v %v", pending, err)
+	}
+
+	scans, total, err := repository.NewGormStore(db).Scans().List(context.Background(),
+		repository.ScanFilter{Notes: "damaged"}, repository.Page{Sort: "scanned_at", Limit: 10})
+	if err != nil {
+		t.Fatalf("notes search on adopted database: %v", err)
+	}
+	if total != 1 || len(scans) != 1 || scans[0].Barcode != "4006381333931" {
+		t.Fatalf("notes search returned %d scans (total %d)", len(scans), total)
+	}
+}
+
+// Every index the baselines create by hand must be known to adoption
+func TestDialectIndexesCoverBaseline(t *testing.T) {
+	special := regexp.MustCompile("(?m)^.*(?:FULLTEXT INDEX|USING GIN|USING GIST).*?`?(idx_\\w+)`?.*$")
+	for _, dialect := range []string{"mysql", "postgres", "sqlite"} {
+		baseline, err := fs.ReadFile(migrationFiles, "migrations/"+dialect+"/0001_baseline.up.sql")
+		if err != nil {
+			t.Fatal(err)
+		}
+		known := make(map[string]bool)
+		for _, index := range dialectIndexes[dialect] {
+			known[index.Name] = true
+		}
+		for _, m := range special.FindAllStringSubmatch(string(baseline), -1) {
+			if !known[m[1]] {
+				t.Errorf("%s baseline creates %s, which adoption does not add", dialect, m[1])
+			}
+		}
+	}
+}
+
+// Adoption records only the migrations AutoMigrate covers; the rest, such
+// as a backfill, still run
+func TestAdoptRunsUnmarkedMigrations(t *testing.T) {
+	db := openTestDB(t)
+	if err := db.AutoMigrate(Models...); err != nil {
+		t.Fatal(err)
+	}
+	user := models.User{Username: "scanner", Name: "Scanner", Role: models.RoleUser}
+	db.Create(&user)
+
+	m, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.migrations = append(m.migrations, Migration{
+		Version: 9000,
+		Name:    "backfill_locale",
+		Up:      "UPDATE users SET locale = 'id' WHERE locale = '';",
+	})
+
+	applied, err := m.Up(context.Background())
+	if err != nil {
+		t.Fatalf("adopt: %v", err)
+	}
+	for _, migration := range applied {
+		if migration.AdoptRecord {
+			t.Fatalf("migration %d_%s ran although adoption records it", migration.Version, migration.Name)
+		}
+	}
+	if len(applied) == 0 || applied[len(applied)-1].Version != 9000 {
+		t.Fatalf("backfill did not run on adoption, ran %+v", applied)
+	}
+	var locale string
+	db.Model(&models.User{}).Where("id = ?", user.ID).Pluck("locale", &locale)
+	if locale != "id" {
+		t.Fatalf("backfill skipped on adoption, locale is %q", locale)
+	}
+}
+
+// AutoMigrate creates the chain head table of a legacy database empty; the
+// migration has to add the row, linked to the newest existing entry
+func TestAdoptSeedsAuditChainHead(t *testing.T) {
+	db := openTestDB(t)
+	if err := db.AutoMigrate(Models...); err != nil {
+		t.Fatal(err)
+	}
+	for _, hash := range []string{"first", "newest"} {
+		if err := db.Create(&models.AuditLog{Action: models.AuditCreate, EntityType: "unit", Hash: hash, CreatedAt: time.Now()}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("adopt: %v", err)
+	}
+
+	var heads []models.AuditChainHead
+	db.Find(&heads)
+	if len(heads) != 1 || heads[0].ID != models.AuditChainHeadID || heads[0].Hash != "newest" {
+		t.Fatalf("unexpected chain head %+v", heads)
+	}
+}
+
+// Whether adoption runs a migration must not depend on the database
+func TestAdoptRecordMatchesAcrossDialects(t *testing.T) {
+	marked := make(map[int64]bool)
+	for i, dialect := range []string{"mysql", "postgres", "sqlite"} {
+		migrations, err := loadMigrations(dialect)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !migrations[0].AdoptRecord {
+			t.Errorf("%s baseline is not marked adopt: record", dialect)
+		}
+		for _, migration := range migrations {
+			if i == 0 {
+				marked[migration.Version] = migration.AdoptRecord
+			} else if marked[migration.Version] != migration.AdoptRecord {
+				t.Errorf("%s marks %d_%s differently from mysql", dialect, migration.Version, migration.Name)
+			}
+		}
+	}
+}
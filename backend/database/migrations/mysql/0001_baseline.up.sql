@@ -0,0 +1,137 @@
+-- Schema as created by AutoMigrate before versioned migrations were introduced
+-- adopt: record
+
+CREATE TABLE `users` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `username` varchar(50) NOT NULL,
+  `password_hash` varchar(255) NOT NULL,
+  `name` varchar(100) NOT NULL,
+  `role` enum('admin','user') DEFAULT 'user',
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_users_username` (`username`),
+  INDEX `idx_users_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE `units` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `qr_code` varchar(100) NOT NULL,
+  `name` varchar(200) NOT NULL,
+  `type` varchar(50),
+  `expected_grade` varchar(100),
+  `location` varchar(200),
+  `is_active` boolean DEFAULT true,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_units_qr_code` (`qr_code`),
+  INDEX `idx_units_type` (`type`),
+  INDEX `idx_units_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE `shifts` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `name` varchar(50) NOT NULL,
+  `start_time` varchar(5) NOT NULL,
+  `end_time` varchar(5) NOT NULL,
+  `days` varchar(50),
+  `is_active` boolean DEFAULT true,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_shifts_name` (`name`),
+  INDEX `idx_shifts_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE `scan_logs` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `barcode` varchar(100) NOT NULL,
+  `user_id` bigint unsigned NOT NULL,
+  `unit_id` bigint unsigned,
+  `is_match` boolean NOT NULL,
+  `notes` varchar(500),
+  `symbology` varchar(30),
+  `gtin` varchar(14),
+  `lot` varchar(20),
+  `serial_number` varchar(20),
+  `expiry_date` date,
+  `scanned_at` datetime(3) NOT NULL,
+  `shift_id` bigint unsigned,
+  `shift_date` date,
+  `duplicate_of_id` bigint unsigned,
+  `duplicate_count` bigint NOT NULL DEFAULT 0,
+  `is_voided` boolean NOT NULL DEFAULT false,
+  `voided_at` datetime(3) NULL,
+  `voided_by` bigint unsigned,
+  `void_reason` varchar(500),
+  `revision` bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY (`id`),
+  INDEX `idx_scan_logs_barcode` (`barcode`),
+  INDEX `idx_scan_logs_user_id` (`user_id`),
+  INDEX `idx_scan_logs_unit_id` (`unit_id`),
+  FULLTEXT INDEX `idx_scan_logs_notes` (`notes`),
+  INDEX `idx_scan_logs_gtin` (`gtin`),
+  INDEX `idx_scan_logs_lot` (`lot`),
+  INDEX `idx_scan_logs_scanned_at` (`scanned_at`),
+  INDEX `idx_scan_logs_shift_id` (`shift_id`),
+  INDEX `idx_scan_logs_shift_date` (`shift_date`),
+  INDEX `idx_scan_logs_duplicate_of_id` (`duplicate_of_id`),
+  INDEX `idx_scan_logs_is_voided` (`is_voided`),
+  CONSTRAINT `fk_scan_logs_shift` FOREIGN KEY (`shift_id`) REFERENCES `shifts`(`id`),
+  CONSTRAINT `fk_users_scan_logs` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`),
+  CONSTRAINT `fk_units_scan_logs` FOREIGN KEY (`unit_id`) REFERENCES `units`(`id`)
+);
+
+CREATE TABLE `saved_views` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `name` varchar(100) NOT NULL,
+  `filters` text NOT NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_saved_views_user_name` (`user_id`, `name`)
+);
+
+CREATE TABLE `scan_revisions` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `scan_log_id` bigint unsigned NOT NULL,
+  `revision` bigint NOT NULL,
+  `action` varchar(20) NOT NULL,
+  `reason` varchar(500) NOT NULL,
+  `changed_by` bigint unsigned NOT NULL,
+  `before` text,
+  `after` text,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_scan_revisions_scan_log_id` (`scan_log_id`),
+  CONSTRAINT `fk_scan_revisions_user` FOREIGN KEY (`changed_by`) REFERENCES `users`(`id`)
+);
+
+CREATE TABLE `audit_logs` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `actor_id` bigint unsigned,
+  `actor_name` varchar(50),
+  `action` varchar(30) NOT NULL,
+  `entity_type` varchar(50) NOT NULL,
+  `entity_id` varchar(50),
+  `before` text,
+  `after` text,
+  `diff` text,
+  `ip` varchar(45),
+  `request_id` varchar(64),
+  `prev_hash` varchar(64) NOT NULL,
+  `hash` varchar(64) NOT NULL,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_audit_logs_actor_id` (`actor_id`),
+  INDEX `idx_audit_logs_action` (`action`),
+  INDEX `idx_audit_logs_entity` (`entity_type`, `entity_id`),
+  INDEX `idx_audit_logs_request_id` (`request_id`),
+  UNIQUE INDEX `idx_audit_logs_hash` (`hash`),
+  INDEX `idx_audit_logs_created_at` (`created_at`)
+);
@@ -0,0 +1,11 @@
+-- Runtime settings saved through the admin settings API. Legacy databases
+-- get the table from AutoMigrate, so adoption records this without running it.
+-- adopt: record
+
+CREATE TABLE `settings` (
+  `key` varchar(100) NOT NULL,
+  `value` text NOT NULL,
+  `updated_by` bigint unsigned NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`key`)
+);
@@ -0,0 +1,6 @@
+-- Preferred language of API messages and exports; empty follows the
+-- Accept-Language header of the client. AutoMigrate adds the column to
+-- legacy databases, so adoption records this without running it.
+-- adopt: record
+
+ALTER TABLE `users` ADD COLUMN `locale` varchar(5) NOT NULL DEFAULT '';
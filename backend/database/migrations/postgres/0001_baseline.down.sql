@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS audit_logs;
+DROP TABLE IF EXISTS scan_revisions;
+DROP TABLE IF EXISTS saved_views;
+DROP TABLE IF EXISTS scan_logs;
+DROP TABLE IF EXISTS shifts;
+DROP TABLE IF EXISTS units;
+DROP TABLE IF EXISTS users;
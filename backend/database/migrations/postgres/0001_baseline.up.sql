@@ -0,0 +1,127 @@
+-- Schema of the models when versioned migrations were introduced
+-- adopt: record
+
+CREATE TABLE users (
+  id bigserial PRIMARY KEY,
+  username varchar(50) NOT NULL,
+  password_hash varchar(255) NOT NULL,
+  name varchar(100) NOT NULL,
+  role varchar(10) DEFAULT 'user' CHECK (role IN ('admin', 'user')),
+  created_at timestamptz NULL,
+  updated_at timestamptz NULL,
+  deleted_at timestamptz NULL
+);
+CREATE UNIQUE INDEX idx_users_username ON users (username);
+CREATE INDEX idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE units (
+  id bigserial PRIMARY KEY,
+  qr_code varchar(100) NOT NULL,
+  name varchar(200) NOT NULL,
+  type varchar(50),
+  expected_grade varchar(100),
+  location varchar(200),
+  is_active boolean DEFAULT true,
+  created_at timestamptz NULL,
+  updated_at timestamptz NULL,
+  deleted_at timestamptz NULL
+);
+CREATE UNIQUE INDEX idx_units_qr_code ON units (qr_code);
+CREATE INDEX idx_units_type ON units (type);
+CREATE INDEX idx_units_deleted_at ON units (deleted_at);
+
+CREATE TABLE shifts (
+  id bigserial PRIMARY KEY,
+  name varchar(50) NOT NULL,
+  start_time varchar(5) NOT NULL,
+  end_time varchar(5) NOT NULL,
+  days varchar(50),
+  is_active boolean DEFAULT true,
+  created_at timestamptz NULL,
+  updated_at timestamptz NULL,
+  deleted_at timestamptz NULL
+);
+CREATE UNIQUE INDEX idx_shifts_name ON shifts (name);
+CREATE INDEX idx_shifts_deleted_at ON shifts (deleted_at);
+
+CREATE TABLE scan_logs (
+  id bigserial PRIMARY KEY,
+  barcode varchar(100) NOT NULL,
+  user_id bigint NOT NULL REFERENCES users (id),
+  unit_id bigint REFERENCES units (id),
+  is_match boolean NOT NULL,
+  notes varchar(500),
+  symbology varchar(30),
+  gtin varchar(14),
+  lot varchar(20),
+  serial_number varchar(20),
+  expiry_date date,
+  scanned_at timestamptz NOT NULL,
+  shift_id bigint REFERENCES shifts (id),
+  shift_date date,
+  duplicate_of_id bigint,
+  duplicate_count bigint NOT NULL DEFAULT 0,
+  is_voided boolean NOT NULL DEFAULT false,
+  voided_at timestamptz NULL,
+  voided_by bigint,
+  void_reason varchar(500),
+  revision bigint NOT NULL DEFAULT 0
+);
+CREATE INDEX idx_scan_logs_barcode ON scan_logs (barcode);
+CREATE INDEX idx_scan_logs_user_id ON scan_logs (user_id);
+CREATE INDEX idx_scan_logs_unit_id ON scan_logs (unit_id);
+-- Matches the expression used by the notes search in ScanFilter.Apply
+CREATE INDEX idx_scan_logs_notes ON scan_logs USING GIN (to_tsvector('simple', coalesce(notes, '')));
+CREATE INDEX idx_scan_logs_gtin ON scan_logs (gtin);
+CREATE INDEX idx_scan_logs_lot ON scan_logs (lot);
+CREATE INDEX idx_scan_logs_scanned_at ON scan_logs (scanned_at);
+CREATE INDEX idx_scan_logs_shift_id ON scan_logs (shift_id);
+CREATE INDEX idx_scan_logs_shift_date ON scan_logs (shift_date);
+CREATE INDEX idx_scan_logs_duplicate_of_id ON scan_logs (duplicate_of_id);
+CREATE INDEX idx_scan_logs_is_voided ON scan_logs (is_voided);
+
+CREATE TABLE saved_views (
+  id bigserial PRIMARY KEY,
+  user_id bigint NOT NULL,
+  name varchar(100) NOT NULL,
+  filters text NOT NULL,
+  created_at timestamptz NULL,
+  updated_at timestamptz NULL
+);
+CREATE UNIQUE INDEX idx_saved_views_user_name ON saved_views (user_id, name);
+
+CREATE TABLE scan_revisions (
+  id bigserial PRIMARY KEY,
+  scan_log_id bigint NOT NULL,
+  revision bigint NOT NULL,
+  action varchar(20) NOT NULL,
+  reason varchar(500) NOT NULL,
+  changed_by bigint NOT NULL REFERENCES users (id),
+  before text,
+  after text,
+  created_at timestamptz NULL
+);
+CREATE INDEX idx_scan_revisions_scan_log_id ON scan_revisions (scan_log_id);
+
+CREATE TABLE audit_logs (
+  id bigserial PRIMARY KEY,
+  actor_id bigint,
+  actor_name varchar(50),
+  action varchar(30) NOT NULL,
+  entity_type varchar(50) NOT NULL,
+  entity_id varchar(50),
+  before text,
+  after text,
+  diff text,
+  ip varchar(45),
+  request_id varchar(64),
+  prev_hash varchar(64) NOT NULL,
+  hash varchar(64) NOT NULL,
+  created_at timestamptz NULL
+);
+CREATE INDEX idx_audit_logs_actor_id ON audit_logs (actor_id);
+CREATE INDEX idx_audit_logs_action ON audit_logs (action);
+CREATE INDEX idx_audit_logs_entity ON audit_logs (entity_type, entity_id);
+CREATE INDEX idx_audit_logs_request_id ON audit_logs (request_id);
+CREATE UNIQUE INDEX idx_audit_logs_hash ON audit_logs (hash);
+CREATE INDEX idx_audit_logs_created_at ON audit_logs (created_at);
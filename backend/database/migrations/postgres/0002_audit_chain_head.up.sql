@@ -0,0 +1,13 @@
+-- The hash of the newest audit entry in a single row. Appending locks the
+-- row until commit, so writers extend the chain one after another even
+-- while audit_logs is empty. Legacy databases may have the table from
+-- AutoMigrate already, but not the row, so this runs on adoption too.
+
+CREATE TABLE IF NOT EXISTS audit_chain_heads (
+  id bigint PRIMARY KEY,
+  hash varchar(64) NOT NULL DEFAULT ''
+);
+
+INSERT INTO audit_chain_heads (id, hash)
+SELECT 1, COALESCE((SELECT hash FROM audit_logs ORDER BY id DESC LIMIT 1), '')
+WHERE NOT EXISTS (SELECT 1 FROM audit_chain_heads WHERE id = 1);
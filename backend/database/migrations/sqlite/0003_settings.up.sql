@@ -0,0 +1,10 @@
+-- Runtime settings saved through the admin settings API. Legacy databases
+-- get the table from AutoMigrate, so adoption records this without running it.
+-- adopt: record
+
+CREATE TABLE settings (
+  key varchar(100) PRIMARY KEY,
+  value text NOT NULL,
+  updated_by integer NULL,
+  updated_at datetime NULL
+);
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"scandata/middleware"
+	"scandata/models"
+	"scandata/services"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUserCRUD(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		token := login(t, "admin")
+
+		var created models.User
+		w := doRequest(t, token, http.MethodPost, "/api/v1/users", gin.H{"username": "operator", "password": "secret1", "name": "Operator", "role": "user"})
+		expectStatus(t, w, http.StatusCreated)
+		decode(t, w, &created)
+
+		path := fmt.Sprintf("/api/v1/users/%d", created.ID)
+		expectStatus(t, doRequest(t, token, http.MethodPut, path, gin.H{"name": "Line Operator"}), http.StatusOK)
+
+		var users []models.User
+		w = doRequest(t, token, http.MethodGet, "/api/v1/users?sort=username", nil)
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &users)
+		if len(users) != 3 || users[1].Name != "Line Operator" {
+			t.Fatalf("unexpected users %+v", users)
+		}
+
+		expectStatus(t, doRequest(t, token, http.MethodDelete, path, nil), http.StatusOK)
+		expectStatus(t, doRequest(t, token, http.MethodGet, path, nil), http.StatusNotFound)
+
+		expectStatus(t, doRequest(t, login(t, "scanner"), http.MethodGet, "/api/v1/users", nil), http.StatusForbidden)
+	})
+}
+
+func TestUnitSearch(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		token := login(t, "admin")
+
+		for _, unit := range []gin.H{
+			{"qr_code": "PLT-001", "name": "Pallet North"},
+			{"qr_code": "PLT-002", "name": "Pallet South"},
+			{"qr_code": "BOX-001", "name": "Box"},
+		} {
+			expectStatus(t, doRequest(t, token, http.MethodPost, "/api/v1/units", unit), http.StatusCreated)
+		}
+
+		var units []models.Unit
+		w := doRequest(t, token, http.MethodGet, "/api/v1/units?search=pallet", nil)
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &units)
+		if len(units) != 2 {
+			t.Fatalf("case-insensitive search found %d units, want 2", len(units))
+		}
+
+		w = doRequest(t, login(t, "scanner"), http.MethodGet, "/api/v1/units/qr/BOX-001", nil)
+		expectStatus(t, w, http.StatusOK)
+	})
+}
+
+// Without limit or cursor the unit and user lists return every row, since
+// the admin screens do not follow cursors
+func TestUnitListUnbounded(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		token := login(t, "admin")
+		for i := 0; i < defaultPageSize+1; i++ {
+			unit := &models.Unit{QRCode: fmt.Sprintf("PLT-%03d", i), Name: "Pallet", IsActive: true}
+			if err := testStore.Units().Create(context.Background(), unit); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var units []models.Unit
+		w := doRequest(t, token, http.MethodGet, "/api/v1/units", nil)
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &units)
+		if len(units) != defaultPageSize+1 || w.Header().Get("X-Next-Cursor") != "" {
+			t.Fatalf("got %d units and cursor %q, want all", len(units), w.Header().Get("X-Next-Cursor"))
+		}
+
+		w = doRequest(t, token, http.MethodGet, "/api/v1/units?limit=60", nil)
+		decode(t, w, &units)
+		cursor := w.Header().Get("X-Next-Cursor")
+		if len(units) != 60 || cursor == "" {
+			t.Fatalf("limited list returned %d units and cursor %q", len(units), cursor)
+		}
+		w = doRequest(t, token, http.MethodGet, "/api/v1/units?cursor="+cursor, nil)
+		decode(t, w, &units)
+		if len(units) != defaultPageSize+1-60 {
+			t.Fatalf("following the cursor returned %d units", len(units))
+		}
+	})
+}
+
+func TestUnitCRUD(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		token := login(t, "admin")
+
+		var unit models.Unit
+		w := doRequest(t, token, http.MethodPost, "/api/v1/units", gin.H{"qr_code": "PLT-001", "name": "Pallet", "location": "Dock 1"})
+		expectStatus(t, w, http.StatusCreated)
+		decode(t, w, &unit)
+		expectStatus(t, doRequest(t, token, http.MethodPost, "/api/v1/units", gin.H{"qr_code": "PLT-001", "name": "Copy"}), http.StatusConflict)
+
+		path := fmt.Sprintf("/api/v1/units/%d", unit.ID)
+		w = doRequest(t, login(t, "scanner"), http.MethodGet, path, nil)
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &unit)
+		if unit.Location != "Dock 1" || !unit.IsActive {
+			t.Fatalf("unexpected unit %+v", unit)
+		}
+
+		// Inactive units are not found by QR code
+		expectStatus(t, doRequest(t, token, http.MethodPut, path, gin.H{"name": "Pallet A", "is_active": false}), http.StatusOK)
+		expectStatus(t, doRequest(t, token, http.MethodGet, "/api/v1/units/qr/PLT-001", nil), http.StatusNotFound)
+
+		var units []models.Unit
+		w = doRequest(t, token, http.MethodGet, "/api/v1/units?active=false", nil)
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &units)
+		if len(units) != 1 || units[0].Name != "Pallet A" {
+			t.Fatalf("unexpected inactive units %+v", units)
+		}
+
+		expectStatus(t, doRequest(t, login(t, "scanner"), http.MethodDelete, path, nil), http.StatusForbidden)
+		expectStatus(t, doRequest(t, token, http.MethodDelete, path, nil), http.StatusOK)
+		expectStatus(t, doRequest(t, token, http.MethodGet, path, nil), http.StatusNotFound)
+		expectStatus(t, doRequest(t, token, http.MethodGet, "/api/v1/units/abc", nil), http.StatusNotFound)
+	})
+}
+
+func TestAuditTrail(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		token := login(t, "admin")
+
+		expectStatus(t, doRequest(t, token, http.MethodPost, "/api/v1/units", gin.H{"qr_code": "PLT-001", "name": "Pallet"}), http.StatusCreated)
+		expectStatus(t, doRequest(t, token, http.MethodPost, "/api/v1/users", gin.H{"username": "operator", "password": "secret1", "name": "Operator", "role": "user"}), http.StatusCreated)
+
+		var entries []models.AuditLog
+		w := doRequest(t, token, http.MethodGet, "/api/v1/audit?sort=id", nil)
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &entries)
+		if len(entries) != 2 || entries[0].EntityType != "unit" || entries[1].PrevHash != entries[0].Hash {
+			t.Fatalf("unexpected audit entries %+v", entries)
+		}
+
+		var result services.AuditVerification
+		w = doRequest(t, token, http.MethodGet, "/api/v1/audit/verify", nil)
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &result)
+		if !result.Valid || result.Checked != 2 {
+			t.Fatalf("audit chain not valid: %+v", result)
+		}
+
+		w = doRequest(t, token, http.MethodGet, "/api/v1/audit/export?entity_type=unit", nil)
+		expectStatus(t, w, http.StatusOK)
+		if ct := w.Header().Get("Content-Type"); ct != "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet" {
+			t.Fatalf("unexpected content type %q", ct)
+		}
+		expectStatus(t, doRequest(t, token, http.MethodGet, "/api/v1/audit/export?from=yesterday", nil), http.StatusBadRequest)
+	})
+}
+
+// Password changes append two entries in one transaction; concurrent ones
+// must neither deadlock nor fork the chain
+func TestAuditTrailConcurrentAppends(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		token := login(t, "admin")
+		scanner, err := testStore.Users().GetByUsername(context.Background(), "scanner")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		const requests = 8
+		codes := make(chan int, 2*requests)
+		var wg sync.WaitGroup
+		send := func(method, path, body string) {
+			defer wg.Done()
+			req := httptest.NewRequest(method, path, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			testRouter.ServeHTTP(w, req)
+			codes <- w.Code
+		}
+		for i := 0; i < requests; i++ {
+			wg.Add(2)
+			go send(http.MethodPut, fmt.Sprintf("/api/v1/users/%d", scanner.ID), fmt.Sprintf(`{"password":"secret%d"}`, i))
+			go send(http.MethodPost, "/api/v1/units", fmt.Sprintf(`{"qr_code":"PLT-%03d","name":"Pallet"}`, i))
+		}
+
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(30 * time.Second):
+			t.Fatal("concurrent audited writes did not finish")
+		}
+		close(codes)
+		for code := range codes {
+			if code != http.StatusOK && code != http.StatusCreated {
+				t.Fatalf("unexpected status %d", code)
+			}
+		}
+
+		var result services.AuditVerification
+		w := doRequest(t, token, http.MethodGet, "/api/v1/audit/verify", nil)
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &result)
+		if !result.Valid || result.Checked != 3*requests {
+			t.Fatalf("audit chain after concurrent appends: %+v", result)
+		}
+	})
+}
+
+func TestSecurityEvents(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		var events []middleware.SecurityEvent
+		w := doRequest(t, login(t, "admin"), http.MethodGet, "/api/v1/security/events?type=AUTH_FAILURE", nil)
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &events)
+
+		expectStatus(t, doRequest(t, login(t, "admin"), http.MethodGet, "/api/v1/security/events?limit=0", nil), http.StatusBadRequest)
+		expectStatus(t, doRequest(t, login(t, "scanner"), http.MethodGet, "/api/v1/security/events", nil), http.StatusForbidden)
+	})
+}
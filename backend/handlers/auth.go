@@ -1,11 +1,15 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"scandata/apierror"
 	"scandata/config"
-	"scandata/database"
+	"scandata/i18n"
+	"scandata/metrics"
 	"scandata/middleware"
 	"scandata/models"
+	"scandata/repository"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,6 +18,7 @@ import (
 
 type AuthHandler struct {
 	Config *config.Config
+	Store  repository.Store
 }
 
 type LoginRequest struct {
@@ -26,25 +31,25 @@ type LoginResponse struct {
 	User  models.User `json:"user"`
 }
 
-func NewAuthHandler(cfg *config.Config) *AuthHandler {
-	return &AuthHandler{Config: cfg}
+func NewAuthHandler(cfg *config.Config, store repository.Store) *AuthHandler {
+	return &AuthHandler{Config: cfg, Store: store}
 }
 
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		apierror.Abort(c, apierror.Binding(err))
 		return
 	}
 
-	var user models.User
-	if err := database.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+	user, err := h.Store.Users().GetByUsername(c.Request.Context(), req.Username)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		apierror.Abort(c, apierror.Internal("Failed to log in", err))
 		return
 	}
-
-	if !user.CheckPassword(req.Password) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+	if err != nil || !user.CheckPassword(req.Password) {
+		metrics.AuthFailures.WithLabelValues(metrics.AuthInvalidCredentials).Inc()
+		apierror.Abort(c, apierror.Unauthorized("Invalid credentials"))
 		return
 	}
 
@@ -62,47 +67,94 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, err := token.SignedString([]byte(h.Config.JWTSecret))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		apierror.Abort(c, apierror.Internal("Failed to generate token", err))
 		return
 	}
 
 	c.JSON(http.StatusOK, LoginResponse{
 		Token: tokenString,
-		User:  user,
+		User:  *user,
 	})
 }
 
 func (h *AuthHandler) Me(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		apierror.Abort(c, apierror.Unauthorized("User not found"))
 		return
 	}
 	c.JSON(http.StatusOK, user)
 }
 
-func (h *AuthHandler) ChangePassword(c *gin.Context) {
-	var req struct {
-		OldPassword string `json:"old_password" binding:"required"`
-		NewPassword string `json:"new_password" binding:"required,min=6"`
+type UpdateMeRequest struct {
+	Locale *string `json:"locale"` // "" follows Accept-Language again
+}
+
+// UpdateMe - Simpan preferensi bahasa user yang sedang login
+func (h *AuthHandler) UpdateMe(c *gin.Context) {
+	var req UpdateMeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, apierror.Binding(err))
+		return
+	}
+
+	user := c.MustGet("user").(models.User)
+	before := user
+	if req.Locale != nil {
+		locale, err := parseLocale(*req.Locale)
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+		user.Locale = locale
+	}
+
+	err := h.Store.Transaction(c.Request.Context(), func(tx repository.Store) error {
+		if err := tx.Users().Update(c.Request.Context(), &user); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, models.AuditUpdate, "user", user.ID, before, user)
+	})
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to update user", err))
+		return
 	}
+	c.JSON(http.StatusOK, user)
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		apierror.Abort(c, apierror.Binding(err))
 		return
 	}
 
 	user := c.MustGet("user").(models.User)
 
 	if !user.CheckPassword(req.OldPassword) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Old password is incorrect"})
+		apierror.Abort(c, apierror.Invalid("old_password", "Old password is incorrect"))
 		return
 	}
 
 	if err := user.SetPassword(req.NewPassword); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set password"})
+		apierror.Abort(c, apierror.Internal("Failed to set password", err))
 		return
 	}
 
-	database.DB.Save(&user)
-	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
+	err := h.Store.Transaction(c.Request.Context(), func(tx repository.Store) error {
+		if err := tx.Users().Update(c.Request.Context(), &user); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, models.AuditPasswordChange, "user", user.ID, nil, nil)
+	})
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to change password", err))
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: i18n.T(c.Request.Context(), "Password changed successfully")})
 }
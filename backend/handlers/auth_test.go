@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+	"scandata/models"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMeAndChangePassword(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		token := login(t, "scanner")
+
+		var me models.User
+		w := doRequest(t, token, http.MethodGet, "/api/v1/auth/me", nil)
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &me)
+		if me.Username != "scanner" || me.Role != models.RoleUser {
+			t.Fatalf("unexpected user %+v", me)
+		}
+
+		expectStatus(t, doRequest(t, token, http.MethodPost, "/api/v1/auth/change-password", gin.H{"old_password": "wrong", "new_password": "secret2"}), http.StatusBadRequest)
+		expectStatus(t, doRequest(t, token, http.MethodPost, "/api/v1/auth/change-password", gin.H{"old_password": "password", "new_password": "secret2"}), http.StatusOK)
+
+		expectStatus(t, doRequest(t, "", http.MethodPost, "/api/v1/auth/login", gin.H{"username": "scanner", "password": "password"}), http.StatusUnauthorized)
+		expectStatus(t, doRequest(t, "", http.MethodPost, "/api/v1/auth/login", gin.H{"username": "scanner", "password": "secret2"}), http.StatusOK)
+
+		expectStatus(t, doRequest(t, "", http.MethodGet, "/api/v1/auth/me", nil), http.StatusUnauthorized)
+		expectStatus(t, doRequest(t, "not-a-token", http.MethodGet, "/api/v1/auth/me", nil), http.StatusUnauthorized)
+	})
+}
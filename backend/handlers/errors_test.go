@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"scandata/apierror"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestErrorFormat(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		token := login(t, "admin")
+		expectError := func(method, path string, body interface{}, status int, code string) apierror.Error {
+			t.Helper()
+			w := doRequest(t, token, method, path, body)
+			expectStatus(t, w, status)
+			var e apierror.Error
+			decode(t, w, &e)
+			if e.Code != code || e.Message == "" || e.RequestID == "" {
+				t.Fatalf("expected code %s with message and request ID, got %s", code, w.Body.String())
+			}
+			return e
+		}
+
+		// Every invalid field is named by its JSON name, without Go internals
+		e := expectError(http.MethodPost, "/api/v1/users", gin.H{"username": "u", "password": "123", "role": "root"},
+			http.StatusBadRequest, apierror.CodeValidation)
+		fields := map[string]string{}
+		for _, f := range e.Fields {
+			fields[f.Field] = f.Code
+		}
+		if len(fields) != 3 || fields["password"] != "min" || fields["name"] != "required" || fields["role"] != "oneof" {
+			t.Fatalf("unexpected field errors %+v", e.Fields)
+		}
+		e = expectError(http.MethodPost, "/api/v1/units", gin.H{"qr_code": 7, "name": "Pallet"},
+			http.StatusBadRequest, apierror.CodeValidation)
+		if len(e.Fields) != 1 || e.Fields[0].Field != "qr_code" || e.Fields[0].Code != "type" {
+			t.Fatalf("unexpected type error %+v", e)
+		}
+
+		// A taken QR code is a conflict on that field
+		expectStatus(t, doRequest(t, token, http.MethodPost, "/api/v1/units", gin.H{"qr_code": "PLT-900", "name": "Pallet"}), http.StatusCreated)
+		e = expectError(http.MethodPost, "/api/v1/units", gin.H{"qr_code": "PLT-900", "name": "Copy"},
+			http.StatusConflict, apierror.CodeDuplicate)
+		if len(e.Fields) != 1 || e.Fields[0].Field != "qr_code" {
+			t.Fatalf("unexpected duplicate error %+v", e)
+		}
+
+		expectError(http.MethodGet, "/api/v1/units/999999", nil, http.StatusNotFound, apierror.CodeNotFound)
+		expectError(http.MethodGet, "/api/v1/nothing", nil, http.StatusNotFound, apierror.CodeRouteNotFound)
+		expectError(http.MethodDelete, "/api/v1/auth/login", nil, http.StatusMethodNotAllowed, apierror.CodeMethodNotAllowed)
+
+		w := doRequest(t, "", http.MethodGet, "/api/v1/units", nil)
+		expectStatus(t, w, http.StatusUnauthorized)
+		var unauthorized apierror.Error
+		decode(t, w, &unauthorized)
+		if unauthorized.Code != apierror.CodeUnauthorized {
+			t.Fatalf("unexpected 401 body %s", w.Body.String())
+		}
+	})
+}
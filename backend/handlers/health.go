@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"scandata/config"
+	"scandata/database"
+	"scandata/health"
+	"scandata/logging"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	Config *config.Config
+}
+
+func NewHealthHandler(cfg *config.Config) *HealthHandler {
+	return &HealthHandler{Config: cfg}
+}
+
+type LiveStatus struct {
+	Status string `json:"status"`
+	Mode   string `json:"mode"`
+}
+
+type ReadinessReport struct {
+	Status     string                      `json:"status"`
+	Components map[string]health.Component `json:"components"`
+	Workers    map[string]health.Component `json:"workers"`
+}
+
+// Live - Proses berjalan dan bisa melayani request, tanpa cek dependensi
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, LiveStatus{Status: "ok", Mode: h.Config.GinMode})
+}
+
+// Ready - Cek database, skema dan background worker; 503 jika ada yang down
+func (h *HealthHandler) Ready(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.Config.HealthCheckTimeout)
+	defer cancel()
+
+	if health.ShuttingDown() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "shutting_down"})
+		return
+	}
+
+	report := ReadinessReport{
+		Status:     "ok",
+		Components: make(map[string]health.Component),
+		Workers:    health.Workers(),
+	}
+
+	report.Components["database"] = probe(ctx, "database", func() error {
+		sqlDB, err := database.DB.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	})
+
+	// Skip the schema check when the database is unreachable; it would only repeat the error
+	if report.Components["database"].Status == health.StatusUp {
+		report.Components["migrations"] = probe(ctx, "migrations", func() error {
+			return database.CheckSchema(ctx)
+		})
+	} else {
+		report.Components["migrations"] = health.Component{Status: health.StatusDown, Error: "database unavailable"}
+	}
+
+	status := http.StatusOK
+	for _, group := range []map[string]health.Component{report.Components, report.Workers} {
+		for _, component := range group {
+			if component.Status != health.StatusUp {
+				report.Status = "degraded"
+				status = http.StatusServiceUnavailable
+			}
+		}
+	}
+
+	c.JSON(status, report)
+}
+
+// probe times check. The readiness endpoint is public, so the cause of a
+// failure is logged rather than returned: driver errors name hosts and users.
+func probe(ctx context.Context, name string, check func() error) health.Component {
+	start := time.Now()
+	err := check()
+	component := health.Component{Status: health.StatusUp, Latency: time.Since(start).Round(time.Microsecond).String()}
+	if err != nil {
+		component.Status = health.StatusDown
+		logging.Logger().LogAttrs(ctx, slog.LevelError, "readiness check failed",
+			slog.String("component", name), slog.String("error", err.Error()))
+	}
+	return component
+}
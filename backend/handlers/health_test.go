@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"scandata/health"
+	"scandata/logging"
+	"strings"
+	"testing"
+)
+
+func TestProbeHidesCause(t *testing.T) {
+	var logs bytes.Buffer
+	logging.SetOutput(&logs)
+	t.Cleanup(func() { logging.SetOutput(os.Stdout) })
+
+	cause := "dial tcp db.internal:3306: Access denied for user 'scandata'"
+	component := probe(context.Background(), "database", func() error { return errors.New(cause) })
+	if component.Status != health.StatusDown || component.Error != "" || component.Latency == "" {
+		t.Fatalf("unexpected component %+v", component)
+	}
+	if !strings.Contains(logs.String(), "db.internal:3306") || !strings.Contains(logs.String(), `"component":"database"`) {
+		t.Fatalf("cause not logged: %s", logs.String())
+	}
+}
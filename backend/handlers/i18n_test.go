@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"scandata/apierror"
+	"scandata/models"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestLocale(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		token := login(t, "scanner")
+		get := func(path, acceptLanguage string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			if acceptLanguage != "" {
+				req.Header.Set("Accept-Language", acceptLanguage)
+			}
+			w := httptest.NewRecorder()
+			testRouter.ServeHTTP(w, req)
+			return w
+		}
+		message := func(w *httptest.ResponseRecorder) string {
+			t.Helper()
+			var e apierror.Error
+			decode(t, w, &e)
+			return e.Message
+		}
+
+		// Accept-Language picks the language, English by default
+		if got := message(get("/api/v1/scans/999999", "")); got != "Scan not found" {
+			t.Fatalf("unexpected default message %q", got)
+		}
+		w := get("/api/v1/scans/999999", "fr-FR, id-ID;q=0.8, en;q=0.5")
+		if got := message(w); got != "Scan tidak ditemukan" || w.Header().Get("Content-Language") != "id" {
+			t.Fatalf("unexpected Indonesian message %q", got)
+		}
+		if got := message(get("/api/v1/scans?limit=0", "id")); got != "limit harus berupa angka positif" {
+			t.Fatalf("unexpected field message %q", got)
+		}
+
+		// A saved preference wins over the header
+		expectStatus(t, doRequest(t, token, http.MethodPut, "/api/v1/auth/me", gin.H{"locale": "xx"}), http.StatusBadRequest)
+		var me models.User
+		w = doRequest(t, token, http.MethodPut, "/api/v1/auth/me", gin.H{"locale": "id"})
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &me)
+		if me.Locale != "id" {
+			t.Fatalf("locale not saved: %+v", me)
+		}
+		if got := message(get("/api/v1/scans/999999", "en")); got != "Scan tidak ditemukan" {
+			t.Fatalf("preference ignored: %q", got)
+		}
+		expectStatus(t, doRequest(t, token, http.MethodPut, "/api/v1/auth/me", gin.H{"locale": ""}), http.StatusOK)
+		if got := message(get("/api/v1/scans/999999", "en")); got != "Scan not found" {
+			t.Fatalf("preference not cleared: %q", got)
+		}
+
+		// Export headers and labels follow the locale too
+		token = login(t, "admin")
+		expectStatus(t, doRequest(t, token, http.MethodPost, "/api/v1/scans", gin.H{"barcode": "4006381333931", "is_match": false}), http.StatusCreated)
+		for _, tc := range []struct{ lang, sheet, header, label string }{
+			{"en", "Scan Report", "Date", "Mismatch"},
+			{"id", "Laporan Scan", "Tanggal", "Tidak Sesuai"},
+		} {
+			w := get("/api/v1/reports/export", tc.lang)
+			expectStatus(t, w, http.StatusOK)
+			f, err := excelize.OpenReader(w.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			header, _ := f.GetCellValue(tc.sheet, "B1")
+			label, _ := f.GetCellValue(tc.sheet, "H2")
+			if header != tc.header || label != tc.label {
+				t.Fatalf("%s export: header %q, label %q", tc.lang, header, label)
+			}
+		}
+	})
+}
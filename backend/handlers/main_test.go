@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"scandata/apierror"
+	"scandata/config"
+	"scandata/database"
+	"scandata/middleware"
+	"scandata/models"
+	"scandata/repository"
+	"scandata/services"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Every handler test runs twice: against the in-memory store, and against a
+// SQLite database created from the same migrations as production so dialect
+// issues in queries show up too. TestMain fails the run if a registered route
+// was never requested.
+
+var (
+	testConfig   *config.Config
+	testBarcodes *services.BarcodeRegistry
+	testStore    repository.Store
+	testLive     *config.Live
+	testRouter   *gin.Engine
+
+	coveredRoutes = make(map[string]bool)
+)
+
+type testBackend struct {
+	name string
+	open func(t *testing.T) repository.Store
+}
+
+var testBackends = []testBackend{
+	{"memory", func(*testing.T) repository.Store { return repository.NewMemoryStore() }},
+	{"sqlite", func(t *testing.T) repository.Store {
+		// Raw deletes bypass the immutability hooks on revisions and audit rows
+		for _, table := range []string{"audit_logs", "settings", "scan_revisions", "saved_views", "scan_logs", "shifts", "units", "users"} {
+			if err := database.DB.Exec("DELETE FROM " + table).Error; err != nil {
+				t.Fatalf("reset %s: %v", table, err)
+			}
+		}
+		if err := database.DB.Exec("UPDATE audit_chain_heads SET hash = ''").Error; err != nil {
+			t.Fatalf("reset audit chain head: %v", err)
+		}
+		return repository.NewGormStore(database.DB)
+	}},
+}
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+
+	dir, err := os.MkdirTemp("", "scandata-test")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	testConfig = &config.Config{
+		DBDriver:            config.DriverSQLite,
+		DBPath:              filepath.Join(dir, "test.db"),
+		JWTSecret:           "test-secret",
+		JWTExpiryHours:      1,
+		DuplicateScanPolicy: config.DuplicatePolicyFlag,
+		ScanEditWindow:      15 * time.Minute,
+		BarcodeFormats:      []string{"gs1", "ean13", "upca", "ean8", "code128"},
+		ShiftIdleThreshold:  10 * time.Minute,
+		Runtime: config.Runtime{
+			RateLimitRequests:   100,
+			RateLimitDuration:   time.Minute,
+			LogLevel:            "error",
+			DuplicateScanWindow: 5 * time.Second,
+			ReportTimezone:      "Local",
+		},
+	}
+
+	database.Connect(testConfig)
+	migrator, err := database.NewMigrator(database.DB)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		log.Fatalf("migrate: %v", err)
+	}
+
+	testBarcodes, err = services.NewBarcodeRegistry(testConfig.BarcodeFormats, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	code := m.Run()
+	if code == 0 && flag.Lookup("test.run").Value.String() == "" {
+		for _, route := range newTestRouter(repository.NewMemoryStore()).Routes() {
+			// The legacy paths run the v1 handlers; TestAPIVersions checks the alias
+			path := route.Path
+			if rest, ok := strings.CutPrefix(path, LegacyPrefix+"/"); ok {
+				path = "/api/v1/" + rest
+			}
+			if !coveredRoutes[route.Method+" "+path] && !coveredRoutes[route.Method+" "+route.Path] {
+				fmt.Printf("FAIL: no test requests %s %s\n", route.Method, route.Path)
+				code = 1
+			}
+		}
+	}
+
+	database.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func newTestRouter(store repository.Store) *gin.Engine {
+	r := gin.New()
+	r.Use(middleware.RequestIDMiddleware())
+	r.Use(middleware.LocaleMiddleware(testConfig))
+	r.Use(func(c *gin.Context) {
+		c.Next()
+		if c.FullPath() != "" {
+			coveredRoutes[c.Request.Method+" "+c.FullPath()] = true
+		}
+	})
+	testLive = config.NewLive(testConfig.Runtime)
+	RegisterRoutes(r, testConfig, testLive, store, testBarcodes)
+	r.HandleMethodNotAllowed = true
+	r.NoRoute(apierror.NoRoute)
+	r.NoMethod(apierror.NoMethod)
+	return r
+}
+
+// forEachStore runs test once per backend on an empty store holding an
+// admin and a regular user, both with password "password"
+func forEachStore(t *testing.T, test func(t *testing.T)) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			testStore = backend.open(t)
+			testRouter = newTestRouter(testStore)
+
+			admin := models.User{Username: "admin", Name: "Admin", Role: models.RoleAdmin}
+			user := models.User{Username: "scanner", Name: "Scanner", Role: models.RoleUser}
+			for _, u := range []*models.User{&admin, &user} {
+				u.SetPassword("password")
+				if err := testStore.Users().Create(context.Background(), u); err != nil {
+					t.Fatalf("create %s: %v", u.Username, err)
+				}
+			}
+
+			test(t)
+		})
+	}
+}
+
+func login(t *testing.T, username string) string {
+	t.Helper()
+
+	w := doRequest(t, "", http.MethodPost, "/api/v1/auth/login", gin.H{"username": username, "password": "password"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("login %s: %d %s", username, w.Code, w.Body.String())
+	}
+	var resp LoginResponse
+	decode(t, w, &resp)
+	return resp.Token
+}
+
+func doRequest(t *testing.T, token, method, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(raw)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+	return w
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode %s: %v", w.Body.String(), err)
+	}
+}
+
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("expected status %d, got %d: %s", status, w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"scandata/apierror"
+	"scandata/middleware"
+	"scandata/models"
+	"scandata/openapi"
+	"scandata/repository"
+	"scandata/services"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIRoutes documents the routes of v1, relative to its prefix. TestOpenAPI
+// fails when a registered route is missing here.
+var APIRoutes = []openapi.Route{
+	// Auth
+	{Method: "POST", Path: "/auth/login", Tag: "auth", Summary: "Log in and receive a JWT", Access: openapi.Public,
+		Request: LoginRequest{}, Response: LoginResponse{}},
+	{Method: "GET", Path: "/auth/me", Tag: "auth", Summary: "The signed-in user", Access: openapi.Authenticated,
+		Response: models.User{}},
+	{Method: "PUT", Path: "/auth/me", Tag: "auth", Summary: "Save the language preference of the signed-in user", Access: openapi.Authenticated,
+		Request: UpdateMeRequest{}, Response: models.User{}},
+	{Method: "POST", Path: "/auth/change-password", Tag: "auth", Summary: "Change the own password", Access: openapi.Authenticated,
+		Request: ChangePasswordRequest{}, Response: MessageResponse{}},
+
+	// Users
+	{Method: "GET", Path: "/users", Tag: "users", Summary: "List users", Access: openapi.Admin,
+		Response: []models.User{}, Sort: sortFields(repository.UserSortFields)},
+	{Method: "POST", Path: "/users", Tag: "users", Summary: "Create a user", Access: openapi.Admin,
+		Request: CreateUserRequest{}, Response: models.User{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/users/:id", Tag: "users", Summary: "Get a user", Access: openapi.Admin,
+		Response: models.User{}},
+	{Method: "PUT", Path: "/users/:id", Tag: "users", Summary: "Update a user; empty fields are kept", Access: openapi.Admin,
+		Request: UpdateUserRequest{}, Response: models.User{}},
+	{Method: "DELETE", Path: "/users/:id", Tag: "users", Summary: "Delete a user", Access: openapi.Admin,
+		Response: MessageResponse{}},
+
+	// Units
+	{Method: "GET", Path: "/units", Tag: "units", Summary: "List units", Access: openapi.Authenticated,
+		Query: []openapi.Parameter{
+			openapi.QueryString("search", "Matches name, QR code or location"),
+			openapi.QueryBool("active", "Only active or inactive units"),
+		},
+		Response: []models.Unit{}, Sort: sortFields(repository.UnitSortFields)},
+	{Method: "GET", Path: "/units/qr/:qr_code", Tag: "units", Summary: "Find a unit by QR code", Access: openapi.Authenticated,
+		Response: models.Unit{}},
+	{Method: "GET", Path: "/units/:id", Tag: "units", Summary: "Get a unit", Access: openapi.Authenticated,
+		Response: models.Unit{}},
+	{Method: "POST", Path: "/units", Tag: "units", Summary: "Create a unit", Access: openapi.Admin,
+		Request: CreateUnitRequest{}, Response: models.Unit{}, Status: http.StatusCreated},
+	{Method: "PUT", Path: "/units/:id", Tag: "units", Summary: "Update a unit; empty fields are kept", Access: openapi.Admin,
+		Request: UpdateUnitRequest{}, Response: models.Unit{}},
+	{Method: "DELETE", Path: "/units/:id", Tag: "units", Summary: "Delete a unit", Access: openapi.Admin,
+		Response: MessageResponse{}},
+
+	// Shifts
+	{Method: "GET", Path: "/shifts", Tag: "shifts", Summary: "List shifts", Access: openapi.Authenticated,
+		Response: []models.Shift{}},
+	{Method: "POST", Path: "/shifts", Tag: "shifts", Summary: "Create a shift", Access: openapi.Admin,
+		Request: CreateShiftRequest{}, Response: models.Shift{}, Status: http.StatusCreated},
+	{Method: "PUT", Path: "/shifts/:id", Tag: "shifts", Summary: "Update a shift", Access: openapi.Admin,
+		Request: UpdateShiftRequest{}, Response: models.Shift{}},
+	{Method: "DELETE", Path: "/shifts/:id", Tag: "shifts", Summary: "Delete a shift", Access: openapi.Admin,
+		Response: MessageResponse{}},
+	{Method: "POST", Path: "/shifts/retag", Tag: "shifts", Summary: "Recompute the shift of scans in a range", Access: openapi.Admin,
+		Query: []openapi.Parameter{
+			{Name: "start", In: "query", Required: true, Description: "YYYY-MM-DD or RFC3339", Schema: &openapi.Schema{Type: "string"}},
+			openapi.QueryString("end", "YYYY-MM-DD or RFC3339, default now"),
+		},
+		Response: RetagResponse{}},
+
+	// Scans
+	{Method: "POST", Path: "/scans", Tag: "scans", Summary: "Submit a scan; a repeat within DUPLICATE_SCAN_WINDOW may return the original with 200", Access: openapi.Authenticated,
+		Request: SubmitScanRequest{}, Response: models.ScanLog{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/scans", Tag: "scans", Summary: "List scans; users see only their own", Access: openapi.Authenticated,
+		Query: scanFilterParameters, Response: []models.ScanLog{}, Sort: sortFields(repository.ScanSortFields)},
+	{Method: "GET", Path: "/scans/stats", Tag: "scans", Summary: "Scan counts of today", Access: openapi.Authenticated,
+		Response: ScanStats{}},
+	{Method: "GET", Path: "/scans/views", Tag: "scans", Summary: "List the saved views of the signed-in user", Access: openapi.Authenticated,
+		Response: []SavedViewResponse{}},
+	{Method: "POST", Path: "/scans/views", Tag: "scans", Summary: "Save a filter as a view", Access: openapi.Authenticated,
+		Request: CreateSavedViewRequest{}, Response: SavedViewResponse{}, Status: http.StatusCreated},
+	{Method: "DELETE", Path: "/scans/views/:id", Tag: "scans", Summary: "Delete a saved view", Access: openapi.Authenticated,
+		Response: MessageResponse{}},
+	{Method: "GET", Path: "/scans/:id", Tag: "scans", Summary: "Get a scan with its revisions", Access: openapi.Authenticated,
+		Response: ScanDetail{}},
+	{Method: "PATCH", Path: "/scans/:id", Tag: "scans", Summary: "Correct a scan within the edit window", Access: openapi.Authenticated,
+		Request: AmendScanRequest{}, Response: models.ScanLog{}},
+	{Method: "POST", Path: "/scans/:id/void", Tag: "scans", Summary: "Void a scan", Access: openapi.Authenticated,
+		Request: VoidScanRequest{}, Response: models.ScanLog{}},
+
+	// Reports
+	{Method: "GET", Path: "/reports/summary", Tag: "reports", Summary: "Scan counts of today, this week and this month", Access: openapi.Authenticated,
+		Response: ReportSummary{}},
+	{Method: "GET", Path: "/reports/daily", Tag: "reports", Summary: "Scan counts per day", Access: openapi.Authenticated,
+		Query:    []openapi.Parameter{openapi.QueryInt("days", "Number of days, at most 30 (default 7)")},
+		Response: []DailyReport{}},
+	{Method: "GET", Path: "/reports/timeseries", Tag: "reports", Summary: "Scan counts per interval", Access: openapi.Authenticated,
+		Query: []openapi.Parameter{
+			openapi.QueryString("start", "YYYY-MM-DD or RFC3339, default 30 days ago"),
+			openapi.QueryString("end", "YYYY-MM-DD or RFC3339, default now"),
+			openapi.QueryEnum("interval", "Bucket size (default day)", "hour", "day", "week", "month"),
+			openapi.QueryEnum("group_by", "One series per group", "user", "unit", "location", "grade"),
+		},
+		Response: TimeSeriesReport{}},
+	{Method: "GET", Path: "/reports/users", Tag: "reports", Summary: "Scan counts and mismatch rate per user", Access: openapi.Admin,
+		Query: []openapi.Parameter{
+			openapi.QueryString("start", "YYYY-MM-DD or RFC3339, default today"),
+			openapi.QueryString("end", "YYYY-MM-DD or RFC3339, default now"),
+			openapi.QueryBool("by_shift", "Add a breakdown per shift"),
+		},
+		Response: []UserPerformance{}},
+	{Method: "GET", Path: "/reports/shifts", Tag: "reports", Summary: "Productivity per shift", Access: openapi.Admin,
+		Query: []openapi.Parameter{
+			openapi.QueryString("start", "YYYY-MM-DD, default 7 days ago"),
+			openapi.QueryString("end", "YYYY-MM-DD, default today"),
+		},
+		Response: []ShiftReport{}},
+	{Method: "GET", Path: "/reports/export", Tag: "reports", Summary: "Export scans as Excel", Access: openapi.Admin,
+		Query: append([]openapi.Parameter{
+			openapi.QueryString("start_date", "YYYY-MM-DD (legacy, use from)"),
+			openapi.QueryString("end_date", "YYYY-MM-DD (legacy, use to)"),
+		}, scanFilterParameters...),
+		ContentType: xlsxContentType},
+
+	// Audit trail
+	{Method: "GET", Path: "/audit", Tag: "audit", Summary: "List audit entries", Access: openapi.Admin,
+		Query: auditFilterParameters, Response: []models.AuditLog{}, Sort: sortFields(repository.AuditSortFields)},
+	{Method: "GET", Path: "/audit/export", Tag: "audit", Summary: "Export audit entries as Excel", Access: openapi.Admin,
+		Query: auditFilterParameters, ContentType: xlsxContentType},
+	{Method: "GET", Path: "/audit/verify", Tag: "audit", Summary: "Check the hash chain of the audit log", Access: openapi.Admin,
+		Response: services.AuditVerification{}},
+
+	// Security and settings
+	{Method: "GET", Path: "/security/events", Tag: "admin", Summary: "Search the security log", Access: openapi.Admin,
+		Query: []openapi.Parameter{
+			openapi.QueryString("type", "Event type"),
+			openapi.QueryString("ip", "Client IP"),
+			openapi.QueryString("user", "Username"),
+			openapi.QueryString("path", "Request path"),
+			openapi.QueryString("request_id", "X-Request-ID of the request"),
+			openapi.QueryString("since", "YYYY-MM-DD or RFC3339"),
+			openapi.QueryString("until", "YYYY-MM-DD or RFC3339"),
+			openapi.QueryInt("limit", "Newest events to return"),
+		},
+		Response: []middleware.SecurityEvent{}},
+	{Method: "GET", Path: "/settings", Tag: "admin", Summary: "List runtime settings", Access: openapi.Admin,
+		Response: []SettingResponse{}},
+	{Method: "PUT", Path: "/settings", Tag: "admin", Summary: `Save runtime settings as {"KEY": "value"}; an empty value removes the override`, Access: openapi.Admin,
+		Request: map[string]string{}, Response: []SettingResponse{}},
+}
+
+// versionRoutes documents the routes each version after v1 changes or
+// adds, like APIVersion.Next and Handle do for the handlers
+var versionRoutes = map[string][]openapi.Route{}
+
+// serviceRoutes documents the unversioned routes: the docs themselves and
+// the health and /metrics routes of main.go
+var serviceRoutes = []openapi.Route{
+	// Documentation
+	{Method: "GET", Path: "/api/openapi.json", Tag: "docs", Summary: "This document", Access: openapi.Public,
+		Response: &openapi.Schema{Type: "object"}},
+	{Method: "GET", Path: "/api/docs", Tag: "docs", Summary: "API docs page", Access: openapi.Public,
+		ContentType: "text/html"},
+	{Method: "GET", Path: "/api/docs/:file", Tag: "docs", Summary: "Script and style of the docs page", Access: openapi.Public,
+		ContentType: "text/plain"},
+
+	// Operations
+	{Method: "GET", Path: "/health", Tag: "health", Summary: "Alias of /health/live", Access: openapi.Public,
+		Response: LiveStatus{}},
+	{Method: "GET", Path: "/health/live", Tag: "health", Summary: "The process is up", Access: openapi.Public,
+		Response: LiveStatus{}},
+	{Method: "GET", Path: "/health/ready", Tag: "health", Summary: "Database, schema and workers are usable; 503 otherwise", Access: openapi.Public,
+		Response: ReadinessReport{}},
+	{Method: "GET", Path: "/metrics", Tag: "health", Summary: "Prometheus metrics, when METRICS_TOKEN is set and METRICS_ADDR is not", Access: openapi.Authenticated,
+		ContentType: "text/plain"},
+}
+
+var apiTags = []openapi.Tag{
+	{Name: "auth"}, {Name: "users"}, {Name: "units"}, {Name: "shifts"}, {Name: "scans"},
+	{Name: "reports"}, {Name: "audit"}, {Name: "admin"}, {Name: "docs"}, {Name: "health"},
+}
+
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+var scanFilterParameters = []openapi.Parameter{
+	openapi.QueryInt("view", "Saved view to start from; other filters override it"),
+	openapi.QueryString("from", "YYYY-MM-DD or RFC3339"),
+	openapi.QueryString("to", "YYYY-MM-DD or RFC3339, a bare date is inclusive"),
+	openapi.QueryString("date", "Single day YYYY-MM-DD (legacy)"),
+	openapi.QueryString("user_id", "Comma separated or repeated"),
+	openapi.QueryString("unit_id", "Comma separated or repeated"),
+	openapi.QueryString("location", "Comma separated or repeated"),
+	openapi.QueryString("grade", "Comma separated or repeated"),
+	openapi.QueryString("barcode", "Exact barcode"),
+	openapi.QueryString("gtin", "GTIN of GS1 barcodes"),
+	openapi.QueryString("lot", "Lot of GS1 barcodes"),
+	openapi.QueryString("q", "Search in notes"),
+	openapi.QueryBool("is_match", "Only matching or mismatching scans"),
+	openapi.QueryBool("include_voided", "Include voided scans"),
+}
+
+var auditFilterParameters = []openapi.Parameter{
+	openapi.QueryString("actor_id", "User who made the change"),
+	openapi.QueryString("action", "create, update, delete, ..."),
+	openapi.QueryString("entity_type", "user, unit, scan, ..."),
+	openapi.QueryString("entity_id", "ID of the entity"),
+	openapi.QueryString("request_id", "X-Request-ID of the request"),
+	openapi.QueryString("from", "YYYY-MM-DD or RFC3339"),
+	openapi.QueryString("to", "YYYY-MM-DD or RFC3339"),
+}
+
+// documentedRoutes lists every route under the path it is served at: each
+// version, the deprecated legacy alias of v1 and the service routes
+func documentedRoutes() []openapi.Route {
+	var routes []openapi.Route
+	add := func(prefix string, table []openapi.Route, deprecated bool) {
+		for _, route := range table {
+			route.Path = prefix + route.Path
+			route.Deprecated = deprecated
+			routes = append(routes, route)
+		}
+	}
+
+	current := APIRoutes
+	for _, name := range APIVersionNames {
+		current = mergeRoutes(current, versionRoutes[name])
+		add("/api/"+name, current, false)
+	}
+	add(LegacyPrefix, APIRoutes, true)
+	return append(routes, serviceRoutes...)
+}
+
+// mergeRoutes returns base with the routes of changes replacing those of
+// the same method and path
+func mergeRoutes(base, changes []openapi.Route) []openapi.Route {
+	merged := append([]openapi.Route(nil), base...)
+next:
+	for _, change := range changes {
+		for i, route := range merged {
+			if route.Method == change.Method && route.Path == change.Path {
+				merged[i] = change
+				continue next
+			}
+		}
+		merged = append(merged, change)
+	}
+	return merged
+}
+
+func sortFields(fields map[string]repository.SortField) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DocsHandler serves the OpenAPI document and the docs page rendering it
+type DocsHandler struct {
+	spec  []byte
+	index []byte
+	page  fs.FS
+}
+
+func NewDocsHandler() *DocsHandler {
+	doc := openapi.Build(openapi.Info{
+		Title:       "ScanData API",
+		Version:     "1.0.0",
+		Description: "Errors share one format, see the Error schema. Messages follow Accept-Language (en or id).",
+	}, apiTags, apierror.Error{}, documentedRoutes())
+	spec, err := json.Marshal(doc)
+	if err != nil {
+		panic(err)
+	}
+	page, err := fs.Sub(openapi.Docs, "docs")
+	if err != nil {
+		panic(err)
+	}
+	index, err := fs.ReadFile(page, "index.html")
+	if err != nil {
+		panic(err)
+	}
+	return &DocsHandler{spec: spec, index: index, page: page}
+}
+
+func (h *DocsHandler) Spec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", h.spec)
+}
+
+func (h *DocsHandler) Page(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", h.index)
+}
+
+// Asset - docs.js dan docs.css untuk halaman docs
+func (h *DocsHandler) Asset(c *gin.Context) {
+	switch file := c.Param("file"); file {
+	case "docs.js", "docs.css":
+		c.FileFromFS(file, http.FS(h.page))
+	default:
+		apierror.Abort(c, apierror.NotFound("Route not found"))
+	}
+}
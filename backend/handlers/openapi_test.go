@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"scandata/openapi"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPI(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		get := func(path string) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			testRouter.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			return w
+		}
+
+		w := get("/api/openapi.json")
+		expectStatus(t, w, http.StatusOK)
+		var doc openapi.Document
+		decode(t, w, &doc)
+		if doc.OpenAPI != "3.0.3" || doc.Components.Schemas["LoginRequest"] == nil {
+			t.Fatalf("unexpected document: %s %v", doc.OpenAPI, doc.Components.Schemas["LoginRequest"])
+		}
+
+		// Every registered route is documented, and every documented route
+		// outside main.go is registered
+		registered := make(map[string]bool)
+		for _, route := range testRouter.Routes() {
+			registered[route.Method+" "+route.Path] = true
+			if !doc.Has(route.Method, route.Path) {
+				t.Errorf("%s %s is not in the OpenAPI document", route.Method, route.Path)
+			}
+		}
+		for _, route := range documentedRoutes() {
+			if strings.HasPrefix(route.Path, "/api/") && !registered[route.Method+" "+route.Path] {
+				t.Errorf("%s %s is documented but not registered", route.Method, route.Path)
+			}
+		}
+
+		login := doc.Paths["/api/v1/auth/login"]
+		if login == nil || (*login)["post"].RequestBody == nil || (*login)["post"].Security != nil {
+			t.Fatalf("login operation: %+v", login)
+		}
+		if users := doc.Paths["/api/v1/users/{id}"]; users == nil || (*users)["get"].Responses["403"] == nil {
+			t.Fatalf("admin operation without 403: %+v", users)
+		}
+		if legacy := doc.Paths["/api/units/{id}"]; legacy == nil || !(*legacy)["get"].Deprecated {
+			t.Fatalf("legacy operation not deprecated: %+v", legacy)
+		}
+
+		w = get("/api/docs")
+		expectStatus(t, w, http.StatusOK)
+		if !strings.Contains(w.Body.String(), "docs/docs.js") {
+			t.Fatalf("docs page does not load its script: %s", w.Body.String())
+		}
+		w = get("/api/docs/docs.js")
+		expectStatus(t, w, http.StatusOK)
+		if !strings.Contains(w.Header().Get("Content-Type"), "javascript") {
+			t.Fatalf("unexpected script content type %q", w.Header().Get("Content-Type"))
+		}
+		expectStatus(t, get("/api/docs/index.html"), http.StatusNotFound)
+	})
+}
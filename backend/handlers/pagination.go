@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"scandata/apierror"
+	"scandata/repository"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 500
+)
+
+// pageCursor marks the last row of a page. It is handed to clients as an
+// opaque base64 string and only valid for the sort it was issued with.
+type pageCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+type pageRequest struct {
+	limit int
+	sort  string
+	desc  bool
+	field string
+	after *pageCursor
+}
+
+// parsePage reads limit, sort ("field" ascending, "-field" descending) and cursor
+func parsePage(c *gin.Context, fields map[string]repository.SortField, defaultSort string) (*pageRequest, error) {
+	page := &pageRequest{limit: defaultPageSize}
+
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return nil, apierror.Invalid("limit", "limit must be a positive number")
+		}
+		if limit > maxPageSize {
+			limit = maxPageSize
+		}
+		page.limit = limit
+	}
+
+	page.sort = c.DefaultQuery("sort", defaultSort)
+	name := strings.TrimPrefix(page.sort, "-")
+	page.desc = strings.HasPrefix(page.sort, "-")
+	if _, ok := fields[name]; !ok {
+		allowed := make([]string, 0, len(fields))
+		for f := range fields {
+			allowed = append(allowed, f)
+		}
+		return nil, apierror.Invalidf("sort", "sort must be one of %s (prefix with - for descending)", strings.Join(allowed, ", "))
+	}
+	page.field = name
+
+	if v := c.Query("cursor"); v != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(v)
+		if err != nil {
+			return nil, apierror.Invalid("cursor", "Invalid cursor")
+		}
+		var cursor pageCursor
+		if err := json.Unmarshal(raw, &cursor); err != nil {
+			return nil, apierror.Invalid("cursor", "Invalid cursor")
+		}
+		if cursor.Sort != page.sort {
+			return nil, apierror.Invalid("cursor", "cursor was issued for a different sort")
+		}
+		page.after = &cursor
+	}
+
+	return page, nil
+}
+
+// parseListPage is parsePage for the short admin lists of users and units.
+// Without limit and cursor they return every row, as they did before they
+// were paginated, so clients that do not follow cursors see all of them.
+func parseListPage(c *gin.Context, fields map[string]repository.SortField, defaultSort string) (*pageRequest, error) {
+	page, err := parsePage(c, fields, defaultSort)
+	if err == nil && c.Query("limit") == "" && c.Query("cursor") == "" {
+		page.limit = 0
+	}
+	return page, err
+}
+
+// storePage converts the request for the store. One extra row is asked
+// for so we know whether another page follows; a limit of 0 asks for all.
+func (p *pageRequest) storePage() repository.Page {
+	page := repository.Page{Sort: p.field, Desc: p.desc}
+	if p.limit > 0 {
+		page.Limit = p.limit + 1
+	}
+	if p.after != nil {
+		page.After = &repository.Cursor{Value: p.after.Value, ID: p.after.ID}
+	}
+	return page
+}
+
+func (p *pageRequest) encodeCursor(value interface{}, id uint) string {
+	cursor := pageCursor{Sort: p.sort, ID: id}
+	if t, ok := value.(time.Time); ok {
+		cursor.Value = t.Format(time.RFC3339Nano)
+	} else {
+		cursor.Value = fmt.Sprint(value)
+	}
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// paginate loads one page through list and sets the X-Total-Count,
+// X-Next-Cursor and Link headers. key returns the sort value and id of a row
+// for building the next cursor.
+func paginate[T any](c *gin.Context, page *pageRequest, list func(repository.Page) ([]T, int64, error), key func(T) (interface{}, uint)) ([]T, error) {
+	items, total, err := list(page.storePage())
+	if err != nil {
+		return nil, err
+	}
+
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	if page.limit > 0 && len(items) > page.limit {
+		items = items[:page.limit]
+		value, id := key(items[len(items)-1])
+		next := page.encodeCursor(value, id)
+
+		nextURL := *c.Request.URL
+		params := nextURL.Query()
+		params.Set("cursor", next)
+		nextURL.RawQuery = params.Encode()
+
+		c.Header("X-Next-Cursor", next)
+		// Add rather than c.Header: on the legacy /api routes the deprecation
+		// middleware has already set a Link to the successor version, which
+		// Set would drop
+		c.Writer.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"next\"", nextURL.RequestURI()))
+	}
+
+	return items, nil
+}
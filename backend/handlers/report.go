@@ -2,122 +2,93 @@ package handlers
 
 import (
 	"fmt"
+	"math"
 	"net/http"
-	"scandata/database"
+	"scandata/apierror"
+	"scandata/config"
+	"scandata/i18n"
+	"scandata/metrics"
 	"scandata/models"
+	"scandata/repository"
 	"scandata/services"
+	"sort"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-type ReportHandler struct{}
+type ReportHandler struct {
+	Config *config.Config
+	Live   *config.Live
+	Store  repository.Store
+}
+
+func NewReportHandler(cfg *config.Config, live *config.Live, store repository.Store) *ReportHandler {
+	return &ReportHandler{Config: cfg, Live: live, Store: store}
+}
 
-func NewReportHandler() *ReportHandler {
-	return &ReportHandler{}
+// ReportSummary counts scans since the start of today, the week and the month
+type ReportSummary struct {
+	Today repository.ScanCounts `json:"today"`
+	Week  repository.ScanCounts `json:"week"`
+	Month repository.ScanCounts `json:"month"`
 }
 
 type DailyReport struct {
-	Date     string `json:"date"`
-	Total    int64  `json:"total"`
-	Match    int64  `json:"match"`
-	NotMatch int64  `json:"not_match"`
+	Date string `json:"date"`
+	repository.ScanCounts
+}
+
+type TimeSeriesReport struct {
+	Start    time.Time             `json:"start"`
+	End      time.Time             `json:"end"`
+	Interval services.Interval     `json:"interval"`
+	GroupBy  string                `json:"group_by,omitempty"`
+	Series   []services.TimeSeries `json:"series"`
 }
 
 type UserPerformance struct {
 	UserID   uint   `json:"user_id"`
 	UserName string `json:"user_name"`
-	Total    int64  `json:"total"`
-	Match    int64  `json:"match"`
-	NotMatch int64  `json:"not_match"`
+	repository.ScanCounts
+	MismatchRate float64                `json:"mismatch_rate"`
+	Shifts       []UserShiftPerformance `json:"shifts,omitempty"`
 }
 
-func (h *ReportHandler) Summary(c *gin.Context) {
-	role := c.MustGet("role").(models.Role)
-	userID := c.MustGet("user_id").(uint)
+type UserShiftPerformance struct {
+	ShiftID   uint   `json:"shift_id"`
+	ShiftName string `json:"shift_name"`
+	services.ShiftStats
+}
 
-	today := time.Now().Truncate(24 * time.Hour)
+type ShiftReport struct {
+	ShiftID   uint   `json:"shift_id"`
+	ShiftName string `json:"shift_name"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+	Users     int    `json:"users"`
+	services.ShiftStats
+}
+
+func (h *ReportHandler) Summary(c *gin.Context) {
+	today := startOfDay(time.Now(), h.Live.Get().Location())
 	weekStart := today.AddDate(0, 0, -int(today.Weekday()))
 	monthStart := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location())
 
-	var totalToday, matchToday, notMatchToday int64
-	var totalWeek, matchWeek, notMatchWeek int64
-	var totalMonth, matchMonth, notMatchMonth int64
-
-	// Today
-	todayQuery := database.DB.Model(&models.ScanLog{}).Where("scanned_at >= ?", today)
-	if role != models.RoleAdmin {
-		todayQuery = todayQuery.Where("user_id = ?", userID)
-	}
-	todayQuery.Count(&totalToday)
-
-	matchQuery := database.DB.Model(&models.ScanLog{}).Where("scanned_at >= ? AND is_match = ?", today, true)
-	if role != models.RoleAdmin {
-		matchQuery = matchQuery.Where("user_id = ?", userID)
-	}
-	matchQuery.Count(&matchToday)
-
-	notMatchQuery := database.DB.Model(&models.ScanLog{}).Where("scanned_at >= ? AND is_match = ?", today, false)
-	if role != models.RoleAdmin {
-		notMatchQuery = notMatchQuery.Where("user_id = ?", userID)
-	}
-	notMatchQuery.Count(&notMatchToday)
-
-	// This week
-	weekQuery := database.DB.Model(&models.ScanLog{}).Where("scanned_at >= ?", weekStart)
-	if role != models.RoleAdmin {
-		weekQuery = weekQuery.Where("user_id = ?", userID)
-	}
-	weekQuery.Count(&totalWeek)
-
-	weekMatchQuery := database.DB.Model(&models.ScanLog{}).Where("scanned_at >= ? AND is_match = ?", weekStart, true)
-	if role != models.RoleAdmin {
-		weekMatchQuery = weekMatchQuery.Where("user_id = ?", userID)
-	}
-	weekMatchQuery.Count(&matchWeek)
-
-	weekNotMatchQuery := database.DB.Model(&models.ScanLog{}).Where("scanned_at >= ? AND is_match = ?", weekStart, false)
-	if role != models.RoleAdmin {
-		weekNotMatchQuery = weekNotMatchQuery.Where("user_id = ?", userID)
-	}
-	weekNotMatchQuery.Count(&notMatchWeek)
-
-	// This month
-	monthQuery := database.DB.Model(&models.ScanLog{}).Where("scanned_at >= ?", monthStart)
-	if role != models.RoleAdmin {
-		monthQuery = monthQuery.Where("user_id = ?", userID)
-	}
-	monthQuery.Count(&totalMonth)
-
-	monthMatchQuery := database.DB.Model(&models.ScanLog{}).Where("scanned_at >= ? AND is_match = ?", monthStart, true)
-	if role != models.RoleAdmin {
-		monthMatchQuery = monthMatchQuery.Where("user_id = ?", userID)
-	}
-	monthMatchQuery.Count(&matchMonth)
-
-	monthNotMatchQuery := database.DB.Model(&models.ScanLog{}).Where("scanned_at >= ? AND is_match = ?", monthStart, false)
-	if role != models.RoleAdmin {
-		monthNotMatchQuery = monthNotMatchQuery.Where("user_id = ?", userID)
+	var summary ReportSummary
+	for _, period := range []struct {
+		since  time.Time
+		counts *repository.ScanCounts
+	}{{today, &summary.Today}, {weekStart, &summary.Week}, {monthStart, &summary.Month}} {
+		counts, err := h.Store.Scans().Count(c.Request.Context(), repository.ScanFilter{From: &period.since, OwnerID: ownerOf(c)})
+		if err != nil {
+			apierror.Abort(c, apierror.Internal("Failed to load summary", err))
+			return
+		}
+		*period.counts = counts
 	}
-	monthNotMatchQuery.Count(&notMatchMonth)
 
-	c.JSON(http.StatusOK, gin.H{
-		"today": gin.H{
-			"total":     totalToday,
-			"match":     matchToday,
-			"not_match": notMatchToday,
-		},
-		"week": gin.H{
-			"total":     totalWeek,
-			"match":     matchWeek,
-			"not_match": notMatchWeek,
-		},
-		"month": gin.H{
-			"total":     totalMonth,
-			"match":     matchMonth,
-			"not_match": notMatchMonth,
-		},
-	})
+	c.JSON(http.StatusOK, summary)
 }
 
 func (h *ReportHandler) Daily(c *gin.Context) {
@@ -130,25 +101,20 @@ func (h *ReportHandler) Daily(c *gin.Context) {
 	}
 
 	reports := make([]DailyReport, days)
-	today := time.Now().Truncate(24 * time.Hour)
+	today := startOfDay(time.Now(), h.Live.Get().Location())
 
 	for i := 0; i < days; i++ {
 		date := today.AddDate(0, 0, -i)
-		nextDate := date.Add(24 * time.Hour)
-
-		var total, match, notMatch int64
-		database.DB.Model(&models.ScanLog{}).
-			Where("scanned_at >= ? AND scanned_at < ?", date, nextDate).Count(&total)
-		database.DB.Model(&models.ScanLog{}).
-			Where("scanned_at >= ? AND scanned_at < ? AND is_match = ?", date, nextDate, true).Count(&match)
-		database.DB.Model(&models.ScanLog{}).
-			Where("scanned_at >= ? AND scanned_at < ? AND is_match = ?", date, nextDate, false).Count(&notMatch)
+		nextDate := date.AddDate(0, 0, 1)
 
+		counts, err := h.Store.Scans().Count(c.Request.Context(), repository.ScanFilter{From: &date, To: &nextDate})
+		if err != nil {
+			apierror.Abort(c, apierror.Internal("Failed to load daily report", err))
+			return
+		}
 		reports[i] = DailyReport{
-			Date:     date.Format("2006-01-02"),
-			Total:    total,
-			Match:    match,
-			NotMatch: notMatch,
+			Date:       date.Format("2006-01-02"),
+			ScanCounts: counts,
 		}
 	}
 
@@ -156,57 +122,197 @@ func (h *ReportHandler) Daily(c *gin.Context) {
 }
 
 func (h *ReportHandler) UserPerformance(c *gin.Context) {
-	today := time.Now().Truncate(24 * time.Hour)
-	weekStart := today.AddDate(0, 0, -int(today.Weekday()))
+	loc := h.Live.Get().Location()
+	today := startOfDay(time.Now(), loc)
+	start := today.AddDate(0, 0, -int(today.Weekday()))
+	end := time.Now()
+	if v := c.Query("start"); v != "" {
+		t, err := parseReportTime(v, false, loc)
+		if err != nil {
+			apierror.Abort(c, invalidTime("start"))
+			return
+		}
+		start = t
+	}
+	if v := c.Query("end"); v != "" {
+		t, err := parseReportTime(v, true, loc)
+		if err != nil {
+			apierror.Abort(c, invalidTime("end"))
+			return
+		}
+		end = t
+	}
 
-	var users []models.User
-	database.DB.Where("role = ?", models.RoleUser).Find(&users)
+	users, err := h.Store.Users().ListByRole(c.Request.Context(), models.RoleUser)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to load users", err))
+		return
+	}
+
+	// Per-shift breakdown, grouped by shift date so night shifts stay whole
+	var shiftEvents map[uint][]services.ShiftEvent
+	var shifts map[uint]models.Shift
+	byShift := c.Query("by_shift") == "true"
+	if byShift {
+		events, err := h.Store.Scans().ShiftEvents(c.Request.Context(), start, end)
+		if err == nil {
+			shifts, err = h.loadShiftMap(c)
+		}
+		if err != nil {
+			apierror.Abort(c, apierror.Internal("Failed to load shift data", err))
+			return
+		}
+		shiftEvents = make(map[uint][]services.ShiftEvent)
+		for _, e := range events {
+			shiftEvents[e.UserID] = append(shiftEvents[e.UserID], e)
+		}
+	}
 
 	performances := make([]UserPerformance, len(users))
 	for i, user := range users {
-		var total, match, notMatch int64
-		database.DB.Model(&models.ScanLog{}).
-			Where("user_id = ? AND scanned_at >= ?", user.ID, weekStart).Count(&total)
-		database.DB.Model(&models.ScanLog{}).
-			Where("user_id = ? AND scanned_at >= ? AND is_match = ?", user.ID, weekStart, true).Count(&match)
-		database.DB.Model(&models.ScanLog{}).
-			Where("user_id = ? AND scanned_at >= ? AND is_match = ?", user.ID, weekStart, false).Count(&notMatch)
+		counts, err := h.Store.Scans().Count(c.Request.Context(), repository.ScanFilter{From: &start, To: &end, UserIDs: []uint{user.ID}})
+		if err != nil {
+			apierror.Abort(c, apierror.Internal("Failed to load user performance", err))
+			return
+		}
 
 		performances[i] = UserPerformance{
-			UserID:   user.ID,
-			UserName: user.Name,
-			Total:    total,
-			Match:    match,
-			NotMatch: notMatch,
+			UserID:     user.ID,
+			UserName:   user.Name,
+			ScanCounts: counts,
+		}
+		if counts.Total > 0 {
+			performances[i].MismatchRate = math.Round(float64(counts.NotMatch)/float64(counts.Total)*10000) / 100
+		}
+
+		if byShift {
+			performances[i].Shifts = []UserShiftPerformance{}
+			for shiftID, events := range groupByShift(shiftEvents[user.ID]) {
+				shift := shifts[shiftID]
+				performances[i].Shifts = append(performances[i].Shifts, UserShiftPerformance{
+					ShiftID:    shiftID,
+					ShiftName:  shift.Name,
+					ShiftStats: services.SummarizeShift(events, shift.Duration(), h.Config.ShiftIdleThreshold),
+				})
+			}
+			sort.Slice(performances[i].Shifts, func(a, b int) bool {
+				return performances[i].Shifts[a].ShiftName < performances[i].Shifts[b].ShiftName
+			})
 		}
 	}
 
 	c.JSON(http.StatusOK, performances)
 }
 
+// Shifts - Produktivitas per shift berdasarkan tanggal mulai shift
+func (h *ReportHandler) Shifts(c *gin.Context) {
+	loc := h.Live.Get().Location()
+	today := startOfDay(time.Now(), loc)
+	start := today.AddDate(0, 0, -7)
+	end := today.AddDate(0, 0, 1)
+	if v := c.Query("start"); v != "" {
+		t, err := parseReportTime(v, false, loc)
+		if err != nil {
+			apierror.Abort(c, apierror.Invalid("start", "Invalid start, use YYYY-MM-DD"))
+			return
+		}
+		start = t
+	}
+	if v := c.Query("end"); v != "" {
+		t, err := parseReportTime(v, true, loc)
+		if err != nil {
+			apierror.Abort(c, apierror.Invalid("end", "Invalid end, use YYYY-MM-DD"))
+			return
+		}
+		end = t
+	}
+
+	events, err := h.Store.Scans().ShiftEvents(c.Request.Context(), start, end)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to load shift data", err))
+		return
+	}
+
+	shifts, err := h.loadShiftMap(c)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to load shift data", err))
+		return
+	}
+	reports := []ShiftReport{}
+	for shiftID, shiftEvents := range groupByShift(events) {
+		shift := shifts[shiftID]
+		users := make(map[uint]struct{})
+		for _, e := range shiftEvents {
+			users[e.UserID] = struct{}{}
+		}
+		reports = append(reports, ShiftReport{
+			ShiftID:    shiftID,
+			ShiftName:  shift.Name,
+			StartTime:  shift.StartTime,
+			EndTime:    shift.EndTime,
+			Users:      len(users),
+			ShiftStats: services.SummarizeShift(shiftEvents, shift.Duration(), h.Config.ShiftIdleThreshold),
+		})
+	}
+	sort.Slice(reports, func(a, b int) bool { return reports[a].StartTime < reports[b].StartTime })
+
+	c.JSON(http.StatusOK, reports)
+}
+
+// loadShiftMap includes deleted shifts so historic scans keep their names
+func (h *ReportHandler) loadShiftMap(c *gin.Context) (map[uint]models.Shift, error) {
+	shifts, err := h.Store.Shifts().ListWithDeleted(c.Request.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[uint]models.Shift, len(shifts))
+	for _, shift := range shifts {
+		result[shift.ID] = shift
+	}
+	return result, nil
+}
+
+func groupByShift(events []services.ShiftEvent) map[uint][]services.ShiftEvent {
+	grouped := make(map[uint][]services.ShiftEvent)
+	for _, e := range events {
+		grouped[e.ShiftID] = append(grouped[e.ShiftID], e)
+	}
+	return grouped
+}
+
 func (h *ReportHandler) Export(c *gin.Context) {
-	startDate := c.Query("start_date")
-	endDate := c.Query("end_date")
+	defer metrics.ObserveExport("scans", time.Now())
 
-	query := database.DB.Model(&models.ScanLog{}).Preload("Unit").Preload("User")
+	loc := h.Live.Get().Location()
+	filter, err := resolveScanFilter(c, h.Store, loc)
+	if err != nil {
+		apierror.Abort(c, err)
+		return
+	}
 
-	if startDate != "" {
-		if start, err := time.Parse("2006-01-02", startDate); err == nil {
-			query = query.Where("scanned_at >= ?", start)
+	// start_date/end_date are kept for existing export links
+	if startDate := c.Query("start_date"); startDate != "" {
+		if start, err := time.ParseInLocation("2006-01-02", startDate, loc); err == nil {
+			filter.From = &start
 		}
 	}
-	if endDate != "" {
-		if end, err := time.Parse("2006-01-02", endDate); err == nil {
-			query = query.Where("scanned_at < ?", end.Add(24*time.Hour))
+	if endDate := c.Query("end_date"); endDate != "" {
+		if end, err := time.ParseInLocation("2006-01-02", endDate, loc); err == nil {
+			end = end.AddDate(0, 0, 1)
+			filter.To = &end
 		}
 	}
 
-	var scans []models.ScanLog
-	query.Order("scanned_at DESC").Find(&scans)
+	scans, err := h.Store.Scans().Find(c.Request.Context(), filter)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to load scans", err))
+		return
+	}
 
-	excelFile, err := services.GenerateExcel(scans)
+	excelFile, err := services.GenerateExcel(scans, i18n.FromContext(c.Request.Context()))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate Excel"})
+		apierror.Abort(c, apierror.Internal("Failed to generate Excel", err))
 		return
 	}
 
@@ -215,3 +321,132 @@ func (h *ReportHandler) Export(c *gin.Context) {
 
 	excelFile.Write(c.Writer)
 }
+
+// TimeSeries - Grafik scan per jam/hari/minggu/bulan untuk rentang tanggal bebas
+func (h *ReportHandler) TimeSeries(c *gin.Context) {
+	// Buckets follow the zone of start, so daily buckets start at local midnight
+	loc := h.Live.Get().Location()
+	end := time.Now().In(loc)
+	start := end.AddDate(0, 0, -30)
+	if v := c.Query("start"); v != "" {
+		t, err := parseReportTime(v, false, loc)
+		if err != nil {
+			apierror.Abort(c, invalidTime("start"))
+			return
+		}
+		start = t
+	}
+	if v := c.Query("end"); v != "" {
+		t, err := parseReportTime(v, true, loc)
+		if err != nil {
+			apierror.Abort(c, invalidTime("end"))
+			return
+		}
+		end = t
+	}
+	if !start.Before(end) {
+		apierror.Abort(c, apierror.Invalid("start", "start must be before end"))
+		return
+	}
+
+	interval := services.IntervalDay
+	if v := c.Query("interval"); v != "" {
+		parsed, ok := services.ParseInterval(v)
+		if !ok {
+			apierror.Abort(c, apierror.Invalid("interval", "interval must be one of hour, day, week, month"))
+			return
+		}
+		interval = parsed
+	}
+	if interval.CountBuckets(start, end) > services.MaxTimeSeriesBuckets {
+		apierror.Abort(c, apierror.Invalidf("interval", "Range too large for interval %s (max %d points)", interval, services.MaxTimeSeriesBuckets))
+		return
+	}
+
+	groupBy := c.Query("group_by")
+	switch groupBy {
+	case "", "user", "unit", "location", "grade":
+	default:
+		apierror.Abort(c, apierror.Invalid("group_by", "group_by must be one of user, unit, location, grade"))
+		return
+	}
+
+	builder := services.NewTimeSeriesBuilder(start, end, interval)
+	err := h.Store.Scans().EachRow(c.Request.Context(), repository.ScanFilter{From: &start, To: &end, OwnerID: ownerOf(c)}, func(row repository.ScanRow) error {
+		sample := services.ScanSample{
+			ScannedAt:  row.ScannedAt,
+			Barcode:    row.Barcode,
+			IsMatch:    row.IsMatch,
+			GroupKey:   "all",
+			GroupLabel: "All",
+		}
+		switch groupBy {
+		case "user":
+			sample.GroupKey = fmt.Sprint(row.UserID)
+			sample.GroupLabel = valueOr(row.UserName, sample.GroupKey)
+		case "unit":
+			sample.GroupKey, sample.GroupLabel = "", "Unknown"
+			if row.UnitID != nil {
+				sample.GroupKey = fmt.Sprint(*row.UnitID)
+				sample.GroupLabel = valueOr(row.UnitName, sample.GroupKey)
+			}
+		case "location":
+			sample.GroupLabel = valueOr(row.UnitLocation, "Unknown")
+			sample.GroupKey = valueOr(row.UnitLocation, "")
+		case "grade":
+			sample.GroupLabel = valueOr(row.UnitGrade, "Unknown")
+			sample.GroupKey = valueOr(row.UnitGrade, "")
+		}
+		builder.Add(sample)
+		return nil
+	})
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to load scans", err))
+		return
+	}
+
+	series := builder.Series()
+	if len(series) == 0 && groupBy == "" {
+		series = append(series, services.ZeroTimeSeries("all", "All", start, end, interval))
+	}
+
+	c.JSON(http.StatusOK, TimeSeriesReport{
+		Start:    start,
+		End:      end,
+		Interval: interval,
+		GroupBy:  groupBy,
+		Series:   series,
+	})
+}
+
+// parseReportTime accepts a YYYY-MM-DD date, taken in loc, or an RFC3339
+// timestamp. A bare end date is inclusive, so it is moved to the start of
+// the next day.
+func parseReportTime(value string, isEnd bool, loc *time.Location) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, loc); err == nil {
+		if isEnd {
+			return t.AddDate(0, 0, 1), nil
+		}
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t.In(loc), err
+}
+
+// invalidTime reports a time query parameter parseReportTime rejected
+func invalidTime(param string) *apierror.Error {
+	return apierror.Invalidf(param, "Invalid %s, use YYYY-MM-DD or RFC3339", param)
+}
+
+// startOfDay returns midnight of t's day in loc
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+func valueOr(value *string, fallback string) string {
+	if value == nil || *value == "" {
+		return fallback
+	}
+	return *value
+}
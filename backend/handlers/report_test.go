@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"net/http"
+	"scandata/repository"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// seedScans submits three matches and one mismatch as the regular user
+func seedScans(t *testing.T, token string) {
+	t.Helper()
+	for _, scan := range []gin.H{
+		{"barcode": "4006381333931", "is_match": true},
+		{"barcode": "5901234123457", "is_match": true},
+		{"barcode": "0012345678905", "is_match": true},
+		{"barcode": "96385074", "is_match": false},
+	} {
+		expectStatus(t, doRequest(t, token, http.MethodPost, "/api/v1/scans", scan), http.StatusCreated)
+	}
+}
+
+func TestReportSummaryAndDaily(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		token := login(t, "scanner")
+		seedScans(t, token)
+
+		var summary map[string]repository.ScanCounts
+		w := doRequest(t, token, http.MethodGet, "/api/v1/reports/summary", nil)
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &summary)
+		if got := summary["today"]; got.Total != 4 || got.Match != 3 || got.NotMatch != 1 || got.DistinctUnits != 4 {
+			t.Fatalf("unexpected summary %+v", got)
+		}
+
+		var daily []DailyReport
+		w = doRequest(t, token, http.MethodGet, "/api/v1/reports/daily?days=3", nil)
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &daily)
+		if len(daily) != 3 || daily[0].Total != 4 {
+			t.Fatalf("unexpected daily report %+v", daily)
+		}
+	})
+}
+
+func TestReportTimeSeries(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		token := login(t, "scanner")
+		seedScans(t, token)
+
+		var report TimeSeriesReport
+		w := doRequest(t, token, http.MethodGet, "/api/v1/reports/timeseries?interval=day&group_by=user", nil)
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &report)
+
+		if len(report.Series) != 1 {
+			t.Fatalf("expected one series for the user, got %d", len(report.Series))
+		}
+		var total int64
+		for _, point := range report.Series[0].Points {
+			total += point.Total
+		}
+		if total != 4 {
+			t.Fatalf("series adds up to %d scans, want 4", total)
+		}
+
+		expectStatus(t, doRequest(t, token, http.MethodGet, "/api/v1/reports/timeseries?group_by=color", nil), http.StatusBadRequest)
+	})
+}
+
+func TestReportUserPerformanceAndShifts(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		adminToken := login(t, "admin")
+
+		// Two shifts covering the whole day so every scan is tagged
+		for _, shift := range []gin.H{
+			{"name": "Day", "start_time": "00:00", "end_time": "12:00"},
+			{"name": "Night", "start_time": "12:00", "end_time": "00:00"},
+		} {
+			expectStatus(t, doRequest(t, adminToken, http.MethodPost, "/api/v1/shifts", shift), http.StatusCreated)
+		}
+
+		seedScans(t, login(t, "scanner"))
+
+		var performance []UserPerformance
+		w := doRequest(t, adminToken, http.MethodGet, "/api/v1/reports/users?by_shift=true", nil)
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &performance)
+		if len(performance) != 1 || performance[0].Total != 4 || performance[0].MismatchRate != 25 {
+			t.Fatalf("unexpected user performance %+v", performance)
+		}
+		if len(performance[0].Shifts) != 1 || performance[0].Shifts[0].Total != 4 {
+			t.Fatalf("unexpected per-shift breakdown %+v", performance[0].Shifts)
+		}
+
+		var shifts []ShiftReport
+		w = doRequest(t, adminToken, http.MethodGet, "/api/v1/reports/shifts", nil)
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &shifts)
+		if len(shifts) != 1 || shifts[0].Users != 1 || shifts[0].Total != 4 {
+			t.Fatalf("unexpected shift report %+v", shifts)
+		}
+	})
+}
+
+func TestReportExport(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		seedScans(t, login(t, "scanner"))
+
+		w := doRequest(t, login(t, "admin"), http.MethodGet, "/api/v1/reports/export", nil)
+		expectStatus(t, w, http.StatusOK)
+		if ct := w.Header().Get("Content-Type"); ct != "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet" {
+			t.Fatalf("unexpected content type %q", ct)
+		}
+
+		expectStatus(t, doRequest(t, login(t, "scanner"), http.MethodGet, "/api/v1/reports/export", nil), http.StatusForbidden)
+	})
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"net/http"
+	"scandata/config"
+	"scandata/middleware"
+	"scandata/openapi"
+	"scandata/repository"
+	"scandata/services"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitClasses maps routes with their own rate limit policy (see
+// config.DefaultRateLimitPolicies) to the policy class. A route shares its
+// bucket across the legacy and versioned paths.
+var RateLimitClasses = versionedRoutes(map[string]string{
+	"POST /auth/login":    "login",
+	"POST /scans":         "scans",
+	"GET /reports/export": "export",
+	"GET /audit/export":   "export",
+})
+
+// RegisterRoutes adds every /api route to r, served from store. Handlers
+// read reloadable settings from live.
+func RegisterRoutes(r gin.IRouter, cfg *config.Config, live *config.Live, store repository.Store, barcodes *services.BarcodeRegistry) {
+	// Initialize handlers
+	authHandler := NewAuthHandler(cfg, store)
+	userHandler := NewUserHandler(store)
+	unitHandler := NewUnitHandler(store)
+	scanHandler := NewScanHandler(cfg, live, store, barcodes)
+	reportHandler := NewReportHandler(cfg, live, store)
+	shiftHandler := NewShiftHandler(live, store)
+	savedViewHandler := NewSavedViewHandler(store)
+	auditHandler := NewAuditHandler(live, store)
+	securityHandler := NewSecurityHandler(live)
+	settingsHandler := NewSettingsHandler(cfg, live, store)
+	docsHandler := NewDocsHandler()
+
+	// Documentation (unversioned)
+	r.GET("/api/openapi.json", docsHandler.Spec)
+	r.GET("/api/docs", docsHandler.Page)
+	r.GET("/api/docs/:file", docsHandler.Asset)
+
+	v1 := NewAPIVersion("v1")
+
+	// Auth
+	v1.Handle(http.MethodPost, "/auth/login", openapi.Public, authHandler.Login)
+	v1.Handle(http.MethodGet, "/auth/me", openapi.Authenticated, authHandler.Me)
+	v1.Handle(http.MethodPut, "/auth/me", openapi.Authenticated, authHandler.UpdateMe)
+	v1.Handle(http.MethodPost, "/auth/change-password", openapi.Authenticated, authHandler.ChangePassword)
+
+	// Users (Admin only)
+	v1.Handle(http.MethodGet, "/users", openapi.Admin, userHandler.List)
+	v1.Handle(http.MethodPost, "/users", openapi.Admin, userHandler.Create)
+	v1.Handle(http.MethodGet, "/users/:id", openapi.Admin, userHandler.Get)
+	v1.Handle(http.MethodPut, "/users/:id", openapi.Admin, userHandler.Update)
+	v1.Handle(http.MethodDelete, "/users/:id", openapi.Admin, userHandler.Delete)
+
+	// Units
+	v1.Handle(http.MethodGet, "/units", openapi.Authenticated, unitHandler.List)
+	v1.Handle(http.MethodGet, "/units/qr/:qr_code", openapi.Authenticated, unitHandler.GetByQRCode)
+	v1.Handle(http.MethodGet, "/units/:id", openapi.Authenticated, unitHandler.Get)
+	v1.Handle(http.MethodPost, "/units", openapi.Admin, unitHandler.Create)
+	v1.Handle(http.MethodPut, "/units/:id", openapi.Admin, unitHandler.Update)
+	v1.Handle(http.MethodDelete, "/units/:id", openapi.Admin, unitHandler.Delete)
+
+	// Shifts
+	v1.Handle(http.MethodGet, "/shifts", openapi.Authenticated, shiftHandler.List)
+	v1.Handle(http.MethodPost, "/shifts", openapi.Admin, shiftHandler.Create)
+	v1.Handle(http.MethodPut, "/shifts/:id", openapi.Admin, shiftHandler.Update)
+	v1.Handle(http.MethodDelete, "/shifts/:id", openapi.Admin, shiftHandler.Delete)
+	v1.Handle(http.MethodPost, "/shifts/retag", openapi.Admin, shiftHandler.Retag)
+
+	// Scans
+	v1.Handle(http.MethodPost, "/scans", openapi.Authenticated, scanHandler.Submit)
+	v1.Handle(http.MethodGet, "/scans", openapi.Authenticated, scanHandler.List)
+	v1.Handle(http.MethodGet, "/scans/stats", openapi.Authenticated, scanHandler.GetStats)
+	v1.Handle(http.MethodGet, "/scans/views", openapi.Authenticated, savedViewHandler.List)
+	v1.Handle(http.MethodPost, "/scans/views", openapi.Authenticated, savedViewHandler.Create)
+	v1.Handle(http.MethodDelete, "/scans/views/:id", openapi.Authenticated, savedViewHandler.Delete)
+	v1.Handle(http.MethodGet, "/scans/:id", openapi.Authenticated, scanHandler.Get)
+	v1.Handle(http.MethodPatch, "/scans/:id", openapi.Authenticated, scanHandler.Amend)
+	v1.Handle(http.MethodPost, "/scans/:id/void", openapi.Authenticated, scanHandler.Void)
+
+	// Reports
+	v1.Handle(http.MethodGet, "/reports/summary", openapi.Authenticated, reportHandler.Summary)
+	v1.Handle(http.MethodGet, "/reports/daily", openapi.Authenticated, reportHandler.Daily)
+	v1.Handle(http.MethodGet, "/reports/timeseries", openapi.Authenticated, reportHandler.TimeSeries)
+	v1.Handle(http.MethodGet, "/reports/users", openapi.Admin, reportHandler.UserPerformance)
+	v1.Handle(http.MethodGet, "/reports/shifts", openapi.Admin, reportHandler.Shifts)
+	v1.Handle(http.MethodGet, "/reports/export", openapi.Admin, reportHandler.Export)
+
+	// Audit trail (Admin only)
+	v1.Handle(http.MethodGet, "/audit", openapi.Admin, auditHandler.List)
+	v1.Handle(http.MethodGet, "/audit/export", openapi.Admin, auditHandler.Export)
+	v1.Handle(http.MethodGet, "/audit/verify", openapi.Admin, auditHandler.Verify)
+
+	// Security events (Admin only)
+	v1.Handle(http.MethodGet, "/security/events", openapi.Admin, securityHandler.Events)
+
+	// Runtime settings (Admin only)
+	v1.Handle(http.MethodGet, "/settings", openapi.Admin, settingsHandler.List)
+	v1.Handle(http.MethodPut, "/settings", openapi.Admin, settingsHandler.Update)
+
+	// Versions differing from v1 are started with v1.Next (see APIVersion)
+	// and added here and to APIVersionNames
+	auth := middleware.AuthMiddleware(cfg, store.Users())
+	for _, version := range []*APIVersion{v1} {
+		version.Register(r, version.Prefix(), auth)
+	}
+
+	// The unversioned paths stay an alias of v1 for cached frontends
+	v1.Register(r, LegacyPrefix, auth, middleware.DeprecationMiddleware(LegacyDeprecatedAt, cfg.LegacyAPISunset, func(path string) string {
+		return v1.Prefix() + strings.TrimPrefix(path, LegacyPrefix)
+	}))
+}
+
+// MessageResponse confirms a change that returns no data
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// idParam reads the :id path parameter; an invalid id reads as 0, which no
+// row has
+func idParam(c *gin.Context) uint {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	return uint(id)
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"scandata/apierror"
+	"scandata/i18n"
+	"scandata/models"
+	"scandata/repository"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SavedViewHandler struct {
+	Store repository.Store
+}
+
+func NewSavedViewHandler(store repository.Store) *SavedViewHandler {
+	return &SavedViewHandler{Store: store}
+}
+
+type CreateSavedViewRequest struct {
+	Name    string                `json:"name" binding:"required,max=100"`
+	Filters repository.ScanFilter `json:"filters"`
+}
+
+type SavedViewResponse struct {
+	ID        uint                  `json:"id"`
+	Name      string                `json:"name"`
+	Filters   repository.ScanFilter `json:"filters"`
+	CreatedAt time.Time             `json:"created_at"`
+}
+
+func (h *SavedViewHandler) List(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+
+	views, err := h.Store.SavedViews().List(c.Request.Context(), userID)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to load views", err))
+		return
+	}
+
+	response := make([]SavedViewResponse, 0, len(views))
+	for _, view := range views {
+		var filters repository.ScanFilter
+		json.Unmarshal([]byte(view.Filters), &filters)
+		response = append(response, SavedViewResponse{
+			ID:        view.ID,
+			Name:      view.Name,
+			Filters:   filters,
+			CreatedAt: view.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *SavedViewHandler) Create(c *gin.Context) {
+	var req CreateSavedViewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, apierror.Binding(err))
+		return
+	}
+
+	filters, _ := json.Marshal(req.Filters)
+	view := &models.SavedView{
+		UserID:  c.MustGet("user_id").(uint),
+		Name:    req.Name,
+		Filters: string(filters),
+	}
+	err := h.Store.SavedViews().Create(c.Request.Context(), view)
+	if errors.Is(err, repository.ErrDuplicate) {
+		apierror.Abort(c, apierror.Duplicate("name", "View name already exists"))
+		return
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to save view", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, SavedViewResponse{
+		ID:        view.ID,
+		Name:      view.Name,
+		Filters:   req.Filters,
+		CreatedAt: view.CreatedAt,
+	})
+}
+
+func (h *SavedViewHandler) Delete(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+
+	view, err := h.Store.SavedViews().Get(c.Request.Context(), userID, idParam(c))
+	if err != nil {
+		apierror.Abort(c, apierror.Lookup(err, "View not found"))
+		return
+	}
+
+	if err := h.Store.SavedViews().Delete(c.Request.Context(), view); err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to delete view", err))
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: i18n.T(c.Request.Context(), "View deleted")})
+}
+
+// resolveScanFilter builds the effective filter for a request: the saved view
+// named by ?view= (owned by the caller) with query parameters layered on top.
+func resolveScanFilter(c *gin.Context, store repository.Store, loc *time.Location) (repository.ScanFilter, error) {
+	filter, err := scanFilterFromQuery(c, loc)
+	if err != nil {
+		return filter, err
+	}
+
+	viewID := c.Query("view")
+	if viewID == "" {
+		return filter, nil
+	}
+
+	id, _ := strconv.ParseUint(viewID, 10, 64)
+	view, err := store.SavedViews().Get(c.Request.Context(), c.MustGet("user_id").(uint), uint(id))
+	if errors.Is(err, repository.ErrNotFound) {
+		return filter, apierror.Invalid("view", "View not found")
+	}
+	if err != nil {
+		return filter, apierror.Internal("Failed to load view", err)
+	}
+
+	var saved repository.ScanFilter
+	if err := json.Unmarshal([]byte(view.Filters), &saved); err != nil {
+		return filter, apierror.Invalid("view", "View has invalid filters")
+	}
+	return saved.Merge(filter), nil
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"scandata/models"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSavedViews(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		token := login(t, "scanner")
+		seedScans(t, token)
+
+		var view SavedViewResponse
+		w := doRequest(t, token, http.MethodPost, "/api/v1/scans/views", gin.H{"name": "Mismatches", "filters": gin.H{"is_match": false}})
+		expectStatus(t, w, http.StatusCreated)
+		decode(t, w, &view)
+		expectStatus(t, doRequest(t, token, http.MethodPost, "/api/v1/scans/views", gin.H{"name": "Mismatches"}), http.StatusConflict)
+
+		var views []SavedViewResponse
+		w = doRequest(t, token, http.MethodGet, "/api/v1/scans/views", nil)
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &views)
+		if len(views) != 1 || views[0].Filters.IsMatch == nil || *views[0].Filters.IsMatch {
+			t.Fatalf("unexpected views %+v", views)
+		}
+
+		var scans []models.ScanLog
+		w = doRequest(t, token, http.MethodGet, fmt.Sprintf("/api/v1/scans?view=%d", view.ID), nil)
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &scans)
+		if len(scans) != 1 || scans[0].IsMatch {
+			t.Fatalf("view returned %+v", scans)
+		}
+
+		// Views are private to their owner
+		adminToken := login(t, "admin")
+		expectStatus(t, doRequest(t, adminToken, http.MethodGet, fmt.Sprintf("/api/v1/scans?view=%d", view.ID), nil), http.StatusBadRequest)
+		expectStatus(t, doRequest(t, adminToken, http.MethodDelete, fmt.Sprintf("/api/v1/scans/views/%d", view.ID), nil), http.StatusNotFound)
+
+		expectStatus(t, doRequest(t, token, http.MethodDelete, fmt.Sprintf("/api/v1/scans/views/%d", view.ID), nil), http.StatusOK)
+		expectStatus(t, doRequest(t, token, http.MethodDelete, fmt.Sprintf("/api/v1/scans/views/%d", view.ID), nil), http.StatusNotFound)
+	})
+}
@@ -1,126 +1,387 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
-	"scandata/database"
+	"scandata/apierror"
+	"scandata/config"
+	"scandata/metrics"
 	"scandata/models"
+	"scandata/repository"
+	"scandata/services"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-type ScanHandler struct{}
+type ScanHandler struct {
+	Config   *config.Config
+	Live     *config.Live
+	Store    repository.Store
+	Barcodes *services.BarcodeRegistry
+}
 
-func NewScanHandler() *ScanHandler {
-	return &ScanHandler{}
+func NewScanHandler(cfg *config.Config, live *config.Live, store repository.Store, barcodes *services.BarcodeRegistry) *ScanHandler {
+	return &ScanHandler{Config: cfg, Live: live, Store: store, Barcodes: barcodes}
 }
 
 type SubmitScanRequest struct {
-	Barcode string `json:"barcode" binding:"required"`
-	IsMatch bool   `json:"is_match"`
-	Notes   string `json:"notes"`
+	Barcode  string `json:"barcode" binding:"required,max=100"`
+	IsMatch  bool   `json:"is_match"`
+	Notes    string `json:"notes" binding:"max=500"`
+	UnitType string `json:"unit_type"`
 }
 
 // Submit - Langsung simpan hasil scan ke database
 func (h *ScanHandler) Submit(c *gin.Context) {
 	var req SubmitScanRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Binding(err))
 		return
 	}
 
+	ctx := c.Request.Context()
 	userID := c.MustGet("user_id").(uint)
+	now := time.Now()
 
 	scanLog := &models.ScanLog{
 		Barcode:   req.Barcode,
 		UserID:    userID,
 		IsMatch:   req.IsMatch,
 		Notes:     req.Notes,
-		ScannedAt: time.Now(),
+		ScannedAt: now,
+	}
+
+	// Hubungkan ke unit jika barcode sesuai dengan QR code unit yang aktif
+	unit, err := h.Store.Units().GetActiveByQRCode(ctx, req.Barcode)
+	knownUnit := err == nil
+	if knownUnit {
+		scanLog.UnitID = &unit.ID
+		if unit.Type != "" {
+			req.UnitType = unit.Type
+		}
+	}
+
+	// QR code unit yang terdaftar dianggap valid kecuali tipe unitnya punya pola sendiri
+	if !knownUnit || h.Barcodes.HasTypePattern(req.UnitType) {
+		info, err := h.Barcodes.Validate(req.Barcode, req.UnitType)
+		if err != nil {
+			apierror.Abort(c, apierror.Invalidf("barcode", "Invalid barcode: %v", err))
+			return
+		}
+		scanLog.Symbology = info.Symbology
+		scanLog.GTIN = info.GTIN
+		scanLog.Lot = info.Lot
+		scanLog.SerialNumber = info.Serial
+		scanLog.ExpiryDate = info.Expiry
 	}
 
-	if err := database.DB.Create(scanLog).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save scan"})
+	shifts, err := h.Store.Shifts().ListActive(ctx)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to save scan", err))
+		return
+	}
+	assignShift(scanLog, shifts, h.Live.Get().Location())
+
+	var merged *models.ScanLog
+	err = h.Store.Transaction(ctx, func(tx repository.Store) error {
+		// Scan dari user yang sama diproses satu per satu, supaya double-trigger
+		// yang datang bersamaan tetap terdeteksi sebagai duplikat
+		if err := tx.Users().Lock(ctx, userID); err != nil {
+			return err
+		}
+
+		// Scanner sering double-trigger, cek scan barcode yang sama dalam window duplikat
+		if window := h.Live.Get().DuplicateScanWindow; window > 0 {
+			previous, err := tx.Scans().LatestSince(ctx, userID, req.Barcode, now.Add(-window))
+			switch {
+			case errors.Is(err, repository.ErrNotFound):
+			case err != nil:
+				return err
+			case h.Config.DuplicateScanPolicy == config.DuplicatePolicyReject:
+				return apierror.New(http.StatusConflict, apierror.CodeDuplicate, "Duplicate scan").
+					WithDetail("duplicate_of", previous.ID)
+			case h.Config.DuplicateScanPolicy == config.DuplicatePolicyMerge:
+				merged = previous
+				return tx.Scans().IncrementDuplicates(ctx, previous.ID)
+			default:
+				originalID := previous.ID
+				if previous.DuplicateOfID != nil {
+					originalID = *previous.DuplicateOfID
+				}
+				scanLog.DuplicateOfID = &originalID
+			}
+		}
+
+		return tx.Scans().Create(ctx, scanLog)
+	})
+	var apiErr *apierror.Error
+	if errors.As(err, &apiErr) {
+		apierror.Abort(c, apiErr)
+		return
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to save scan", err))
 		return
 	}
+	if merged != nil {
+		if saved, err := h.Store.Scans().Get(ctx, merged.ID); err == nil {
+			merged = saved
+		}
+		c.JSON(http.StatusOK, merged)
+		return
+	}
+	metrics.ScanSubmitted(scanLog.IsMatch)
 
 	// Load user info for response
-	database.DB.Preload("User").First(scanLog, scanLog.ID)
+	if saved, err := h.Store.Scans().Get(ctx, scanLog.ID); err == nil {
+		scanLog = saved
+	}
 
 	c.JSON(http.StatusCreated, scanLog)
 }
 
 // List - Tampilkan history scan
 func (h *ScanHandler) List(c *gin.Context) {
+	filter, err := resolveScanFilter(c, h.Store, h.Live.Get().Location())
+	if err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+
+	// Regular users can only see their own scans
+	filter.OwnerID = ownerOf(c)
+
+	page, err := parsePage(c, repository.ScanSortFields, "-scanned_at")
+	if err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+
+	scans, err := paginate(c, page, func(p repository.Page) ([]models.ScanLog, int64, error) {
+		return h.Store.Scans().List(c.Request.Context(), filter, p)
+	}, func(s models.ScanLog) (interface{}, uint) {
+		switch page.field {
+		case "barcode":
+			return s.Barcode, s.ID
+		case "id":
+			return s.ID, s.ID
+		}
+		return s.ScannedAt, s.ID
+	})
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to load scans", err))
+		return
+	}
+	c.JSON(http.StatusOK, scans)
+}
+
+// GetStats - Statistik scan hari ini
+func (h *ScanHandler) GetStats(c *gin.Context) {
+	today := startOfDay(time.Now(), h.Live.Get().Location())
+
+	counts, err := h.Store.Scans().Count(c.Request.Context(), repository.ScanFilter{From: &today, OwnerID: ownerOf(c)})
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to load stats", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, ScanStats{Today: counts})
+}
+
+type ScanStats struct {
+	Today repository.ScanCounts `json:"today"`
+}
+
+type AmendScanRequest struct {
+	IsMatch *bool   `json:"is_match"`
+	Notes   *string `json:"notes" binding:"omitempty,max=500"`
+	Reason  string  `json:"reason" binding:"required,max=500"`
+}
+
+type VoidScanRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+type ScanDetail struct {
+	models.ScanLog
+	Revisions []models.ScanRevision `json:"revisions"`
+}
+
+// scanSnapshot is the editable part of a scan stored in each revision
+type scanSnapshot struct {
+	IsMatch  bool   `json:"is_match"`
+	Notes    string `json:"notes"`
+	IsVoided bool   `json:"is_voided"`
+}
+
+// Get - Detail scan beserta riwayat koreksi
+func (h *ScanHandler) Get(c *gin.Context) {
 	userID := c.MustGet("user_id").(uint)
 	role := c.MustGet("role").(models.Role)
 
-	var scans []models.ScanLog
-	query := database.DB.Preload("User")
+	scan, err := h.Store.Scans().Get(c.Request.Context(), idParam(c))
+	if err != nil {
+		apierror.Abort(c, apierror.Lookup(err, "Scan not found"))
+		return
+	}
+	if role != models.RoleAdmin && scan.UserID != userID {
+		apierror.Abort(c, apierror.NotFound("Scan not found"))
+		return
+	}
+
+	revisions, err := h.Store.Scans().Revisions(c.Request.Context(), scan.ID)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to load revisions", err))
+		return
+	}
 
-	// Regular users can only see their own scans
-	if role != models.RoleAdmin {
-		query = query.Where("user_id = ?", userID)
+	c.JSON(http.StatusOK, ScanDetail{ScanLog: *scan, Revisions: revisions})
+}
+
+// Amend - Koreksi status sesuai/catatan dengan alasan
+func (h *ScanHandler) Amend(c *gin.Context) {
+	var req AmendScanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, apierror.Binding(err))
+		return
+	}
+	if req.IsMatch == nil && req.Notes == nil {
+		apierror.Abort(c, apierror.BadRequest("Nothing to change, provide is_match or notes"))
+		return
 	}
 
-	// Filter by date
-	if date := c.Query("date"); date != "" {
-		startDate, err := time.Parse("2006-01-02", date)
-		if err == nil {
-			endDate := startDate.Add(24 * time.Hour)
-			query = query.Where("scanned_at >= ? AND scanned_at < ?", startDate, endDate)
-		}
+	scan, ok := h.loadEditableScan(c)
+	if !ok {
+		return
 	}
 
-	// Filter by barcode
-	if barcode := c.Query("barcode"); barcode != "" {
-		query = query.Where("barcode LIKE ?", "%"+barcode+"%")
+	before := snapshotOf(scan)
+	if req.IsMatch != nil {
+		scan.IsMatch = *req.IsMatch
+	}
+	if req.Notes != nil {
+		scan.Notes = *req.Notes
 	}
 
-	// Filter by match status
-	if match := c.Query("is_match"); match != "" {
-		query = query.Where("is_match = ?", match == "true")
+	if !h.saveRevision(c, scan, before, models.RevisionAmend, req.Reason) {
+		return
 	}
 
-	query.Order("scanned_at DESC").Limit(100).Find(&scans)
-	c.JSON(http.StatusOK, scans)
+	c.JSON(http.StatusOK, scan)
 }
 
-// GetStats - Statistik scan hari ini
-func (h *ScanHandler) GetStats(c *gin.Context) {
+// Void - Batalkan scan; tetap tersimpan tapi tidak dihitung di laporan
+func (h *ScanHandler) Void(c *gin.Context) {
+	var req VoidScanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, apierror.Binding(err))
+		return
+	}
+
+	scan, ok := h.loadEditableScan(c)
+	if !ok {
+		return
+	}
+
+	userID := c.MustGet("user_id").(uint)
+	now := time.Now()
+	before := snapshotOf(scan)
+	scan.IsVoided = true
+	scan.VoidedAt = &now
+	scan.VoidedBy = &userID
+	scan.VoidReason = req.Reason
+
+	if !h.saveRevision(c, scan, before, models.RevisionVoid, req.Reason) {
+		return
+	}
+
+	c.JSON(http.StatusOK, scan)
+}
+
+// loadEditableScan loads the scan in the URL and checks the caller may change it:
+// admins always, the original scanner only within the edit window.
+func (h *ScanHandler) loadEditableScan(c *gin.Context) (*models.ScanLog, bool) {
 	userID := c.MustGet("user_id").(uint)
 	role := c.MustGet("role").(models.Role)
 
-	today := time.Now().Truncate(24 * time.Hour)
+	scan, err := h.Store.Scans().Get(c.Request.Context(), idParam(c))
+	if err != nil {
+		apierror.Abort(c, apierror.Lookup(err, "Scan not found"))
+		return nil, false
+	}
 
-	baseQuery := database.DB.Model(&models.ScanLog{}).Where("scanned_at >= ?", today)
 	if role != models.RoleAdmin {
-		baseQuery = baseQuery.Where("user_id = ?", userID)
+		if scan.UserID != userID {
+			apierror.Abort(c, apierror.NotFound("Scan not found"))
+			return nil, false
+		}
+		if time.Since(scan.ScannedAt) > h.Config.ScanEditWindow {
+			apierror.Abort(c, apierror.Forbidden("Edit window has passed, ask an admin to correct this scan"))
+			return nil, false
+		}
 	}
 
-	var totalToday int64
-	var matchToday int64
-	var notMatchToday int64
+	if scan.IsVoided {
+		apierror.Abort(c, apierror.Conflict("Scan is voided"))
+		return nil, false
+	}
 
-	baseQuery.Count(&totalToday)
-	
-	matchQuery := database.DB.Model(&models.ScanLog{}).Where("scanned_at >= ? AND is_match = ?", today, true)
-	if role != models.RoleAdmin {
-		matchQuery = matchQuery.Where("user_id = ?", userID)
+	return scan, true
+}
+
+// saveRevision updates the scan and appends its revision in one transaction
+func (h *ScanHandler) saveRevision(c *gin.Context, scan *models.ScanLog, before scanSnapshot, action, reason string) bool {
+	ctx := c.Request.Context()
+	beforeJSON, _ := json.Marshal(before)
+	afterJSON, _ := json.Marshal(snapshotOf(scan))
+
+	err := h.Store.Transaction(ctx, func(tx repository.Store) error {
+		// Guard on the revision number so concurrent corrections cannot both win
+		scan.Revision++
+		if err := tx.Scans().SaveEdit(ctx, scan, scan.Revision-1); err != nil {
+			return err
+		}
+
+		if err := recordAudit(c, tx, action, "scan", scan.ID, before, snapshotOf(scan)); err != nil {
+			return err
+		}
+
+		return tx.Scans().CreateRevision(ctx, &models.ScanRevision{
+			ScanLogID: scan.ID,
+			Revision:  scan.Revision,
+			Action:    action,
+			Reason:    reason,
+			ChangedBy: c.MustGet("user_id").(uint),
+			Before:    beforeJSON,
+			After:     afterJSON,
+		})
+	})
+	if errors.Is(err, repository.ErrConflict) {
+		apierror.Abort(c, apierror.Conflict("Scan was changed by someone else, reload and try again"))
+		return false
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to save scan", err))
+		return false
 	}
-	matchQuery.Count(&matchToday)
+	return true
+}
 
-	notMatchQuery := database.DB.Model(&models.ScanLog{}).Where("scanned_at >= ? AND is_match = ?", today, false)
-	if role != models.RoleAdmin {
-		notMatchQuery = notMatchQuery.Where("user_id = ?", userID)
+func snapshotOf(scan *models.ScanLog) scanSnapshot {
+	return scanSnapshot{
+		IsMatch:  scan.IsMatch,
+		Notes:    scan.Notes,
+		IsVoided: scan.IsVoided,
 	}
-	notMatchQuery.Count(&notMatchToday)
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"today": gin.H{
-			"total":     totalToday,
-			"match":     matchToday,
-			"not_match": notMatchToday,
-		},
-	})
+// ownerOf limits regular users to their own scans; admins see everyone's
+func ownerOf(c *gin.Context) *uint {
+	if c.MustGet("role").(models.Role) == models.RoleAdmin {
+		return nil
+	}
+	userID := c.MustGet("user_id").(uint)
+	return &userID
 }
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"scandata/apierror"
+	"scandata/repository"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// scanFilterFromQuery reads filters from the query string. List parameters
+// accept comma separated values or repeated keys (user_id=1,2 or user_id=1&user_id=2).
+// Bare dates are taken in loc.
+func scanFilterFromQuery(c *gin.Context, loc *time.Location) (repository.ScanFilter, error) {
+	var f repository.ScanFilter
+
+	if v := c.Query("from"); v != "" {
+		t, err := parseReportTime(v, false, loc)
+		if err != nil {
+			return f, invalidTime("from")
+		}
+		f.From = &t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := parseReportTime(v, true, loc)
+		if err != nil {
+			return f, invalidTime("to")
+		}
+		f.To = &t
+	}
+
+	// Legacy single-day filter
+	if v := c.Query("date"); v != "" {
+		start, err := parseReportTime(v, false, loc)
+		if err != nil {
+			return f, apierror.Invalid("date", "Invalid date, use YYYY-MM-DD")
+		}
+		end := start.AddDate(0, 0, 1)
+		f.From, f.To = &start, &end
+	}
+
+	var err error
+	if f.UserIDs, err = queryUintList(c, "user_id"); err != nil {
+		return f, err
+	}
+	if f.UnitIDs, err = queryUintList(c, "unit_id"); err != nil {
+		return f, err
+	}
+	f.Locations = queryStringList(c, "location")
+	f.Grades = queryStringList(c, "grade")
+
+	f.Barcode = c.Query("barcode")
+	f.GTIN = c.Query("gtin")
+	f.Lot = c.Query("lot")
+	f.Notes = c.Query("q")
+
+	f.IncludeVoided = c.Query("include_voided") == "true"
+
+	if v := c.Query("is_match"); v != "" {
+		match := v == "true"
+		f.IsMatch = &match
+	}
+
+	return f, nil
+}
+
+func queryStringList(c *gin.Context, key string) []string {
+	var values []string
+	for _, raw := range c.QueryArray(key) {
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+func queryUintList(c *gin.Context, key string) ([]uint, error) {
+	var ids []uint
+	for _, v := range queryStringList(c, key) {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return nil, apierror.Invalidf(key, "Invalid %s %q", key, v)
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"scandata/apierror"
+	"scandata/config"
+	"scandata/models"
+	"scandata/repository"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSubmitScan(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		token := login(t, "scanner")
+
+		w := doRequest(t, token, http.MethodPost, "/api/v1/scans", gin.H{"barcode": "4006381333931", "is_match": true})
+		expectStatus(t, w, http.StatusCreated)
+
+		var scan models.ScanLog
+		decode(t, w, &scan)
+		if scan.Symbology != "ean13" || scan.User.Username != "scanner" {
+			t.Fatalf("unexpected scan %+v", scan)
+		}
+
+		w = doRequest(t, token, http.MethodPost, "/api/v1/scans", gin.H{"barcode": "4006381333932", "is_match": true})
+		expectStatus(t, w, http.StatusBadRequest)
+	})
+}
+
+func TestSubmitScanLinksUnit(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		testStore.Units().Create(context.Background(), &models.Unit{QRCode: "UNIT-001", Name: "Pallet 1", IsActive: true})
+		token := login(t, "scanner")
+
+		w := doRequest(t, token, http.MethodPost, "/api/v1/scans", gin.H{"barcode": "UNIT-001", "is_match": true})
+		expectStatus(t, w, http.StatusCreated)
+
+		var scan models.ScanLog
+		decode(t, w, &scan)
+		if scan.UnitID == nil || scan.Unit.Name != "Pallet 1" {
+			t.Fatalf("scan not linked to unit: %+v", scan)
+		}
+	})
+}
+
+func TestSubmitScanFlagsDuplicate(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		token := login(t, "scanner")
+
+		var first, second models.ScanLog
+		decode(t, doRequest(t, token, http.MethodPost, "/api/v1/scans", gin.H{"barcode": "5901234123457", "is_match": true}), &first)
+		decode(t, doRequest(t, token, http.MethodPost, "/api/v1/scans", gin.H{"barcode": "5901234123457", "is_match": true}), &second)
+
+		if second.DuplicateOfID == nil || *second.DuplicateOfID != first.ID {
+			t.Fatalf("second scan not flagged as duplicate of %d: %+v", first.ID, second)
+		}
+	})
+}
+
+func TestSubmitScanRejectsDuplicate(t *testing.T) {
+	setDuplicatePolicy(t, config.DuplicatePolicyReject)
+	forEachStore(t, func(t *testing.T) {
+		token := login(t, "scanner")
+
+		var first models.ScanLog
+		decode(t, doRequest(t, token, http.MethodPost, "/api/v1/scans", gin.H{"barcode": "5901234123457", "is_match": true}), &first)
+
+		w := doRequest(t, token, http.MethodPost, "/api/v1/scans", gin.H{"barcode": "5901234123457", "is_match": true})
+		expectStatus(t, w, http.StatusConflict)
+		var e apierror.Error
+		decode(t, w, &e)
+		if e.Code != apierror.CodeDuplicate || e.Details["duplicate_of"] != float64(first.ID) {
+			t.Fatalf("unexpected duplicate error %s", w.Body.String())
+		}
+
+		// Another user scanning the same barcode is not a repeat
+		expectStatus(t, doRequest(t, login(t, "admin"), http.MethodPost, "/api/v1/scans", gin.H{"barcode": "5901234123457", "is_match": true}), http.StatusCreated)
+	})
+}
+
+func TestSubmitScanMergesDuplicate(t *testing.T) {
+	setDuplicatePolicy(t, config.DuplicatePolicyMerge)
+	forEachStore(t, func(t *testing.T) {
+		token := login(t, "scanner")
+
+		var first, second models.ScanLog
+		decode(t, doRequest(t, token, http.MethodPost, "/api/v1/scans", gin.H{"barcode": "5901234123457", "is_match": true}), &first)
+		w := doRequest(t, token, http.MethodPost, "/api/v1/scans", gin.H{"barcode": "5901234123457", "is_match": true})
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &second)
+		if second.ID != first.ID || second.DuplicateCount != 1 {
+			t.Fatalf("repeat not merged into scan %d: %+v", first.ID, second)
+		}
+
+		var stats struct {
+			Today repository.ScanCounts `json:"today"`
+		}
+		decode(t, doRequest(t, token, http.MethodGet, "/api/v1/scans/stats", nil), &stats)
+		if stats.Today.Total != 1 || stats.Today.RawEvents != 2 || stats.Today.Duplicates != 1 {
+			t.Fatalf("unexpected counts after merge: %+v", stats.Today)
+		}
+	})
+}
+
+// A scanner double-trigger sends both requests at once; only one may pass
+// the duplicate check
+func TestSubmitScanConcurrentDuplicates(t *testing.T) {
+	setDuplicatePolicy(t, config.DuplicatePolicyReject)
+	forEachStore(t, func(t *testing.T) {
+		token := login(t, "scanner")
+
+		// Hold each request after its lookup until every request has looked
+		// up, or briefly when they are serialised, so the lookups all run
+		// before any insert even on a single CPU
+		const requests = 8
+		var arrived int32
+		all := make(chan struct{})
+		testRouter = newTestRouter(lookupHookStore{testStore, func() error {
+			if atomic.AddInt32(&arrived, 1) == requests {
+				close(all)
+			}
+			select {
+			case <-all:
+			case <-time.After(50 * time.Millisecond):
+			}
+			return nil
+		}})
+
+		codes := make(chan int, requests)
+		var wg sync.WaitGroup
+		for i := 0; i < requests; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				req := httptest.NewRequest(http.MethodPost, "/api/v1/scans", strings.NewReader(`{"barcode":"5901234123457","is_match":true}`))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("Authorization", "Bearer "+token)
+				w := httptest.NewRecorder()
+				testRouter.ServeHTTP(w, req)
+				codes <- w.Code
+			}()
+		}
+		wg.Wait()
+		close(codes)
+
+		created := 0
+		for code := range codes {
+			switch code {
+			case http.StatusCreated:
+				created++
+			case http.StatusConflict:
+			default:
+				t.Fatalf("unexpected status %d", code)
+			}
+		}
+		if created != 1 {
+			t.Fatalf("%d of %d simultaneous repeats were stored, want 1", created, requests)
+		}
+	})
+}
+
+func TestSubmitScanFailsWhenDuplicateCheckFails(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		token := login(t, "scanner")
+		testRouter = newTestRouter(lookupHookStore{testStore, func() error { return errors.New("lookup failed") }})
+
+		expectStatus(t, doRequest(t, token, http.MethodPost, "/api/v1/scans", gin.H{"barcode": "5901234123457", "is_match": true}), http.StatusInternalServerError)
+		if counts, err := testStore.Scans().Count(context.Background(), repository.ScanFilter{}); err != nil || counts.Total != 0 {
+			t.Fatalf("scan stored although the duplicate check failed: %+v %v", counts, err)
+		}
+	})
+}
+
+// lookupHookStore calls hook after every duplicate lookup; an error from
+// hook is returned instead of the lookup result
+type lookupHookStore struct {
+	repository.Store
+	hook func() error
+}
+
+type lookupHookScans struct {
+	repository.ScanRepository
+	hook func() error
+}
+
+func (s lookupHookStore) Scans() repository.ScanRepository {
+	return lookupHookScans{s.Store.Scans(), s.hook}
+}
+
+func (s lookupHookStore) Transaction(ctx context.Context, fn func(tx repository.Store) error) error {
+	return s.Store.Transaction(ctx, func(tx repository.Store) error { return fn(lookupHookStore{tx, s.hook}) })
+}
+
+func (s lookupHookScans) LatestSince(ctx context.Context, userID uint, barcode string, since time.Time) (*models.ScanLog, error) {
+	scan, err := s.ScanRepository.LatestSince(ctx, userID, barcode, since)
+	if hookErr := s.hook(); hookErr != nil {
+		return nil, hookErr
+	}
+	return scan, err
+}
+
+// setDuplicatePolicy switches the duplicate policy for the rest of the test
+func setDuplicatePolicy(t *testing.T, policy string) {
+	previous := testConfig.DuplicateScanPolicy
+	testConfig.DuplicateScanPolicy = policy
+	t.Cleanup(func() { testConfig.DuplicateScanPolicy = previous })
+}
+
+func TestListScans(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		adminToken := login(t, "admin")
+		userToken := login(t, "scanner")
+
+		barcodes := []string{"4006381333931", "5901234123457", "0012345678905"}
+		for _, barcode := range barcodes {
+			expectStatus(t, doRequest(t, userToken, http.MethodPost, "/api/v1/scans", gin.H{"barcode": barcode, "is_match": barcode != "0012345678905", "notes": "checked " + barcode}), http.StatusCreated)
+		}
+		expectStatus(t, doRequest(t, adminToken, http.MethodPost, "/api/v1/scans", gin.H{"barcode": "96385074", "is_match": true}), http.StatusCreated)
+
+		var scans []models.ScanLog
+		w := doRequest(t, userToken, http.MethodGet, "/api/v1/scans", nil)
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &scans)
+		if len(scans) != 3 || w.Header().Get("X-Total-Count") != "3" {
+			t.Fatalf("user should see own 3 scans, got %d (total %s)", len(scans), w.Header().Get("X-Total-Count"))
+		}
+
+		w = doRequest(t, adminToken, http.MethodGet, "/api/v1/scans?limit=2", nil)
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &scans)
+		cursor := w.Header().Get("X-Next-Cursor")
+		if len(scans) != 2 || cursor == "" {
+			t.Fatalf("expected a first page of 2 with a cursor, got %d %q", len(scans), cursor)
+		}
+		w = doRequest(t, adminToken, http.MethodGet, "/api/v1/scans?limit=2&cursor="+cursor, nil)
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &scans)
+		if len(scans) != 2 || w.Header().Get("X-Next-Cursor") != "" {
+			t.Fatalf("expected a last page of 2, got %d", len(scans))
+		}
+
+		w = doRequest(t, adminToken, http.MethodGet, "/api/v1/scans?is_match=false", nil)
+		decode(t, w, &scans)
+		if len(scans) != 1 || scans[0].Barcode != "0012345678905" {
+			t.Fatalf("is_match filter returned %+v", scans)
+		}
+
+		w = doRequest(t, adminToken, http.MethodGet, "/api/v1/scans?q=5901234123457", nil)
+		decode(t, w, &scans)
+		if len(scans) != 1 {
+			t.Fatalf("notes search returned %d scans", len(scans))
+		}
+	})
+}
+
+func TestAmendAndVoidScan(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		token := login(t, "scanner")
+
+		var scan models.ScanLog
+		decode(t, doRequest(t, token, http.MethodPost, "/api/v1/scans", gin.H{"barcode": "4006381333931", "is_match": true}), &scan)
+		path := fmt.Sprintf("/api/v1/scans/%d", scan.ID)
+
+		expectStatus(t, doRequest(t, token, http.MethodPatch, path, gin.H{"is_match": false, "reason": "wrong grade"}), http.StatusOK)
+		expectStatus(t, doRequest(t, token, http.MethodPost, path+"/void", gin.H{"reason": "test scan"}), http.StatusOK)
+		expectStatus(t, doRequest(t, token, http.MethodPost, path+"/void", gin.H{"reason": "again"}), http.StatusConflict)
+
+		var detail ScanDetail
+		w := doRequest(t, token, http.MethodGet, path, nil)
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &detail)
+		if !detail.IsVoided || detail.IsMatch || len(detail.Revisions) != 2 {
+			t.Fatalf("unexpected scan after amend and void: %+v", detail)
+		}
+
+		var stats struct {
+			Today repository.ScanCounts `json:"today"`
+		}
+		decode(t, doRequest(t, token, http.MethodGet, "/api/v1/scans/stats", nil), &stats)
+		if stats.Today.Total != 0 {
+			t.Fatalf("voided scan counted in stats: %+v", stats.Today)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"scandata/apierror"
+	"scandata/config"
+	"scandata/middleware"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultSecurityEventLimit = 200
+
+type SecurityHandler struct {
+	Live *config.Live
+}
+
+func NewSecurityHandler(live *config.Live) *SecurityHandler {
+	return &SecurityHandler{Live: live}
+}
+
+// Events - Cari event keamanan terbaru dari security log (terbaru dulu)
+func (h *SecurityHandler) Events(c *gin.Context) {
+	filter := middleware.SecurityEventFilter{
+		Type:      c.Query("type"),
+		IP:        c.Query("ip"),
+		User:      c.Query("user"),
+		Path:      c.Query("path"),
+		RequestID: c.Query("request_id"),
+		Limit:     defaultSecurityEventLimit,
+	}
+
+	loc := h.Live.Get().Location()
+	if v := c.Query("since"); v != "" {
+		t, err := parseReportTime(v, false, loc)
+		if err != nil {
+			apierror.Abort(c, invalidTime("since"))
+			return
+		}
+		filter.Since = t
+	}
+	if v := c.Query("until"); v != "" {
+		t, err := parseReportTime(v, true, loc)
+		if err != nil {
+			apierror.Abort(c, invalidTime("until"))
+			return
+		}
+		filter.Until = t
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			apierror.Abort(c, apierror.Invalid("limit", "limit must be a positive number"))
+			return
+		}
+		if limit > maxPageSize {
+			limit = maxPageSize
+		}
+		filter.Limit = limit
+	}
+
+	events, err := middleware.SearchSecurityEvents(filter)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to read security log", err))
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
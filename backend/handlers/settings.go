@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"scandata/apierror"
+	"scandata/config"
+	"scandata/models"
+	"scandata/repository"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SettingsHandler edits the runtime settings (config.Runtime). Saved values
+// override the environment and apply at once; an empty value removes the
+// override so the environment value applies again.
+type SettingsHandler struct {
+	Config *config.Config
+	Live   *config.Live
+	Store  repository.Store
+}
+
+// settingsMu is held from reading the saved settings to publishing them, so
+// neither an update nor a SIGHUP reload can publish an older combination
+// over a newer one
+var settingsMu sync.Mutex
+
+func NewSettingsHandler(cfg *config.Config, live *config.Live, store repository.Store) *SettingsHandler {
+	return &SettingsHandler{Config: cfg, Live: live, Store: store}
+}
+
+type SettingResponse struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// Source is "database" for a saved override, otherwise "env"
+	Source    string     `json:"source"`
+	UpdatedBy *uint      `json:"updated_by,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// LoadSettings publishes the runtime settings from the environment, the .env
+// file and the saved overrides. It runs at startup and on SIGHUP.
+func LoadSettings(ctx context.Context, cfg *config.Config, store repository.Store, live *config.Live) error {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+
+	saved, err := store.Settings().List(ctx)
+	if err != nil {
+		return err
+	}
+	runtime, err := cfg.ReloadRuntime(settingValues(saved))
+	if err != nil {
+		return err
+	}
+	live.Set(runtime)
+	return nil
+}
+
+func settingValues(settings []models.Setting) map[string]string {
+	values := make(map[string]string, len(settings))
+	for _, setting := range settings {
+		values[setting.Key] = setting.Value
+	}
+	return values
+}
+
+func (h *SettingsHandler) List(c *gin.Context) {
+	saved, err := h.Store.Settings().List(c.Request.Context())
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to load settings", err))
+		return
+	}
+	c.JSON(http.StatusOK, h.response(saved))
+}
+
+func (h *SettingsHandler) response(saved []models.Setting) []SettingResponse {
+	overrides := make(map[string]models.Setting, len(saved))
+	for _, setting := range saved {
+		overrides[setting.Key] = setting
+	}
+
+	values := h.Live.Get().Values()
+	response := make([]SettingResponse, 0, len(config.RuntimeKeys))
+	for _, key := range config.RuntimeKeys {
+		item := SettingResponse{Key: key, Value: values[key], Source: "env"}
+		if setting, ok := overrides[key]; ok {
+			updatedAt := setting.UpdatedAt
+			item.Source = "database"
+			item.UpdatedBy = setting.UpdatedBy
+			item.UpdatedAt = &updatedAt
+		}
+		response = append(response, item)
+	}
+	return response
+}
+
+// Update takes {"KEY": "value", ...}. Every value is validated before
+// anything is saved, and all problems are reported together.
+func (h *SettingsHandler) Update(c *gin.Context) {
+	var req map[string]string
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, apierror.Binding(err))
+		return
+	}
+	keys := make([]string, 0, len(req))
+	for key := range req {
+		if !isRuntimeKey(key) {
+			apierror.Abort(c, apierror.Invalidf(key, "Unknown setting %s", key))
+			return
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+
+	ctx := c.Request.Context()
+	saved, err := h.Store.Settings().List(ctx)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to load settings", err))
+		return
+	}
+
+	before := settingValues(saved)
+	after := settingValues(saved)
+	for _, key := range keys {
+		if value := strings.TrimSpace(req[key]); value != "" {
+			after[key] = value
+		} else {
+			delete(after, key)
+		}
+	}
+
+	runtime, err := h.Config.ReloadRuntime(after)
+	if err != nil {
+		apierror.Abort(c, settingErrors(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uint)
+	err = h.Store.Transaction(ctx, func(tx repository.Store) error {
+		for _, key := range keys {
+			value, ok := after[key]
+			if !ok {
+				if err := tx.Settings().Delete(ctx, key); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := tx.Settings().Save(ctx, &models.Setting{Key: key, Value: value, UpdatedBy: &userID}); err != nil {
+				return err
+			}
+		}
+		return recordAuditKey(c, tx, models.AuditUpdate, "settings", "runtime", before, after)
+	})
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to save settings", err))
+		return
+	}
+	h.Live.Set(runtime)
+
+	if saved, err = h.Store.Settings().List(ctx); err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to load settings", err))
+		return
+	}
+	c.JSON(http.StatusOK, h.response(saved))
+}
+
+func isRuntimeKey(key string) bool {
+	for _, k := range config.RuntimeKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// settingErrors reports each "KEY: problem" line of a config error as a
+// field, so the settings page can mark every invalid input. The problems
+// come from config and stay in English.
+func settingErrors(err error) *apierror.Error {
+	e := apierror.New(http.StatusBadRequest, apierror.CodeValidation, "Invalid settings")
+	for _, line := range strings.Split(err.Error(), "\n") {
+		key, _, _ := strings.Cut(line, ":")
+		e.Fields = append(e.Fields, apierror.FieldError{Field: key, Code: "invalid", Message: line})
+	}
+	return e
+}
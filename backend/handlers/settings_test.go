@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"scandata/apierror"
+	"scandata/models"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSettings(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		expectStatus(t, doRequest(t, login(t, "scanner"), http.MethodPut, "/api/v1/settings", gin.H{"LOG_LEVEL": "error"}), http.StatusForbidden)
+
+		token := login(t, "admin")
+		setting := func(settings []SettingResponse, key string) SettingResponse {
+			for _, s := range settings {
+				if s.Key == key {
+					return s
+				}
+			}
+			t.Fatalf("setting %s missing from %+v", key, settings)
+			return SettingResponse{}
+		}
+
+		var settings []SettingResponse
+		w := doRequest(t, token, http.MethodGet, "/api/v1/settings", nil)
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &settings)
+		if s := setting(settings, "DUPLICATE_SCAN_WINDOW"); s.Value != "5s" || s.Source != "env" {
+			t.Fatalf("unexpected initial setting %+v", s)
+		}
+
+		// Every invalid value is reported and nothing is applied
+		var invalid apierror.Error
+		w = doRequest(t, token, http.MethodPut, "/api/v1/settings", gin.H{"RATE_LIMIT_REQUESTS": "many", "LOG_LEVEL": "loud", "DUPLICATE_SCAN_WINDOW": "0s"})
+		expectStatus(t, w, http.StatusBadRequest)
+		decode(t, w, &invalid)
+		if invalid.Code != apierror.CodeValidation || len(invalid.Fields) != 2 {
+			t.Fatalf("expected 2 problems, got %+v", invalid)
+		}
+		expectStatus(t, doRequest(t, token, http.MethodPut, "/api/v1/settings", gin.H{"JWT_SECRET": "x"}), http.StatusBadRequest)
+		if testLive.Get().DuplicateScanWindow == 0 {
+			t.Fatal("invalid update was applied")
+		}
+
+		// A saved value applies to the next request
+		w = doRequest(t, token, http.MethodPut, "/api/v1/settings", gin.H{"DUPLICATE_SCAN_WINDOW": "0s"})
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &settings)
+		if s := setting(settings, "DUPLICATE_SCAN_WINDOW"); s.Value != "0s" || s.Source != "database" || s.UpdatedBy == nil {
+			t.Fatalf("setting not saved: %+v", s)
+		}
+		var scan models.ScanLog
+		for i := 0; i < 2; i++ {
+			w = doRequest(t, token, http.MethodPost, "/api/v1/scans", gin.H{"barcode": "4006381333931", "is_match": true})
+			expectStatus(t, w, http.StatusCreated)
+			decode(t, w, &scan)
+		}
+		if scan.DuplicateOfID != nil {
+			t.Fatal("duplicate check ran with a zero window")
+		}
+
+		// An empty value falls back to the environment
+		w = doRequest(t, token, http.MethodPut, "/api/v1/settings", gin.H{"DUPLICATE_SCAN_WINDOW": ""})
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &settings)
+		if s := setting(settings, "DUPLICATE_SCAN_WINDOW"); s.Value != "5s" || s.Source != "env" {
+			t.Fatalf("override not removed: %+v", s)
+		}
+
+		var audit []models.AuditLog
+		w = doRequest(t, token, http.MethodGet, "/api/v1/audit?entity_type=settings", nil)
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &audit)
+		if len(audit) != 2 {
+			t.Fatalf("expected 2 audit entries, got %d", len(audit))
+		}
+	})
+}
+
+// A SIGHUP reload racing an update must not publish the settings from
+// before the update once it has been saved
+func TestSettingsReloadDuringUpdate(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		token := login(t, "admin")
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(2)
+			go func(i int) {
+				defer wg.Done()
+				w := doRequest(t, token, http.MethodPut, "/api/v1/settings", gin.H{"RATE_LIMIT_REQUESTS": fmt.Sprint(100 + i)})
+				if w.Code != http.StatusOK {
+					t.Errorf("update: status %d", w.Code)
+				}
+			}(i)
+			go func() {
+				defer wg.Done()
+				if err := LoadSettings(context.Background(), testConfig, testStore, testLive); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		saved, err := testStore.Settings().List(context.Background())
+		if err != nil || len(saved) != 1 {
+			t.Fatalf("unexpected saved settings %+v %v", saved, err)
+		}
+		if live := testLive.Get().Values()["RATE_LIMIT_REQUESTS"]; live != saved[0].Value {
+			t.Fatalf("live setting %s, saved %s", live, saved[0].Value)
+		}
+	})
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"scandata/apierror"
+	"scandata/config"
+	"scandata/i18n"
+	"scandata/models"
+	"scandata/repository"
+	"scandata/services"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ShiftHandler struct {
+	Live  *config.Live
+	Store repository.Store
+}
+
+func NewShiftHandler(live *config.Live, store repository.Store) *ShiftHandler {
+	return &ShiftHandler{Live: live, Store: store}
+}
+
+type CreateShiftRequest struct {
+	Name      string `json:"name" binding:"required"`
+	StartTime string `json:"start_time" binding:"required"`
+	EndTime   string `json:"end_time" binding:"required"`
+	Days      string `json:"days"`
+}
+
+type UpdateShiftRequest struct {
+	Name      string  `json:"name"`
+	StartTime string  `json:"start_time"`
+	EndTime   string  `json:"end_time"`
+	Days      *string `json:"days"`
+	IsActive  *bool   `json:"is_active"`
+}
+
+type RetagResponse struct {
+	Updated int64 `json:"updated"`
+}
+
+func (h *ShiftHandler) List(c *gin.Context) {
+	shifts, err := h.Store.Shifts().List(c.Request.Context())
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to load shifts", err))
+		return
+	}
+	c.JSON(http.StatusOK, shifts)
+}
+
+func (h *ShiftHandler) Create(c *gin.Context) {
+	var req CreateShiftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, apierror.Binding(err))
+		return
+	}
+
+	shift := &models.Shift{
+		Name:      req.Name,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+		Days:      req.Days,
+		IsActive:  true,
+	}
+	if err := shift.Validate(); err != nil {
+		apierror.Abort(c, invalidShift(err))
+		return
+	}
+
+	err := h.Store.Transaction(c.Request.Context(), func(tx repository.Store) error {
+		if err := tx.Shifts().Create(c.Request.Context(), shift); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, models.AuditCreate, "shift", shift.ID, nil, shift)
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		apierror.Abort(c, apierror.Duplicate("name", "Shift name already exists"))
+		return
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to create shift", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, shift)
+}
+
+func (h *ShiftHandler) Update(c *gin.Context) {
+	shift, ok := h.load(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateShiftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, apierror.Binding(err))
+		return
+	}
+
+	before := *shift
+	if req.Name != "" {
+		shift.Name = req.Name
+	}
+	if req.StartTime != "" {
+		shift.StartTime = req.StartTime
+	}
+	if req.EndTime != "" {
+		shift.EndTime = req.EndTime
+	}
+	if req.Days != nil {
+		shift.Days = *req.Days
+	}
+	if req.IsActive != nil {
+		shift.IsActive = *req.IsActive
+	}
+	if err := shift.Validate(); err != nil {
+		apierror.Abort(c, invalidShift(err))
+		return
+	}
+
+	err := h.Store.Transaction(c.Request.Context(), func(tx repository.Store) error {
+		if err := tx.Shifts().Update(c.Request.Context(), shift); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, models.AuditUpdate, "shift", shift.ID, before, shift)
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		apierror.Abort(c, apierror.Duplicate("name", "Shift name already exists"))
+		return
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to update shift", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, shift)
+}
+
+func (h *ShiftHandler) Delete(c *gin.Context) {
+	shift, ok := h.load(c)
+	if !ok {
+		return
+	}
+
+	err := h.Store.Transaction(c.Request.Context(), func(tx repository.Store) error {
+		if err := tx.Shifts().Delete(c.Request.Context(), shift); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, models.AuditDelete, "shift", shift.ID, shift, nil)
+	})
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to delete shift", err))
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: i18n.T(c.Request.Context(), "Shift deleted")})
+}
+
+// Retag - Hitung ulang shift untuk scan lama setelah definisi shift diubah
+func (h *ShiftHandler) Retag(c *gin.Context) {
+	loc := h.Live.Get().Location()
+	start, err := parseReportTime(c.Query("start"), false, loc)
+	if err != nil {
+		apierror.Abort(c, apierror.Invalid("start", "start is required, use YYYY-MM-DD or RFC3339"))
+		return
+	}
+	end := time.Now()
+	if v := c.Query("end"); v != "" {
+		if end, err = parseReportTime(v, true, loc); err != nil {
+			apierror.Abort(c, invalidTime("end"))
+			return
+		}
+	}
+
+	shifts, err := h.Store.Shifts().ListActive(c.Request.Context())
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to load shifts", err))
+		return
+	}
+
+	updated, err := h.Store.Scans().Retag(c.Request.Context(), start, end, func(scan *models.ScanLog) {
+		assignShift(scan, shifts, loc)
+	})
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to retag scans", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, RetagResponse{Updated: updated})
+}
+
+// load reads the shift in the URL or responds 404
+func (h *ShiftHandler) load(c *gin.Context) (*models.Shift, bool) {
+	shift, err := h.Store.Shifts().Get(c.Request.Context(), idParam(c))
+	if err != nil {
+		apierror.Abort(c, apierror.Lookup(err, "Shift not found"))
+		return nil, false
+	}
+	return shift, true
+}
+
+// assignShift tags a scan with the shift it falls in, or clears the tag.
+// Shift times are taken in loc, the report time zone.
+func assignShift(scan *models.ScanLog, shifts []models.Shift, loc *time.Location) {
+	scan.ShiftID = nil
+	scan.ShiftDate = nil
+	if shift, date, ok := services.ResolveShift(shifts, scan.ScannedAt, loc); ok {
+		scan.ShiftID = &shift.ID
+		scan.ShiftDate = &date
+	}
+}
+
+// invalidShift reports a Shift.Validate error on the field it names
+func invalidShift(err error) *apierror.Error {
+	field, _, ok := strings.Cut(err.Error(), ":")
+	if !ok {
+		field = "name"
+	}
+	return apierror.Invalid(field, err.Error())
+}
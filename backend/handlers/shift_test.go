@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"scandata/models"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestShiftCRUDAndRetag(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		adminToken := login(t, "admin")
+
+		// Scanned before any shift exists, so it starts untagged
+		var scan models.ScanLog
+		decode(t, doRequest(t, login(t, "scanner"), http.MethodPost, "/api/v1/scans", gin.H{"barcode": "4006381333931", "is_match": true}), &scan)
+		if scan.ShiftID != nil {
+			t.Fatalf("scan tagged without shifts: %+v", scan)
+		}
+
+		var day, night models.Shift
+		decode(t, doRequest(t, adminToken, http.MethodPost, "/api/v1/shifts", gin.H{"name": "Day", "start_time": "00:00", "end_time": "12:00"}), &day)
+		decode(t, doRequest(t, adminToken, http.MethodPost, "/api/v1/shifts", gin.H{"name": "Night", "start_time": "12:00", "end_time": "00:00"}), &night)
+		expectStatus(t, doRequest(t, adminToken, http.MethodPost, "/api/v1/shifts", gin.H{"name": "Day", "start_time": "06:00", "end_time": "14:00"}), http.StatusConflict)
+		expectStatus(t, doRequest(t, adminToken, http.MethodPost, "/api/v1/shifts", gin.H{"name": "Bad", "start_time": "25:00", "end_time": "14:00"}), http.StatusBadRequest)
+
+		var retag struct {
+			Updated int64 `json:"updated"`
+		}
+		w := doRequest(t, adminToken, http.MethodPost, "/api/v1/shifts/retag?start="+time.Now().Format("2006-01-02"), nil)
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &retag)
+		if retag.Updated != 1 {
+			t.Fatalf("retag updated %d scans, want 1", retag.Updated)
+		}
+
+		// Nothing moved since, so a second run changes no rows
+		w = doRequest(t, adminToken, http.MethodPost, "/api/v1/shifts/retag?start="+time.Now().Format("2006-01-02"), nil)
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &retag)
+		if retag.Updated != 0 {
+			t.Fatalf("second retag updated %d scans, want 0", retag.Updated)
+		}
+
+		var detail ScanDetail
+		decode(t, doRequest(t, adminToken, http.MethodGet, fmt.Sprintf("/api/v1/scans/%d", scan.ID), nil), &detail)
+		if detail.ShiftID == nil || detail.Shift == nil {
+			t.Fatalf("scan not tagged after retag: %+v", detail.ScanLog)
+		}
+
+		var updated models.Shift
+		w = doRequest(t, adminToken, http.MethodPut, fmt.Sprintf("/api/v1/shifts/%d", day.ID), gin.H{"name": "Morning"})
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &updated)
+		if updated.Name != "Morning" || updated.StartTime != "00:00" {
+			t.Fatalf("unexpected shift after update %+v", updated)
+		}
+
+		expectStatus(t, doRequest(t, adminToken, http.MethodDelete, fmt.Sprintf("/api/v1/shifts/%d", night.ID), nil), http.StatusOK)
+		expectStatus(t, doRequest(t, adminToken, http.MethodPut, fmt.Sprintf("/api/v1/shifts/%d", night.ID), gin.H{"name": "Late"}), http.StatusNotFound)
+
+		var shifts []models.Shift
+		w = doRequest(t, login(t, "scanner"), http.MethodGet, "/api/v1/shifts", nil)
+		expectStatus(t, w, http.StatusOK)
+		decode(t, w, &shifts)
+		if len(shifts) != 1 || shifts[0].Name != "Morning" {
+			t.Fatalf("unexpected shifts %+v", shifts)
+		}
+	})
+}
+
+// Shift times are clock times in the report time zone, not the server's
+func TestShiftsFollowReportTimezone(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		runtime := testLive.Get()
+		runtime.ReportTimezone = "Pacific/Kiritimati" // UTC+14, never the server's hour
+		testLive.Set(runtime)
+
+		hour := time.Now().In(runtime.Location()).Hour()
+		shift := gin.H{"name": "Now", "start_time": fmt.Sprintf("%02d:00", hour), "end_time": fmt.Sprintf("%02d:00", (hour+2)%24)}
+		expectStatus(t, doRequest(t, login(t, "admin"), http.MethodPost, "/api/v1/shifts", shift), http.StatusCreated)
+
+		var scan models.ScanLog
+		decode(t, doRequest(t, login(t, "scanner"), http.MethodPost, "/api/v1/scans", gin.H{"barcode": "4006381333931", "is_match": true}), &scan)
+		if scan.ShiftID == nil {
+			t.Fatalf("scan at %02d:xx report time not tagged with the shift starting then", hour)
+		}
+	})
+}
@@ -1,22 +1,28 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
-	"scandata/database"
+	"scandata/apierror"
+	"scandata/i18n"
 	"scandata/models"
+	"scandata/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
-type UnitHandler struct{}
+type UnitHandler struct {
+	Store repository.Store
+}
 
-func NewUnitHandler() *UnitHandler {
-	return &UnitHandler{}
+func NewUnitHandler(store repository.Store) *UnitHandler {
+	return &UnitHandler{Store: store}
 }
 
 type CreateUnitRequest struct {
 	QRCode        string `json:"qr_code" binding:"required"`
 	Name          string `json:"name" binding:"required"`
+	Type          string `json:"type"`
 	ExpectedGrade string `json:"expected_grade"`
 	Location      string `json:"location"`
 }
@@ -24,34 +30,49 @@ type CreateUnitRequest struct {
 type UpdateUnitRequest struct {
 	QRCode        string `json:"qr_code"`
 	Name          string `json:"name"`
+	Type          string `json:"type"`
 	ExpectedGrade string `json:"expected_grade"`
 	Location      string `json:"location"`
 	IsActive      *bool  `json:"is_active"`
 }
 
 func (h *UnitHandler) List(c *gin.Context) {
-	var units []models.Unit
-	query := database.DB
-
-	// Filter by active status
+	// Filter by active status, search by name or qr_code
+	filter := repository.UnitFilter{Search: c.Query("search")}
 	if active := c.Query("active"); active != "" {
-		query = query.Where("is_active = ?", active == "true")
+		isActive := active == "true"
+		filter.Active = &isActive
 	}
 
-	// Search by name or qr_code
-	if search := c.Query("search"); search != "" {
-		query = query.Where("name LIKE ? OR qr_code LIKE ?", "%"+search+"%", "%"+search+"%")
+	page, err := parseListPage(c, repository.UnitSortFields, "-created_at")
+	if err != nil {
+		apierror.Abort(c, err)
+		return
 	}
 
-	query.Order("created_at DESC").Find(&units)
+	units, err := paginate(c, page, func(p repository.Page) ([]models.Unit, int64, error) {
+		return h.Store.Units().List(c.Request.Context(), filter, p)
+	}, func(u models.Unit) (interface{}, uint) {
+		switch page.field {
+		case "name":
+			return u.Name, u.ID
+		case "qr_code":
+			return u.QRCode, u.ID
+		case "id":
+			return u.ID, u.ID
+		}
+		return u.CreatedAt, u.ID
+	})
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to load units", err))
+		return
+	}
 	c.JSON(http.StatusOK, units)
 }
 
 func (h *UnitHandler) Get(c *gin.Context) {
-	id := c.Param("id")
-	var unit models.Unit
-	if err := database.DB.First(&unit, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Unit not found"})
+	unit, ok := h.load(c)
+	if !ok {
 		return
 	}
 	c.JSON(http.StatusOK, unit)
@@ -59,9 +80,9 @@ func (h *UnitHandler) Get(c *gin.Context) {
 
 func (h *UnitHandler) GetByQRCode(c *gin.Context) {
 	qrCode := c.Param("qr_code")
-	var unit models.Unit
-	if err := database.DB.Where("qr_code = ? AND is_active = ?", qrCode, true).First(&unit).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Unit not found"})
+	unit, err := h.Store.Units().GetActiveByQRCode(c.Request.Context(), qrCode)
+	if err != nil {
+		apierror.Abort(c, apierror.Lookup(err, "Unit not found"))
 		return
 	}
 	c.JSON(http.StatusOK, unit)
@@ -70,20 +91,31 @@ func (h *UnitHandler) GetByQRCode(c *gin.Context) {
 func (h *UnitHandler) Create(c *gin.Context) {
 	var req CreateUnitRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Binding(err))
 		return
 	}
 
 	unit := &models.Unit{
 		QRCode:        req.QRCode,
 		Name:          req.Name,
+		Type:          req.Type,
 		ExpectedGrade: req.ExpectedGrade,
 		Location:      req.Location,
 		IsActive:      true,
 	}
 
-	if err := database.DB.Create(unit).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "QR Code already exists"})
+	err := h.Store.Transaction(c.Request.Context(), func(tx repository.Store) error {
+		if err := tx.Units().Create(c.Request.Context(), unit); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, models.AuditCreate, "unit", unit.ID, nil, unit)
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		apierror.Abort(c, apierror.Duplicate("qr_code", "QR Code already exists"))
+		return
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to create unit", err))
 		return
 	}
 
@@ -91,25 +123,27 @@ func (h *UnitHandler) Create(c *gin.Context) {
 }
 
 func (h *UnitHandler) Update(c *gin.Context) {
-	id := c.Param("id")
-	var unit models.Unit
-	if err := database.DB.First(&unit, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Unit not found"})
+	unit, ok := h.load(c)
+	if !ok {
 		return
 	}
 
 	var req UpdateUnitRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Binding(err))
 		return
 	}
 
+	before := *unit
 	if req.QRCode != "" {
 		unit.QRCode = req.QRCode
 	}
 	if req.Name != "" {
 		unit.Name = req.Name
 	}
+	if req.Type != "" {
+		unit.Type = req.Type
+	}
 	if req.ExpectedGrade != "" {
 		unit.ExpectedGrade = req.ExpectedGrade
 	}
@@ -120,8 +154,18 @@ func (h *UnitHandler) Update(c *gin.Context) {
 		unit.IsActive = *req.IsActive
 	}
 
-	if err := database.DB.Save(&unit).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "QR Code already exists"})
+	err := h.Store.Transaction(c.Request.Context(), func(tx repository.Store) error {
+		if err := tx.Units().Update(c.Request.Context(), unit); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, models.AuditUpdate, "unit", unit.ID, before, unit)
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		apierror.Abort(c, apierror.Duplicate("qr_code", "QR Code already exists"))
+		return
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to update unit", err))
 		return
 	}
 
@@ -129,13 +173,30 @@ func (h *UnitHandler) Update(c *gin.Context) {
 }
 
 func (h *UnitHandler) Delete(c *gin.Context) {
-	id := c.Param("id")
-	var unit models.Unit
-	if err := database.DB.First(&unit, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Unit not found"})
+	unit, ok := h.load(c)
+	if !ok {
 		return
 	}
 
-	database.DB.Delete(&unit)
-	c.JSON(http.StatusOK, gin.H{"message": "Unit deleted"})
+	err := h.Store.Transaction(c.Request.Context(), func(tx repository.Store) error {
+		if err := tx.Units().Delete(c.Request.Context(), unit); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, models.AuditDelete, "unit", unit.ID, unit, nil)
+	})
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to delete unit", err))
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: i18n.T(c.Request.Context(), "Unit deleted")})
+}
+
+// load reads the unit in the URL or responds 404
+func (h *UnitHandler) load(c *gin.Context) (*models.Unit, bool) {
+	unit, err := h.Store.Units().Get(c.Request.Context(), idParam(c))
+	if err != nil {
+		apierror.Abort(c, apierror.Lookup(err, "Unit not found"))
+		return nil, false
+	}
+	return unit, true
 }
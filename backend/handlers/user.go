@@ -1,17 +1,22 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
-	"scandata/database"
+	"scandata/apierror"
+	"scandata/i18n"
 	"scandata/models"
+	"scandata/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
-type UserHandler struct{}
+type UserHandler struct {
+	Store repository.Store
+}
 
-func NewUserHandler() *UserHandler {
-	return &UserHandler{}
+func NewUserHandler(store repository.Store) *UserHandler {
+	return &UserHandler{Store: store}
 }
 
 type CreateUserRequest struct {
@@ -19,25 +24,46 @@ type CreateUserRequest struct {
 	Password string      `json:"password" binding:"required,min=6"`
 	Name     string      `json:"name" binding:"required"`
 	Role     models.Role `json:"role" binding:"required,oneof=admin user"`
+	Locale   string      `json:"locale"`
 }
 
 type UpdateUserRequest struct {
 	Name     string      `json:"name"`
 	Role     models.Role `json:"role" binding:"omitempty,oneof=admin user"`
 	Password string      `json:"password" binding:"omitempty,min=6"`
+	Locale   *string     `json:"locale"` // "" clears the preference
 }
 
 func (h *UserHandler) List(c *gin.Context) {
-	var users []models.User
-	database.DB.Find(&users)
+	page, err := parseListPage(c, repository.UserSortFields, "id")
+	if err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+
+	users, err := paginate(c, page, func(p repository.Page) ([]models.User, int64, error) {
+		return h.Store.Users().List(c.Request.Context(), p)
+	}, func(u models.User) (interface{}, uint) {
+		switch page.field {
+		case "username":
+			return u.Username, u.ID
+		case "name":
+			return u.Name, u.ID
+		case "created_at":
+			return u.CreatedAt, u.ID
+		}
+		return u.ID, u.ID
+	})
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to load users", err))
+		return
+	}
 	c.JSON(http.StatusOK, users)
 }
 
 func (h *UserHandler) Get(c *gin.Context) {
-	id := c.Param("id")
-	var user models.User
-	if err := database.DB.First(&user, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	user, ok := h.load(c)
+	if !ok {
 		return
 	}
 	c.JSON(http.StatusOK, user)
@@ -46,7 +72,13 @@ func (h *UserHandler) Get(c *gin.Context) {
 func (h *UserHandler) Create(c *gin.Context) {
 	var req CreateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Binding(err))
+		return
+	}
+
+	locale, err := parseLocale(req.Locale)
+	if err != nil {
+		apierror.Abort(c, err)
 		return
 	}
 
@@ -54,14 +86,25 @@ func (h *UserHandler) Create(c *gin.Context) {
 		Username: req.Username,
 		Name:     req.Name,
 		Role:     req.Role,
+		Locale:   locale,
 	}
 	if err := user.SetPassword(req.Password); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		apierror.Abort(c, apierror.Internal("Failed to hash password", err))
 		return
 	}
 
-	if err := database.DB.Create(user).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Username already exists"})
+	err = h.Store.Transaction(c.Request.Context(), func(tx repository.Store) error {
+		if err := tx.Users().Create(c.Request.Context(), user); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, models.AuditCreate, "user", user.ID, nil, user)
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		apierror.Abort(c, apierror.Duplicate("username", "Username already exists"))
+		return
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to create user", err))
 		return
 	}
 
@@ -69,19 +112,18 @@ func (h *UserHandler) Create(c *gin.Context) {
 }
 
 func (h *UserHandler) Update(c *gin.Context) {
-	id := c.Param("id")
-	var user models.User
-	if err := database.DB.First(&user, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	user, ok := h.load(c)
+	if !ok {
 		return
 	}
 
 	var req UpdateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Binding(err))
 		return
 	}
 
+	before := *user
 	if req.Name != "" {
 		user.Name = req.Name
 	}
@@ -91,27 +133,77 @@ func (h *UserHandler) Update(c *gin.Context) {
 	if req.Password != "" {
 		user.SetPassword(req.Password)
 	}
+	if req.Locale != nil {
+		locale, err := parseLocale(*req.Locale)
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+		user.Locale = locale
+	}
 
-	database.DB.Save(&user)
+	err := h.Store.Transaction(c.Request.Context(), func(tx repository.Store) error {
+		if err := tx.Users().Update(c.Request.Context(), user); err != nil {
+			return err
+		}
+		if req.Password != "" {
+			if err := recordAudit(c, tx, models.AuditPasswordChange, "user", user.ID, nil, nil); err != nil {
+				return err
+			}
+		}
+		return recordAudit(c, tx, models.AuditUpdate, "user", user.ID, before, user)
+	})
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to update user", err))
+		return
+	}
 	c.JSON(http.StatusOK, user)
 }
 
 func (h *UserHandler) Delete(c *gin.Context) {
-	id := c.Param("id")
-
 	// Prevent deleting self
 	currentUserID := c.MustGet("user_id").(uint)
-	var user models.User
-	if err := database.DB.First(&user, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	user, ok := h.load(c)
+	if !ok {
 		return
 	}
 
 	if user.ID == currentUserID {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete yourself"})
+		apierror.Abort(c, apierror.BadRequest("Cannot delete yourself"))
+		return
+	}
+
+	err := h.Store.Transaction(c.Request.Context(), func(tx repository.Store) error {
+		if err := tx.Users().Delete(c.Request.Context(), user); err != nil {
+			return err
+		}
+		return recordAudit(c, tx, models.AuditDelete, "user", user.ID, user, nil)
+	})
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to delete user", err))
 		return
 	}
+	c.JSON(http.StatusOK, MessageResponse{Message: i18n.T(c.Request.Context(), "User deleted")})
+}
+
+// load reads the user in the URL or responds 404
+func (h *UserHandler) load(c *gin.Context) (*models.User, bool) {
+	user, err := h.Store.Users().Get(c.Request.Context(), idParam(c))
+	if err != nil {
+		apierror.Abort(c, apierror.Lookup(err, "User not found"))
+		return nil, false
+	}
+	return user, true
+}
 
-	database.DB.Delete(&user)
-	c.JSON(http.StatusOK, gin.H{"message": "User deleted"})
+// parseLocale reads a locale preference; empty means none
+func parseLocale(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	locale, ok := i18n.Parse(value)
+	if !ok {
+		return "", apierror.Validation(apierror.Field("locale", "oneof", "%s must be one of %s", "locale", "en, id"))
+	}
+	return string(locale), nil
 }
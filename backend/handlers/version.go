@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"scandata/middleware"
+	"scandata/openapi"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LegacyPrefix serves v1 without a version in the path, as the API was
+// served before versioning; its responses carry Deprecation headers
+const LegacyPrefix = "/api"
+
+// LegacyDeprecatedAt is when /api/v1 replaced the unversioned paths
+var LegacyDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// APIVersionNames lists the versions RegisterRoutes serves under
+// /api/<name>, oldest first
+var APIVersionNames = []string{"v1"}
+
+// APIVersion holds the routes of one API version. A new version starts as
+// a copy of the previous one (Next) and only declares the routes whose
+// request or response changed, e.g. for v2:
+//
+//	v2 := v1.Next("v2")
+//	v2.Handle(http.MethodGet, "/scans", openapi.Authenticated, scanHandler.ListV2)
+//
+// Older clients keep the v1 shape until they move to /api/v2.
+type APIVersion struct {
+	Name   string
+	routes []versionRoute
+}
+
+type versionRoute struct {
+	method  string
+	path    string
+	access  openapi.Access
+	handler gin.HandlerFunc
+}
+
+func NewAPIVersion(name string) *APIVersion {
+	return &APIVersion{Name: name}
+}
+
+// Prefix is the path the version is served under
+func (v *APIVersion) Prefix() string {
+	return "/api/" + v.Name
+}
+
+// Handle adds a route, or replaces the route with the same method and path
+func (v *APIVersion) Handle(method, path string, access openapi.Access, handler gin.HandlerFunc) {
+	route := versionRoute{method: method, path: path, access: access, handler: handler}
+	for i, r := range v.routes {
+		if r.method == method && r.path == path {
+			v.routes[i] = route
+			return
+		}
+	}
+	v.routes = append(v.routes, route)
+}
+
+// Next starts version name with the routes of v
+func (v *APIVersion) Next(name string) *APIVersion {
+	next := NewAPIVersion(name)
+	next.routes = append(next.routes, v.routes...)
+	return next
+}
+
+// Register adds the routes under prefix. auth runs ahead of every route
+// that is not public, then AdminMiddleware ahead of admin routes.
+func (v *APIVersion) Register(r gin.IRouter, prefix string, auth gin.HandlerFunc, extra ...gin.HandlerFunc) {
+	group := r.Group(prefix, extra...)
+	for _, route := range v.routes {
+		var chain []gin.HandlerFunc
+		if route.access != openapi.Public {
+			chain = append(chain, auth)
+		}
+		if route.access == openapi.Admin {
+			chain = append(chain, middleware.AdminMiddleware())
+		}
+		group.Handle(route.method, route.path, append(chain, route.handler)...)
+	}
+}
+
+// versionedRoutes expands "METHOD /path" keys relative to a version to the
+// legacy and every versioned path
+func versionedRoutes(routes map[string]string) map[string]string {
+	prefixes := []string{LegacyPrefix}
+	for _, name := range APIVersionNames {
+		prefixes = append(prefixes, "/api/"+name)
+	}
+
+	expanded := make(map[string]string, len(routes)*len(prefixes))
+	for key, value := range routes {
+		method, path, _ := strings.Cut(key, " ")
+		for _, prefix := range prefixes {
+			expanded[method+" "+prefix+path] = value
+		}
+	}
+	return expanded
+}
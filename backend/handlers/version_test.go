@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"scandata/openapi"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAPIVersions(t *testing.T) {
+	forEachStore(t, func(t *testing.T) {
+		token := login(t, "admin")
+
+		// The unversioned paths answer like v1, marked as deprecated
+		w := doRequest(t, token, http.MethodGet, "/api/units?limit=1", nil)
+		expectStatus(t, w, http.StatusOK)
+		if got := w.Header().Get("Deprecation"); got != fmt.Sprintf("@%d", LegacyDeprecatedAt.Unix()) {
+			t.Fatalf("unexpected Deprecation %q", got)
+		}
+		if got := w.Header().Values("Link"); len(got) != 1 || got[0] != `</api/v1/units>; rel="successor-version"` {
+			t.Fatalf("unexpected Link %q", got)
+		}
+		expectStatus(t, doRequest(t, "", http.MethodGet, "/api/units", nil), http.StatusUnauthorized)
+		expectStatus(t, doRequest(t, login(t, "scanner"), http.MethodDelete, "/api/units/1", nil), http.StatusForbidden)
+
+		w = doRequest(t, token, http.MethodGet, "/api/v1/units", nil)
+		expectStatus(t, w, http.StatusOK)
+		if w.Header().Get("Deprecation") != "" {
+			t.Fatal("v1 marked as deprecated")
+		}
+
+		// Both paths share one rate limit bucket
+		if RateLimitClasses["POST /api/auth/login"] != "login" || RateLimitClasses["POST /api/v1/auth/login"] != "login" {
+			t.Fatalf("unexpected rate limit classes %v", RateLimitClasses)
+		}
+	})
+}
+
+func TestAPIVersionNext(t *testing.T) {
+	handler := func(body string) gin.HandlerFunc {
+		return func(c *gin.Context) { c.String(http.StatusOK, body) }
+	}
+	v1 := NewAPIVersion("v1")
+	v1.Handle(http.MethodGet, "/scans", openapi.Public, handler("v1 scans"))
+	v1.Handle(http.MethodGet, "/units", openapi.Public, handler("v1 units"))
+	v2 := v1.Next("v2")
+	v2.Handle(http.MethodGet, "/scans", openapi.Public, handler("v2 scans"))
+
+	r := gin.New()
+	for _, v := range []*APIVersion{v1, v2} {
+		v.Register(r, v.Prefix(), nil)
+	}
+	for path, want := range map[string]string{
+		"/api/v1/scans": "v1 scans",
+		"/api/v1/units": "v1 units",
+		"/api/v2/scans": "v2 scans",
+		"/api/v2/units": "v1 units",
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Body.String() != want {
+			t.Errorf("GET %s: got %q, want %q", path, w.Body.String(), want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package health
+
+import (
+	"sync"
+	"time"
+)
+
+// Component statuses reported by the readiness check
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Component is the state of one dependency in the readiness response.
+// Error is only set to messages of our own, never to the error of a driver.
+type Component struct {
+	Status  string `json:"status"`
+	Latency string `json:"latency,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// worker tracks the heartbeat of a background goroutine. A worker is
+// considered stuck once it misses three beats.
+type worker struct {
+	interval time.Duration
+	lastBeat time.Time
+}
+
+var (
+	mu           sync.Mutex
+	workers      = make(map[string]*worker)
+	shuttingDown bool
+)
+
+// SetShuttingDown marks the server as draining so readiness fails and load
+// balancers stop sending new requests
+func SetShuttingDown() {
+	mu.Lock()
+	defer mu.Unlock()
+	shuttingDown = true
+}
+
+func ShuttingDown() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return shuttingDown
+}
+
+// RegisterWorker declares a background worker that calls Heartbeat at least
+// every interval. Registering counts as the first beat.
+func RegisterWorker(name string, interval time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+	workers[name] = &worker{interval: interval, lastBeat: time.Now()}
+}
+
+// UnregisterWorker removes a worker that stopped on purpose
+func UnregisterWorker(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(workers, name)
+}
+
+func Heartbeat(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if w, ok := workers[name]; ok {
+		w.lastBeat = time.Now()
+	}
+}
+
+// Workers returns the status of every registered worker by name
+func Workers() map[string]Component {
+	mu.Lock()
+	defer mu.Unlock()
+
+	result := make(map[string]Component, len(workers))
+	for name, w := range workers {
+		since := time.Since(w.lastBeat)
+		if since > 3*w.interval {
+			result[name] = Component{Status: StatusDown, Error: "no heartbeat for " + since.Round(time.Second).String()}
+		} else {
+			result[name] = Component{Status: StatusUp}
+		}
+	}
+	return result
+}
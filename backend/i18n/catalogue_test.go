@@ -0,0 +1,92 @@
+package i18n
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// translated maps the functions that translate a literal message to the
+// position of the message among their arguments; -1 means every argument
+// from the first on
+var translated = map[string]map[string]int{
+	"i18n": {"T": 1, "Translate": 1, "Textf": 0},
+	"apierror": {
+		"Field": 2, "New": 2, "Newf": 2, "BadRequest": 0, "Unauthorized": 0, "Forbidden": 0,
+		"NotFound": 0, "Conflict": 0, "Duplicate": 1, "Invalid": 1, "Invalidf": 1,
+		"Internal": 0, "Lookup": 1,
+	},
+	"services": {"translateAll": -1},
+}
+
+// Every message the code translates must have an Indonesian entry,
+// otherwise it is silently shown in English
+func TestCatalogueCoversMessages(t *testing.T) {
+	fset := token.NewFileSet()
+	err := filepath.WalkDir("..", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return err
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			for _, arg := range messageArgs(file.Name.Name, call) {
+				lit, ok := arg.(*ast.BasicLit)
+				if !ok || lit.Kind != token.STRING {
+					continue
+				}
+				message, err := strconv.Unquote(lit.Value)
+				if err != nil {
+					t.Errorf("%s: %v", fset.Position(lit.Pos()), err)
+					continue
+				}
+				if _, ok := indonesian[message]; !ok {
+					t.Errorf("%s: %q has no Indonesian translation", fset.Position(lit.Pos()), message)
+				}
+			}
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// messageArgs returns the message arguments of call, made in package pkg
+func messageArgs(pkg string, call *ast.CallExpr) []ast.Expr {
+	var name string
+	switch fn := call.Fun.(type) {
+	case *ast.Ident:
+		name = fn.Name
+	case *ast.SelectorExpr:
+		ident, ok := fn.X.(*ast.Ident)
+		if !ok {
+			return nil
+		}
+		pkg, name = ident.Name, fn.Sel.Name
+	default:
+		return nil
+	}
+	index, ok := translated[pkg][name]
+	switch {
+	case !ok:
+		return nil
+	case index < 0:
+		return call.Args
+	case index < len(call.Args):
+		return call.Args[index : index+1]
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+// Package i18n translates API messages and export headers. Messages are
+// written in English in the code and the English text is the catalogue key,
+// so an entry missing from a catalogue falls back to English.
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Locale string
+
+const (
+	EN Locale = "en"
+	ID Locale = "id"
+)
+
+// Locales lists the supported locales
+var Locales = []Locale{EN, ID}
+
+// catalogues maps an English message or format to its translation
+var catalogues = map[Locale]map[string]string{
+	ID: indonesian,
+}
+
+// Parse accepts a language tag such as "id", "id-ID" or "en_US"; "in" is
+// the old code for Indonesian
+func Parse(tag string) (Locale, bool) {
+	primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+	primary, _, _ = strings.Cut(primary, "_")
+	switch primary {
+	case "en":
+		return EN, true
+	case "id", "in":
+		return ID, true
+	}
+	return "", false
+}
+
+// FromAcceptLanguage picks the supported locale the client prefers most, or
+// fallback when it accepts none of them
+func FromAcceptLanguage(header string, fallback Locale) Locale {
+	type choice struct {
+		locale Locale
+		q      float64
+	}
+	var choices []choice
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if l, ok := Parse(tag); ok && q > 0 {
+			choices = append(choices, choice{l, q})
+		}
+	}
+	if len(choices) == 0 {
+		return fallback
+	}
+	sort.SliceStable(choices, func(i, j int) bool { return choices[i].q > choices[j].q })
+	return choices[0].locale
+}
+
+type contextKey struct{}
+
+// WithLocale stores the locale of a request in ctx
+func WithLocale(ctx context.Context, l Locale) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the locale stored by WithLocale, English without one
+func FromContext(ctx context.Context) Locale {
+	if l, ok := ctx.Value(contextKey{}).(Locale); ok {
+		return l
+	}
+	return EN
+}
+
+// Translate formats message in locale l. Without args message is returned
+// as is, so it may contain a literal %.
+func Translate(l Locale, message string, args ...interface{}) string {
+	if translated, ok := catalogues[l][message]; ok {
+		message = translated
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// T translates message into the locale of the request in ctx
+func T(ctx context.Context, message string, args ...interface{}) string {
+	return Translate(FromContext(ctx), message, args...)
+}
+
+// Text is a message with its arguments, kept so it can be translated when
+// the locale is known
+type Text struct {
+	Format string
+	Args   []interface{}
+}
+
+func Textf(format string, args ...interface{}) Text {
+	return Text{Format: format, Args: args}
+}
+
+// In formats the text in locale l
+func (t Text) In(l Locale) string {
+	return Translate(l, t.Format, t.Args...)
+}
+
+// String formats the text in English
+func (t Text) String() string {
+	return t.In(EN)
+}
@@ -0,0 +1,140 @@
+package i18n
+
+// indonesian is the Indonesian catalogue
+var indonesian = map[string]string{
+	// Request validation
+	"Validation failed":                                     "Validasi gagal",
+	"Request body is required":                              "Body request wajib diisi",
+	"Request body is not valid JSON":                        "Body request bukan JSON yang valid",
+	"Request body is larger than %d bytes":                  "Body request lebih dari %d byte",
+	"%s is required":                                        "%s wajib diisi",
+	"%s is invalid":                                         "%s tidak valid",
+	"%s must be at least %s characters":                     "%s minimal %s karakter",
+	"%s must be at least %s":                                "%s minimal %s",
+	"%s must be at most %s characters":                      "%s maksimal %s karakter",
+	"%s must be at most %s":                                 "%s maksimal %s",
+	"%s must be one of %s":                                  "%s harus salah satu dari %s",
+	"%s must be a string":                                   "%s harus berupa teks",
+	"%s must be true or false":                              "%s harus true atau false",
+	"%s must be a whole number":                             "%s harus berupa bilangan bulat",
+	"%s must be a number":                                   "%s harus berupa angka",
+	"%s must be a list":                                     "%s harus berupa daftar",
+	"%s must be an object":                                  "%s harus berupa objek",
+	"Invalid %s %q":                                         "%s %q tidak valid",
+	"Invalid %s, use YYYY-MM-DD or RFC3339":                 "%s tidak valid, gunakan YYYY-MM-DD atau RFC3339",
+	"Invalid start, use YYYY-MM-DD":                         "start tidak valid, gunakan YYYY-MM-DD",
+	"Invalid end, use YYYY-MM-DD":                           "end tidak valid, gunakan YYYY-MM-DD",
+	"Invalid date, use YYYY-MM-DD":                          "date tidak valid, gunakan YYYY-MM-DD",
+	"start is required, use YYYY-MM-DD or RFC3339":          "start wajib diisi, gunakan YYYY-MM-DD atau RFC3339",
+	"start must be before end":                              "start harus sebelum end",
+	"limit must be a positive number":                       "limit harus berupa angka positif",
+	"Invalid cursor":                                        "Cursor tidak valid",
+	"cursor was issued for a different sort":                "Cursor dibuat untuk urutan yang berbeda",
+	"sort must be one of %s (prefix with - for descending)": "sort harus salah satu dari %s (awali dengan - untuk urutan menurun)",
+	"interval must be one of hour, day, week, month":        "interval harus salah satu dari hour, day, week, month",
+	"Range too large for interval %s (max %d points)":       "Rentang terlalu besar untuk interval %s (maksimal %d titik)",
+	"group_by must be one of user, unit, location, grade":   "group_by harus salah satu dari user, unit, location, grade",
+	"Unknown setting %s":                                    "Pengaturan %s tidak dikenal",
+	"Invalid settings":                                      "Pengaturan tidak valid",
+
+	// Authentication and access
+	"Invalid credentials":                        "Username atau password salah",
+	"Authorization header required":              "Header Authorization wajib diisi",
+	"Invalid authorization header format":        "Format header Authorization tidak valid",
+	"Invalid token":                              "Token tidak valid",
+	"Invalid metrics token":                      "Token metrics tidak valid",
+	"Admin access required":                      "Hanya untuk admin",
+	"Old password is incorrect":                  "Password lama salah",
+	"Password changed successfully":              "Password berhasil diubah",
+	"Too many requests. Please try again later.": "Terlalu banyak permintaan. Silakan coba lagi nanti.",
+
+	// Not found and conflicts
+	"Route not found":                              "Rute tidak ditemukan",
+	"Method not allowed":                           "Metode tidak diizinkan",
+	"User not found":                               "User tidak ditemukan",
+	"Unit not found":                               "Unit tidak ditemukan",
+	"Scan not found":                               "Scan tidak ditemukan",
+	"Shift not found":                              "Shift tidak ditemukan",
+	"View not found":                               "View tidak ditemukan",
+	"Username already exists":                      "Username sudah digunakan",
+	"QR Code already exists":                       "QR Code sudah terdaftar",
+	"Shift name already exists":                    "Nama shift sudah digunakan",
+	"View name already exists":                     "Nama view sudah digunakan",
+	"View has invalid filters":                     "Filter view tidak valid",
+	"Cannot delete yourself":                       "Tidak dapat menghapus akun sendiri",
+	"Duplicate scan":                               "Scan duplikat",
+	"Invalid barcode: %v":                          "Barcode tidak valid: %v",
+	"Nothing to change, provide is_match or notes": "Tidak ada yang diubah, isi is_match atau notes",
+	"Edit window has passed, ask an admin to correct this scan": "Batas waktu edit sudah lewat, minta admin untuk mengoreksi scan ini",
+	"Scan is voided": "Scan sudah dibatalkan",
+	"Scan was changed by someone else, reload and try again": "Scan sudah diubah orang lain, muat ulang lalu coba lagi",
+	"User deleted":  "User dihapus",
+	"Unit deleted":  "Unit dihapus",
+	"Shift deleted": "Shift dihapus",
+	"View deleted":  "View dihapus",
+
+	// Server errors
+	"Internal server error":                            "Terjadi kesalahan pada server",
+	"Service temporarily unavailable, try again later": "Layanan sedang tidak tersedia, coba lagi nanti",
+	"Failed to load data":                              "Gagal memuat data",
+	"Failed to log in":                                 "Gagal login",
+	"Failed to generate token":                         "Gagal membuat token",
+	"Failed to set password":                           "Gagal mengatur password",
+	"Failed to hash password":                          "Gagal mengatur password",
+	"Failed to change password":                        "Gagal mengubah password",
+	"Failed to load users":                             "Gagal memuat user",
+	"Failed to create user":                            "Gagal membuat user",
+	"Failed to update user":                            "Gagal memperbarui user",
+	"Failed to delete user":                            "Gagal menghapus user",
+	"Failed to load units":                             "Gagal memuat unit",
+	"Failed to create unit":                            "Gagal membuat unit",
+	"Failed to update unit":                            "Gagal memperbarui unit",
+	"Failed to delete unit":                            "Gagal menghapus unit",
+	"Failed to load scans":                             "Gagal memuat scan",
+	"Failed to save scan":                              "Gagal menyimpan scan",
+	"Failed to load revisions":                         "Gagal memuat riwayat koreksi",
+	"Failed to retag scans":                            "Gagal menandai ulang scan",
+	"Failed to load shifts":                            "Gagal memuat shift",
+	"Failed to load shift data":                        "Gagal memuat data shift",
+	"Failed to create shift":                           "Gagal membuat shift",
+	"Failed to update shift":                           "Gagal memperbarui shift",
+	"Failed to delete shift":                           "Gagal menghapus shift",
+	"Failed to load views":                             "Gagal memuat view",
+	"Failed to load view":                              "Gagal memuat view",
+	"Failed to save view":                              "Gagal menyimpan view",
+	"Failed to delete view":                            "Gagal menghapus view",
+	"Failed to load stats":                             "Gagal memuat statistik",
+	"Failed to load summary":                           "Gagal memuat ringkasan",
+	"Failed to load daily report":                      "Gagal memuat laporan harian",
+	"Failed to load user performance":                  "Gagal memuat performa user",
+	"Failed to generate Excel":                         "Gagal membuat file Excel",
+	"Failed to load audit log":                         "Gagal memuat audit log",
+	"Failed to verify audit log":                       "Gagal memverifikasi audit log",
+	"Failed to read security log":                      "Gagal membaca log keamanan",
+	"Failed to load settings":                          "Gagal memuat pengaturan",
+	"Failed to save settings":                          "Gagal menyimpan pengaturan",
+
+	// Excel exports; headers that read the same in Indonesian are listed
+	// so the catalogue test can tell them from forgotten ones
+	"Scan Report": "Laporan Scan",
+	"Audit Log":   "Log Audit",
+	"No":          "No",
+	"Date":        "Tanggal",
+	"Time":        "Waktu",
+	"Unit":        "Unit",
+	"QR Code":     "Kode QR",
+	"Location":    "Lokasi",
+	"Scanner":     "Pemindai",
+	"Match":       "Sesuai",
+	"Mismatch":    "Tidak Sesuai",
+	"Notes":       "Catatan",
+	"Actor":       "Aktor",
+	"Action":      "Aksi",
+	"Entity":      "Entitas",
+	"Entity ID":   "ID Entitas",
+	"Changes":     "Perubahan",
+	"ID":          "ID",
+	"IP":          "IP",
+	"Request ID":  "ID Request",
+	"Hash":        "Hash",
+}
@@ -0,0 +1,73 @@
+// Package logging writes the structured JSON logs (access and SQL). Records
+// logged with a request context carry its request ID, so an error report
+// quoting X-Request-ID leads to every line of that request.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+type requestIDKey struct{}
+
+// level follows LOG_LEVEL and may change at runtime
+var level = new(slog.LevelVar)
+
+var logger = newLogger(os.Stdout)
+
+func newLogger(w io.Writer) *slog.Logger {
+	return slog.New(requestIDHandler{slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})})
+}
+
+// Logger returns the JSON logger
+func Logger() *slog.Logger {
+	return logger
+}
+
+// SetOutput sends the logs to w (stdout by default)
+func SetOutput(w io.Writer) {
+	logger = newLogger(w)
+}
+
+// SetLevel applies LOG_LEVEL: debug, info, warn or error. Anything else
+// keeps the current level; config validation rejects it first.
+func SetLevel(name string) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(name))); err == nil {
+		level.Set(l)
+	}
+}
+
+// WithRequestID returns ctx carrying the request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID in ctx, or ""
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestIDHandler adds request_id to records logged with a request context
+type requestIDHandler struct {
+	slog.Handler
+}
+
+func (h requestIDHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h requestIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return requestIDHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h requestIDHandler) WithGroup(name string) slog.Handler {
+	return requestIDHandler{h.Handler.WithGroup(name)}
+}
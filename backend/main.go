@@ -1,11 +1,27 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"scandata/apierror"
 	"scandata/config"
 	"scandata/database"
 	"scandata/handlers"
+	"scandata/health"
+	"scandata/logging"
+	"scandata/metrics"
 	"scandata/middleware"
+	"scandata/proxyproto"
+	"scandata/repository"
+	"scandata/services"
+	"scandata/web"
+	"syscall"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -13,13 +29,32 @@ import (
 
 func main() {
 	// Load config from .env
-	cfg := config.LoadConfig()
+	cfg, cfgErr := config.LoadConfig()
+
+	// Subcommands (migrate, config check, ...) run and exit without starting the server
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(cfg, cfgErr, os.Args[1:]))
+	}
+
+	// Refuse to start on invalid or insecure settings
+	if cfgErr != nil {
+		log.Fatalf("Invalid configuration:\n%v", cfgErr)
+	}
 
 	// Set Gin mode
 	gin.SetMode(cfg.GinMode)
 
 	// Initialize database
 	database.InitDB(cfg)
+	store := repository.NewGormStore(database.DB)
+
+	// Reloadable settings: environment first, then the values saved through
+	// the settings API
+	live := config.NewLive(cfg.Runtime)
+	live.OnChange(func(r config.Runtime) { logging.SetLevel(r.LogLevel) })
+	if err := handlers.LoadSettings(context.Background(), cfg, store, live); err != nil {
+		log.Printf("Warning: saved settings ignored: %v", err)
+	}
 
 	// Initialize security logger
 	middleware.InitSecurityLogger(cfg)
@@ -27,87 +62,173 @@ func main() {
 	// Setup Gin
 	r := gin.New()
 
+	// Client IPs: forwarded headers count only from TRUSTED_PROXIES
+	if err := middleware.TrustProxies(r, cfg); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	// Recovery middleware
 	r.Use(gin.Recovery())
 
+	// Request ID, locale and JSON access log first, so every later log line
+	// and rejected request is covered
+	r.Use(middleware.RequestIDMiddleware())
+	r.Use(middleware.LocaleMiddleware(cfg))
+	r.Use(middleware.AccessLogMiddleware())
+
+	// Request metrics (ahead of the rate limiter so rejected requests are counted)
+	r.Use(metrics.Middleware())
+
 	// Security middlewares (OWASP)
 	r.Use(middleware.ErrorHandlerMiddleware(cfg.IsProduction()))
 	r.Use(middleware.SecurityHeadersMiddleware())
 	r.Use(middleware.RequestSizeLimitMiddleware(cfg.MaxBodySize))
-	r.Use(middleware.RateLimitMiddleware(cfg))
+	rateLimiter := middleware.NewRateLimiter(cfg, live, middleware.NewMemoryRateLimitStore(), handlers.RateLimitClasses)
+	rateLimiter.APIKey = middleware.APIKeys(cfg.APIKeys)
+	r.Use(middleware.RateLimitMiddleware(rateLimiter))
 	r.Use(middleware.SecurityLoggerMiddleware(cfg))
 
 	// CORS configuration (A01: Broken Access Control fix)
 	corsConfig := cors.Config{
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders: []string{"Origin", "Content-Type", "Authorization", "X-API-Key", "X-Request-ID"},
+		ExposeHeaders: []string{
+			"X-Total-Count", "X-Next-Cursor", "Link", "X-Request-ID", "Deprecation", "Sunset",
+			"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After",
+		},
 		AllowCredentials: true,
 	}
 
-	// In debug mode, allow all origins for easier development
+	// In debug mode, allow all origins for easier development. In release the
+	// list is read on every request so a settings change applies at once.
 	if cfg.IsProduction() {
-		corsConfig.AllowOrigins = cfg.AllowedOrigins
+		corsConfig.AllowOriginFunc = func(origin string) bool {
+			return live.Get().AllowsOrigin(origin)
+		}
 	} else {
 		corsConfig.AllowAllOrigins = true
 	}
 	r.Use(cors.New(corsConfig))
 
-	// Custom logger
-	r.Use(gin.Logger())
-
-	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(cfg)
-	userHandler := handlers.NewUserHandler()
-	scanHandler := handlers.NewScanHandler()
-	reportHandler := handlers.NewReportHandler()
-
-	// Public routes
-	r.POST("/api/auth/login", authHandler.Login)
-
-	// Protected routes
-	protected := r.Group("/api")
-	protected.Use(middleware.AuthMiddleware(cfg))
-	{
-		// Auth
-		protected.GET("/auth/me", authHandler.Me)
-		protected.POST("/auth/change-password", authHandler.ChangePassword)
-
-		// Users (Admin only)
-		adminRoutes := protected.Group("/users")
-		adminRoutes.Use(middleware.AdminMiddleware())
-		{
-			adminRoutes.GET("", userHandler.List)
-			adminRoutes.POST("", userHandler.Create)
-			adminRoutes.GET("/:id", userHandler.Get)
-			adminRoutes.PUT("/:id", userHandler.Update)
-			adminRoutes.DELETE("/:id", userHandler.Delete)
+	// API routes
+	barcodes, err := services.NewBarcodeRegistry(cfg.BarcodeFormats, cfg.BarcodeTypePatterns)
+	if err != nil {
+		log.Fatalf("Invalid barcode configuration: %v", err)
+	}
+	handlers.RegisterRoutes(r, cfg, live, store, barcodes)
+
+	// Health checks: live = process is up, ready = dependencies are usable
+	healthHandler := handlers.NewHealthHandler(cfg)
+	r.GET("/health", healthHandler.Live)
+	r.GET("/health/live", healthHandler.Live)
+	r.GET("/health/ready", healthHandler.Ready)
+
+	// Unknown routes answer in the API error format too, unless the
+	// frontend is embedded (-tags embedfrontend) and serves them
+	r.HandleMethodNotAllowed = true
+	if web.Files != nil {
+		frontend, err := web.New(web.Files)
+		if err != nil {
+			log.Fatalf("Embedded frontend: %v", err)
+		}
+		r.NoRoute(frontend.Serve)
+		log.Println("Serving the embedded frontend")
+	} else {
+		r.NoRoute(apierror.NoRoute)
+	}
+	r.NoMethod(apierror.NoMethod)
+
+	// Prometheus metrics, either on a separate (internal) address or token protected
+	var metricsServer *http.Server
+	if cfg.MetricsAddr != "" {
+		metricsRouter := gin.New()
+		metricsRouter.GET("/metrics", metrics.Handler(cfg.MetricsToken))
+		metricsServer = &http.Server{
+			Addr:              cfg.MetricsAddr,
+			Handler:           metricsRouter,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
 		}
+		go func() {
+			log.Printf("Metrics available on %s/metrics", cfg.MetricsAddr)
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("Metrics server stopped: %v", err)
+			}
+		}()
+	} else if cfg.MetricsToken != "" {
+		r.GET("/metrics", metrics.Handler(cfg.MetricsToken))
+	} else {
+		log.Println("Metrics disabled (set METRICS_ADDR or METRICS_TOKEN to enable)")
+	}
 
-		// Scans
-		protected.POST("/scans", scanHandler.Submit)
-		protected.GET("/scans", scanHandler.List)
-		protected.GET("/scans/stats", scanHandler.GetStats)
+	srv := &http.Server{
+		Addr:              ":" + cfg.ServerPort,
+		Handler:           r,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
 
-		// Reports
-		protected.GET("/reports/summary", reportHandler.Summary)
-		protected.GET("/reports/daily", reportHandler.Daily)
+	listener, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		log.Fatalf("Server failed: %v", err)
+	}
+	if cfg.ProxyProtocol {
+		// Validated at startup
+		trusted, _ := config.ParseNetworks(cfg.TrustedProxies)
+		listener = proxyproto.NewListener(listener, trusted, cfg.ReadHeaderTimeout)
+	}
 
-		reportAdminRoutes := protected.Group("/reports")
-		reportAdminRoutes.Use(middleware.AdminMiddleware())
-		{
-			reportAdminRoutes.GET("/users", reportHandler.UserPerformance)
-			reportAdminRoutes.GET("/export", reportHandler.Export)
+	go func() {
+		log.Printf("Server running on port %s (mode: %s)", cfg.ServerPort, cfg.GinMode)
+		if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed: %v", err)
+		}
+	}()
+
+	// SIGHUP re-reads .env and the saved settings without a restart
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := handlers.LoadSettings(context.Background(), cfg, store, live); err != nil {
+				log.Printf("Settings not reloaded:\n%v", err)
+				continue
+			}
+			log.Println("Settings reloaded")
 		}
+	}()
+
+	// Tunggu SIGINT/SIGTERM (docker stop, redeploy) lalu selesaikan request yang sedang berjalan
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-quit
+	signal.Stop(quit)
+
+	log.Printf("Received %s, shutting down", sig)
+	health.SetShuttingDown()
+	if cfg.ShutdownDelay > 0 {
+		time.Sleep(cfg.ShutdownDelay)
 	}
 
-	// Health check
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status": "ok",
-			"mode":   cfg.GinMode,
-		})
-	})
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("Server did not drain in time: %v", err)
+	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(ctx); err != nil {
+			log.Printf("Metrics server did not drain in time: %v", err)
+		}
+	}
+
+	// Background jobs and resources, after the last request has finished
+	rateLimiter.Stop()
+	middleware.CloseSecurityLogger()
+	if err := database.Close(); err != nil {
+		log.Printf("Failed to close database: %v", err)
+	}
 
-	log.Printf("Server running on port %s (mode: %s)", cfg.ServerPort, cfg.GinMode)
-	r.Run(":" + cfg.ServerPort)
+	log.Println("Server stopped")
 }
@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"scandata/apierror"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "scandata"
+
+// Registry holds every scandata collector. A dedicated registry keeps
+// metrics from imported libraries out of /metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	RateLimitRejections = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_rejections_total",
+		Help:      "Requests rejected by the rate limiter.",
+	})
+
+	AuthFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_failures_total",
+		Help:      "Failed logins and rejected tokens by reason.",
+	}, []string{"reason"})
+
+	ScansSubmitted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "scans_submitted_total",
+		Help:      "Scans stored, by result (match or not_match).",
+	}, []string{"result"})
+
+	ExportDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "export_duration_seconds",
+		Help:      "Time taken to build Excel exports.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"export"})
+)
+
+// Auth failure reasons
+const (
+	AuthMissingToken       = "missing_token"
+	AuthInvalidToken       = "invalid_token"
+	AuthUnknownUser        = "unknown_user"
+	AuthInvalidCredentials = "invalid_credentials"
+	AuthForbidden          = "forbidden"
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		RateLimitRejections,
+		AuthFailures,
+		ScansSubmitted,
+		ExportDuration,
+	)
+}
+
+// RegisterDB exposes the connection pool statistics of db
+func RegisterDB(db *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// ScanSubmitted counts a stored scan
+func ScanSubmitted(isMatch bool) {
+	if isMatch {
+		ScansSubmitted.WithLabelValues("match").Inc()
+	} else {
+		ScansSubmitted.WithLabelValues("not_match").Inc()
+	}
+}
+
+// ObserveExport records how long an export took; call with defer
+func ObserveExport(export string, start time.Time) {
+	ExportDuration.WithLabelValues(export).Observe(time.Since(start).Seconds())
+}
+
+// Middleware records request count and latency. Requests that match no
+// route are grouped under "unmatched" so scanners probing random paths
+// cannot blow up the label cardinality.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		HTTPRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		HTTPDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler serves the registry in Prometheus text format. When token is set
+// scrapers must send it as a bearer token.
+func Handler(token string) gin.HandlerFunc {
+	h := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+
+	return func(c *gin.Context) {
+		if token != "" {
+			got := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				apierror.Abort(c, apierror.Unauthorized("Invalid metrics token"))
+				return
+			}
+		}
+		h.ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"log/slog"
+	"scandata/logging"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccessLogMiddleware writes one JSON line per request. Server errors are
+// logged at error level, client errors at warn, the rest at info, so
+// LOG_LEVEL=warn keeps only failed requests.
+func AccessLogMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+
+		log := logging.Logger()
+		ctx := c.Request.Context()
+		if !log.Enabled(ctx, level) {
+			return
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Float64("duration_ms", float64(time.Since(start).Nanoseconds())/1e6),
+			slog.Int("bytes", max(c.Writer.Size(), 0)),
+			slog.String("ip", c.ClientIP()),
+			slog.String("user_agent", c.Request.UserAgent()),
+		}
+		if username, exists := c.Get("username"); exists {
+			attrs = append(attrs, slog.Any("user", username))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+		log.LogAttrs(ctx, level, "request", attrs...)
+	}
+}
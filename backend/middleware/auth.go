@@ -1,10 +1,12 @@
 package middleware
 
 import (
-	"net/http"
+	"scandata/apierror"
 	"scandata/config"
-	"scandata/database"
+	"scandata/i18n"
+	"scandata/metrics"
 	"scandata/models"
+	"scandata/repository"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -18,57 +20,78 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+func AuthMiddleware(cfg *config.Config, users repository.UserRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
+			metrics.AuthFailures.WithLabelValues(metrics.AuthMissingToken).Inc()
+			apierror.Abort(c, apierror.Unauthorized("Authorization header required"))
 			return
 		}
 
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
-			c.Abort()
+		tokenString, ok := bearerToken(authHeader)
+		if !ok {
+			metrics.AuthFailures.WithLabelValues(metrics.AuthInvalidToken).Inc()
+			apierror.Abort(c, apierror.Unauthorized("Invalid authorization header format"))
 			return
 		}
 
-		tokenString := parts[1]
-		claims := &Claims{}
-
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(cfg.JWTSecret), nil
-		})
-
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
+		claims, err := parseClaims(cfg.JWTSecret, tokenString)
+		if err != nil {
+			metrics.AuthFailures.WithLabelValues(metrics.AuthInvalidToken).Inc()
+			apierror.Abort(c, apierror.Unauthorized("Invalid token"))
 			return
 		}
 
 		// Verify user still exists
-		var user models.User
-		if err := database.DB.First(&user, claims.UserID).Error; err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
-			c.Abort()
+		user, err := users.Get(c.Request.Context(), claims.UserID)
+		if err != nil {
+			metrics.AuthFailures.WithLabelValues(metrics.AuthUnknownUser).Inc()
+			apierror.Abort(c, apierror.Unauthorized("User not found"))
 			return
 		}
 
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("role", claims.Role)
-		c.Set("user", user)
+		c.Set("user", *user)
+		if locale, ok := i18n.Parse(user.Locale); ok {
+			setLocale(c, locale)
+		}
 		c.Next()
 	}
 }
 
+// bearerToken returns the token of an "Authorization: Bearer <token>" header
+func bearerToken(header string) (string, bool) {
+	parts := strings.Split(header, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// parseClaims verifies a token signed with secret and returns its claims
+func parseClaims(secret, tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	return claims, nil
+}
+
 func AdminMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role, exists := c.Get("role")
 		if !exists || role != models.RoleAdmin {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
-			c.Abort()
+			metrics.AuthFailures.WithLabelValues(metrics.AuthForbidden).Inc()
+			apierror.Abort(c, apierror.Forbidden("Admin access required"))
 			return
 		}
 		c.Next()
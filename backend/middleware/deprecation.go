@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeprecationMiddleware marks the responses of deprecated routes with a
+// Deprecation header (RFC 9745), a Sunset header (RFC 8594) when sunset is
+// set, and a Link to the route replacing them, which successor returns for
+// the request path.
+func DeprecationMiddleware(since, sunset time.Time, successor func(path string) string) gin.HandlerFunc {
+	deprecation := fmt.Sprintf("@%d", since.Unix())
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		h.Set("Deprecation", deprecation)
+		if !sunset.IsZero() {
+			h.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		h.Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor(c.Request.URL.Path)))
+		c.Next()
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDeprecation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	since := time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, time.April, 1, 0, 0, 0, 0, time.UTC)
+
+	r := gin.New()
+	r.GET("/api/units", DeprecationMiddleware(since, sunset, func(path string) string {
+		return "/api/v1" + path[len("/api"):]
+	}), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/units?limit=5", nil))
+	for header, want := range map[string]string{
+		"Deprecation": "@1792368000",
+		"Sunset":      "Thu, 01 Apr 2027 00:00:00 GMT",
+		"Link":        `</api/v1/units>; rel="successor-version"`,
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("%s: got %q, want %q", header, got, want)
+		}
+	}
+}
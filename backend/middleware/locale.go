@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"scandata/config"
+	"scandata/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LocaleMiddleware picks the language of messages and exports from
+// Accept-Language, or DEFAULT_LOCALE when the client accepts none we have.
+// AuthMiddleware replaces it with the preference of the signed-in user.
+func LocaleMiddleware(cfg *config.Config) gin.HandlerFunc {
+	fallback, ok := i18n.Parse(cfg.DefaultLocale)
+	if !ok {
+		fallback = i18n.EN
+	}
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Accept-Language")
+		setLocale(c, i18n.FromAcceptLanguage(c.GetHeader("Accept-Language"), fallback))
+		c.Next()
+	}
+}
+
+func setLocale(c *gin.Context, locale i18n.Locale) {
+	c.Request = c.Request.WithContext(i18n.WithLocale(c.Request.Context(), locale))
+	c.Header("Content-Language", string(locale))
+}
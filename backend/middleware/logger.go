@@ -1,35 +1,220 @@
 package middleware
 
 import (
+	"bufio"
+	"encoding/json"
+	"io"
 	"log"
 	"os"
 	"scandata/config"
+	"scandata/models"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-var securityLogger *log.Logger
+const (
+	EventAuthFailure = "AUTH_FAILURE"
+	EventRateLimit   = "RATE_LIMIT"
+	EventAdminAction = "ADMIN_ACTION"
+	EventSlowRequest = "SLOW_REQUEST"
+)
+
+// SecurityEvent is one line of the JSON security log
+type SecurityEvent struct {
+	Time      time.Time `json:"time"`
+	Type      string    `json:"type"`
+	IP        string    `json:"ip"`
+	User      string    `json:"user,omitempty"`
+	Method    string    `json:"method,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	Status    int       `json:"status,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Details   string    `json:"details,omitempty"`
+}
+
+var (
+	securityMu     sync.Mutex
+	securityWriter io.Writer
+	securityFile   *RotatingFile
+)
 
 func InitSecurityLogger(cfg *config.Config) {
 	if !cfg.EnableSecurityLog {
 		return
 	}
 
-	file, err := os.OpenFile("security.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	file, err := OpenRotatingFile(
+		cfg.SecurityLogPath,
+		cfg.SecurityLogMaxSizeMB<<20,
+		cfg.SecurityLogRotateInterval,
+		cfg.SecurityLogMaxBackups,
+		cfg.SecurityLogMaxAge,
+	)
+	if err != nil {
+		log.Printf("Warning: Could not open %s: %v", cfg.SecurityLogPath, err)
+		securityWriter = os.Stdout
+		return
+	}
+
+	securityFile = file
+	securityWriter = file
+}
+
+// CloseSecurityLogger flushes and closes the security log file
+func CloseSecurityLogger() {
+	securityMu.Lock()
+	defer securityMu.Unlock()
+
+	if securityFile != nil {
+		securityFile.Close()
+	}
+	securityWriter = nil
+	securityFile = nil
+}
+
+func LogSecurityEvent(event SecurityEvent) {
+	securityMu.Lock()
+	defer securityMu.Unlock()
+
+	if securityWriter == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	line, err := json.Marshal(event)
 	if err != nil {
-		log.Printf("Warning: Could not open security.log: %v", err)
-		securityLogger = log.New(os.Stdout, "[SECURITY] ", log.LstdFlags)
 		return
 	}
+	securityWriter.Write(append(line, '\n'))
+}
+
+// NewSecurityEvent fills in the request details of an event
+func NewSecurityEvent(c *gin.Context, eventType, details string) SecurityEvent {
+	event := SecurityEvent{
+		Type:      eventType,
+		IP:        c.ClientIP(),
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+		Status:    c.Writer.Status(),
+		RequestID: RequestID(c),
+		UserAgent: c.Request.UserAgent(),
+		Details:   details,
+	}
+	if u, exists := c.Get("username"); exists {
+		event.User = u.(string)
+	}
+	return event
+}
 
-	securityLogger = log.New(file, "", log.LstdFlags)
+// SecurityEventFilter narrows SearchSecurityEvents; empty fields match everything
+type SecurityEventFilter struct {
+	Type      string
+	IP        string
+	User      string
+	Path      string
+	RequestID string
+	Since     time.Time
+	Until     time.Time
+	Limit     int
 }
 
-func LogSecurityEvent(eventType, ip, username, details string) {
-	if securityLogger != nil {
-		securityLogger.Printf("[%s] IP=%s User=%s Details=%s", eventType, ip, username, details)
+func (f SecurityEventFilter) matches(e SecurityEvent) bool {
+	if f.Type != "" && !strings.EqualFold(e.Type, f.Type) {
+		return false
+	}
+	if f.IP != "" && e.IP != f.IP {
+		return false
+	}
+	if f.User != "" && e.User != f.User {
+		return false
+	}
+	if f.Path != "" && !strings.HasPrefix(e.Path, f.Path) {
+		return false
 	}
+	if f.RequestID != "" && e.RequestID != f.RequestID {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !e.Time.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// SearchSecurityEvents reads the current and rotated log files and returns
+// matching events, newest first. Lines that are not JSON (from the old text
+// format) are skipped.
+func SearchSecurityEvents(filter SecurityEventFilter) ([]SecurityEvent, error) {
+	securityMu.Lock()
+	file := securityFile
+	securityMu.Unlock()
+
+	events := []SecurityEvent{}
+	if file == nil {
+		return events, nil
+	}
+
+	for _, path := range file.Files() {
+		fileEvents, reachedSince, err := readSecurityEvents(path, filter)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		// Each file is oldest first; walk it backwards to stay newest first
+		for i := len(fileEvents) - 1; i >= 0; i-- {
+			events = append(events, fileEvents[i])
+			if filter.Limit > 0 && len(events) >= filter.Limit {
+				return events, nil
+			}
+		}
+
+		// Rotated files are older than everything after them
+		if reachedSince {
+			break
+		}
+	}
+
+	return events, nil
+}
+
+// readSecurityEvents returns the matching events of one file and whether
+// the file goes back to before filter.Since, so older files can be skipped
+func readSecurityEvents(path string, filter SecurityEventFilter) ([]SecurityEvent, bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+
+	var events []SecurityEvent
+	reachedSince := false
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var event SecurityEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		if !filter.Since.IsZero() && event.Time.Before(filter.Since) {
+			reachedSince = true
+			continue
+		}
+		if filter.matches(event) {
+			events = append(events, event)
+		}
+	}
+	return events, reachedSince, scanner.Err()
 }
 
 // SecurityLoggerMiddleware logs security-related events
@@ -40,62 +225,33 @@ func SecurityLoggerMiddleware(cfg *config.Config) gin.HandlerFunc {
 		c.Next()
 
 		// Log after request
-		if cfg.EnableSecurityLog && securityLogger != nil {
-			latency := time.Since(start)
-			status := c.Writer.Status()
-
-			// Log failed authentication attempts
-			if status == 401 || status == 403 {
-				username := ""
-				if u, exists := c.Get("username"); exists {
-					username = u.(string)
-				}
-
-				LogSecurityEvent(
-					"AUTH_FAILURE",
-					c.ClientIP(),
-					username,
-					c.Request.URL.Path,
-				)
-			}
+		if !cfg.EnableSecurityLog {
+			return
+		}
 
-			// Log suspicious activity (too many requests)
-			if status == 429 {
-				LogSecurityEvent(
-					"RATE_LIMIT",
-					c.ClientIP(),
-					"",
-					c.Request.URL.Path,
-				)
-			}
+		latency := time.Since(start)
+		status := c.Writer.Status()
 
-			// Log all admin actions
-			if status >= 200 && status < 300 {
-				if role, exists := c.Get("role"); exists && role == "admin" {
-					if c.Request.Method != "GET" {
-						username := ""
-						if u, exists := c.Get("username"); exists {
-							username = u.(string)
-						}
-						LogSecurityEvent(
-							"ADMIN_ACTION",
-							c.ClientIP(),
-							username,
-							c.Request.Method+" "+c.Request.URL.Path,
-						)
-					}
-				}
-			}
+		// Log failed authentication attempts
+		if status == 401 || status == 403 {
+			LogSecurityEvent(NewSecurityEvent(c, EventAuthFailure, ""))
+		}
 
-			// Log slow requests (potential DoS)
-			if latency > 5*time.Second {
-				LogSecurityEvent(
-					"SLOW_REQUEST",
-					c.ClientIP(),
-					"",
-					c.Request.URL.Path,
-				)
+		// Log suspicious activity (too many requests)
+		if status == 429 {
+			LogSecurityEvent(NewSecurityEvent(c, EventRateLimit, ""))
+		}
+
+		// Log all admin actions
+		if status >= 200 && status < 300 && c.Request.Method != "GET" {
+			if role, exists := c.Get("role"); exists && role == models.RoleAdmin {
+				LogSecurityEvent(NewSecurityEvent(c, EventAdminAction, ""))
 			}
 		}
+
+		// Log slow requests (potential DoS)
+		if latency > 5*time.Second {
+			LogSecurityEvent(NewSecurityEvent(c, EventSlowRequest, "latency="+latency.String()))
+		}
 	}
 }
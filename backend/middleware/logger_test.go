@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"scandata/config"
+	"testing"
+	"time"
+)
+
+func TestSearchSecurityEvents(t *testing.T) {
+	dir := t.TempDir()
+	InitSecurityLogger(&config.Config{EnableSecurityLog: true, SecurityLogPath: filepath.Join(dir, "security.log")})
+	t.Cleanup(CloseSecurityLogger)
+
+	base := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	at := func(hours int) time.Time { return base.Add(time.Duration(hours) * time.Hour) }
+
+	// Two rotated files, each oldest first, plus a directory in the place of
+	// the oldest backup that fails the search if it is ever opened
+	writeEvents := func(name string, modTime time.Time, events ...SecurityEvent) {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range events {
+			line, _ := json.Marshal(e)
+			f.Write(append(line, '\n'))
+		}
+		f.Close()
+		os.Chtimes(f.Name(), modTime, modTime)
+	}
+	writeEvents("security-20261001T100000,000.log", at(2),
+		SecurityEvent{Time: at(0), Type: EventAuthFailure, IP: "10.0.0.1"},
+		SecurityEvent{Time: at(1), Type: EventRateLimit, IP: "10.0.0.2"},
+	)
+	writeEvents("security-20261001T120000,000.log", at(4),
+		SecurityEvent{Time: at(3), Type: EventAuthFailure, IP: "10.0.0.1"},
+	)
+	oldest := filepath.Join(dir, "security-20260930T000000,000.log")
+	if err := os.Mkdir(oldest, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(oldest, at(-24), at(-24))
+	LogSecurityEvent(SecurityEvent{Time: at(5), Type: EventAuthFailure, IP: "10.0.0.3"})
+
+	events, err := SearchSecurityEvents(SecurityEventFilter{Type: EventAuthFailure, Since: at(1)})
+	if err != nil {
+		t.Fatalf("search read past the files covering since: %v", err)
+	}
+	if len(events) != 2 || !events[0].Time.Equal(at(5)) || !events[1].Time.Equal(at(3)) {
+		t.Fatalf("unexpected events %+v", events)
+	}
+
+	events, err = SearchSecurityEvents(SecurityEventFilter{IP: "10.0.0.1", Since: at(-1), Limit: 1})
+	if err != nil || len(events) != 1 || !events[0].Time.Equal(at(3)) {
+		t.Fatalf("limit not applied newest first: %+v %v", events, err)
+	}
+
+	// Without since every file is read, including the broken one
+	if _, err := SearchSecurityEvents(SecurityEventFilter{Type: EventAuthFailure}); err == nil {
+		t.Fatal("search without since skipped the oldest file")
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"scandata/config"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrustProxies makes c.ClientIP() read cfg.ClientIPHeader, but only on
+// requests from TRUSTED_PROXIES. From any other peer (or with no trusted
+// proxies at all) the header is ignored and the connection address is the
+// client, so a forged X-Forwarded-For cannot dodge the rate limiter or the
+// security log. Only one header is read: a proxy that sets X-Real-IP may
+// pass a client's own X-Forwarded-For through unchanged.
+func TrustProxies(r *gin.Engine, cfg *config.Config) error {
+	proxies := make([]string, 0, len(cfg.TrustedProxies))
+	for _, proxy := range cfg.TrustedProxies {
+		proxies = append(proxies, strings.TrimSpace(proxy))
+	}
+	r.ForwardedByClientIP = true
+	r.RemoteIPHeaders = []string{cfg.ClientIPHeader}
+	return r.SetTrustedProxies(proxies)
+}
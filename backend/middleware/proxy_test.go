@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"scandata/config"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTrustProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	clientIP := func(cfg *config.Config, remoteAddr string, headers map[string]string) string {
+		r := gin.New()
+		if err := TrustProxies(r, cfg); err != nil {
+			t.Fatal(err)
+		}
+		r.GET("/ip", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+
+		req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+		req.RemoteAddr = remoteAddr
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	spoofed := map[string]string{"X-Forwarded-For": "198.51.100.7", "X-Real-IP": "198.51.100.8"}
+	caddy := &config.Config{TrustedProxies: []string{"172.28.0.0/16"}, ClientIPHeader: "X-Real-IP"}
+
+	tests := []struct {
+		name       string
+		cfg        *config.Config
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{"no trusted proxies", &config.Config{ClientIPHeader: "X-Forwarded-For"}, "203.0.113.5:4000", spoofed, "203.0.113.5"},
+		{"untrusted peer", caddy, "203.0.113.5:4000", spoofed, "203.0.113.5"},
+		{"trusted proxy", caddy, "172.28.0.10:4000", spoofed, "198.51.100.8"},
+		{"trusted proxy, other header ignored", caddy, "172.28.0.10:4000", map[string]string{"X-Forwarded-For": "198.51.100.7"}, "172.28.0.10"},
+		{
+			"forwarded chain", &config.Config{TrustedProxies: []string{"10.0.0.1", " 10.0.0.2"}, ClientIPHeader: "X-Forwarded-For"},
+			"10.0.0.2:4000", map[string]string{"X-Forwarded-For": "198.51.100.7, 203.0.113.5, 10.0.0.1"}, "203.0.113.5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clientIP(tt.cfg, tt.remoteAddr, tt.headers); got != tt.want {
+				t.Fatalf("client IP %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"scandata/apierror"
+	"scandata/config"
+	"scandata/health"
+	"scandata/metrics"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const rateLimiterWorker = "rate_limiter_cleanup"
+
+// defaultRateLimitClass is the class of routes without their own policy
+const defaultRateLimitClass = "default"
+
+// RateLimitResult is the state of a bucket after a request
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until the next token, when not Allowed
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps the token buckets. Take spends one token from the
+// bucket of key, which refills at limit.Requests per limit.Period. A shared
+// store (e.g. Redis) lets several instances enforce one limit.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit config.RateLimit, now time.Time) (RateLimitResult, error)
+}
+
+// RateLimitSweeper is implemented by stores that drop idle buckets
+// themselves; the limiter calls Sweep every minute
+type RateLimitSweeper interface {
+	Sweep(now time.Time)
+}
+
+// MemoryRateLimitStore keeps the buckets of this process. A bucket is
+// dropped once it has refilled, so memory follows the number of clients
+// seen within one period rather than the number of requests.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	// full is when the bucket has refilled; from then on it equals a new one
+	full time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*tokenBucket)}
+}
+
+func (s *MemoryRateLimitStore) Take(_ context.Context, key string, limit config.RateLimit, now time.Time) (RateLimitResult, error) {
+	capacity := float64(limit.Requests)
+	perSecond := capacity / limit.Period.Seconds()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: capacity}
+		s.buckets[key] = b
+	} else {
+		elapsed := max(now.Sub(b.updated).Seconds(), 0)
+		// min also applies a lowered limit at once
+		b.tokens = min(b.tokens+elapsed*perSecond, capacity)
+	}
+	b.updated = now
+
+	result := RateLimitResult{Limit: limit.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / perSecond)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((capacity - b.tokens) / perSecond)
+	b.full = now.Add(result.Reset)
+	return result, nil
+}
+
+func (s *MemoryRateLimitStore) Sweep(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// RateLimiter applies a token bucket per route class and client. A request
+// is charged to its API key, else to the signed-in user, else to the client
+// IP, so clients behind one proxy address do not share a bucket. The limits are read from live on
+// every request, so a reload applies at once.
+type RateLimiter struct {
+	store     RateLimitStore
+	live      *config.Live
+	routes    map[string]string
+	jwtSecret string
+	// APIKey resolves an X-API-Key header to a stable id. While unset the
+	// header is ignored: an unverified key would let a client pick a fresh
+	// bucket for every request.
+	APIKey   func(key string) (id string, ok bool)
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewRateLimiter limits requests through store. routes maps "METHOD /path"
+// (the gin route path) to a class of config.Runtime.RateLimitPolicies; other
+// routes share the default limit.
+func NewRateLimiter(cfg *config.Config, live *config.Live, store RateLimitStore, routes map[string]string) *RateLimiter {
+	rl := &RateLimiter{
+		store:     store,
+		live:      live,
+		routes:    routes,
+		jwtSecret: cfg.JWTSecret,
+		stop:      make(chan struct{}),
+	}
+	// Cleanup idle buckets every minute
+	health.RegisterWorker(rateLimiterWorker, time.Minute)
+	go rl.cleanup()
+	return rl
+}
+
+// Stop ends the cleanup goroutine
+func (rl *RateLimiter) Stop() {
+	rl.stopOnce.Do(func() {
+		close(rl.stop)
+		health.UnregisterWorker(rateLimiterWorker)
+	})
+}
+
+func (rl *RateLimiter) cleanup() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-rl.stop:
+			return
+		case <-ticker.C:
+		}
+		health.Heartbeat(rateLimiterWorker)
+		if sweeper, ok := rl.store.(RateLimitSweeper); ok {
+			sweeper.Sweep(time.Now())
+		}
+	}
+}
+
+// Take charges the request to its bucket
+func (rl *RateLimiter) Take(c *gin.Context) (RateLimitResult, config.RateLimit, error) {
+	class, ok := rl.routes[c.Request.Method+" "+c.FullPath()]
+	if !ok {
+		class = defaultRateLimitClass
+	}
+	limit := rl.live.Get().RateLimitFor(class)
+	result, err := rl.store.Take(c.Request.Context(), class+"|"+rl.clientKey(c), limit, time.Now())
+	return result, limit, err
+}
+
+// clientKey identifies who the request is charged to
+func (rl *RateLimiter) clientKey(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" && rl.APIKey != nil {
+		if id, ok := rl.APIKey(key); ok {
+			return "key:" + id
+		}
+	}
+	// The limiter runs before AuthMiddleware, so the token is verified here;
+	// an invalid one falls back to the IP
+	if token, ok := bearerToken(c.GetHeader("Authorization")); ok {
+		if claims, err := parseClaims(rl.jwtSecret, token); err == nil {
+			return "user:" + strconv.FormatUint(uint64(claims.UserID), 10)
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// APIKeys verifies X-API-Key headers against keys (id to key, see
+// config.Config.APIKeys). Every key is compared in constant time.
+func APIKeys(keys map[string]string) func(key string) (id string, ok bool) {
+	return func(key string) (string, bool) {
+		found := ""
+		for id, want := range keys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(want)) == 1 {
+				found = id
+			}
+		}
+		return found, found != ""
+	}
+}
+
+// RateLimitMiddleware rejects requests over their limit with 429. Every
+// response carries RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset
+// (seconds until the bucket is full); a 429 adds Retry-After.
+func RateLimitMiddleware(limiter *RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result, limit, err := limiter.Take(c)
+		if err != nil {
+			// A failing store must not take the API down with it
+			log.Printf("Rate limiter unavailable: %v", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", ceilSeconds(result.Reset))
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%s", limit.Requests, ceilSeconds(limit.Period)))
+
+		if !result.Allowed {
+			metrics.RateLimitRejections.Inc()
+			c.Header("Retry-After", ceilSeconds(result.RetryAfter))
+			apierror.Abort(c, apierror.New(http.StatusTooManyRequests, apierror.CodeRateLimited,
+				"Too many requests. Please try again later."))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
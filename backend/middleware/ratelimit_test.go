@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"scandata/config"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	limit := config.RateLimit{Requests: 2, Period: 2 * time.Second}
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if result, _ := store.Take(context.Background(), "k", limit, now); !result.Allowed {
+			t.Fatalf("request %d rejected", i+1)
+		}
+	}
+	result, _ := store.Take(context.Background(), "k", limit, now)
+	if result.Allowed || result.Remaining != 0 || result.RetryAfter != time.Second {
+		t.Fatalf("expected a rejection with a 1s retry, got %+v", result)
+	}
+
+	// One token per second comes back
+	if result, _ = store.Take(context.Background(), "k", limit, now.Add(time.Second)); !result.Allowed {
+		t.Fatal("token was not refilled")
+	}
+
+	store.Sweep(now.Add(2 * time.Second))
+	if len(store.buckets) != 1 {
+		t.Fatal("bucket dropped before it refilled")
+	}
+	store.Sweep(now.Add(3 * time.Second))
+	if len(store.buckets) != 0 {
+		t.Fatal("refilled bucket was kept")
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	live := config.NewLive(config.Runtime{
+		RateLimitRequests: 3,
+		RateLimitDuration: time.Minute,
+		RateLimitPolicies: map[string]config.RateLimit{"login": {Requests: 1, Period: time.Minute}},
+	})
+	limiter := NewRateLimiter(cfg, live, NewMemoryRateLimitStore(), map[string]string{"POST /login": "login"})
+	defer limiter.Stop()
+
+	r := gin.New()
+	r.Use(RateLimitMiddleware(limiter))
+	r.POST("/login", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/data", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	signed := func(userID uint) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{UserID: userID}).SignedString([]byte(cfg.JWTSecret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + token
+	}
+	do := func(method, path, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// The login class has its own, stricter bucket
+	if w := do(http.MethodPost, "/login", ""); w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "1" {
+		t.Fatalf("first login: %d %v", w.Code, w.Header())
+	}
+	w := do(http.MethodPost, "/login", "")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Fatalf("second login: %d %v", w.Code, w.Header())
+	}
+
+	// Users behind one IP get a bucket each; a forged token counts as the IP
+	for i := 0; i < 3; i++ {
+		if w := do(http.MethodGet, "/data", signed(1)); w.Code != http.StatusOK {
+			t.Fatalf("user 1 request %d: %d", i+1, w.Code)
+		}
+	}
+	if w := do(http.MethodGet, "/data", signed(1)); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("user 1 over the limit: %d", w.Code)
+	}
+	if w := do(http.MethodGet, "/data", signed(2)); w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != "2" {
+		t.Fatalf("user 2: %d %v", w.Code, w.Header())
+	}
+	if w := do(http.MethodGet, "/data", "Bearer forged"); w.Header().Get("RateLimit-Remaining") != "2" {
+		t.Fatalf("forged token did not use the IP bucket: %v", w.Header())
+	}
+
+	// Verified API keys get a bucket each; an unknown key counts as the IP
+	limiter.APIKey = APIKeys(map[string]string{"scanner-1": "key-one", "scanner-2": "key-two"})
+	withKey := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/data", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	for _, key := range []string{"key-one", "key-two"} {
+		if w := withKey(key); w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != "2" {
+			t.Fatalf("API key %s did not get its own bucket: %d %v", key, w.Code, w.Header())
+		}
+	}
+	if w := withKey("key-three"); w.Header().Get("RateLimit-Remaining") != "1" {
+		t.Fatalf("unknown API key did not use the IP bucket: %v", w.Header())
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"scandata/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength matches the audit_logs.request_id column
+const maxRequestIDLength = 64
+
+// RequestIDMiddleware takes the X-Request-ID sent by the client or proxy,
+// or generates one, and echoes it in the response. The ID is stored in the
+// request context, so audit entries, security events and SQL logs of the
+// request carry it.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Set("request_id", id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Header(requestIDHeader, id)
+		c.Next()
+	}
+}
+
+// RequestID returns the ID assigned by RequestIDMiddleware
+func RequestID(c *gin.Context) string {
+	return c.GetString("request_id")
+}
+
+// validRequestID accepts short IDs of letters, digits and - _ . : so a
+// client cannot inject anything into the logs through the header
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"scandata/logging"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var logs bytes.Buffer
+	logging.SetOutput(&logs)
+	defer logging.SetOutput(os.Stdout)
+	logging.SetLevel("info")
+
+	r := gin.New()
+	r.Use(RequestIDMiddleware(), AccessLogMiddleware(), ErrorHandlerMiddleware(true))
+	r.GET("/ok", func(c *gin.Context) {
+		logging.Logger().InfoContext(c.Request.Context(), "handler")
+		c.Status(http.StatusOK)
+	})
+	r.GET("/panic", func(c *gin.Context) { panic("boom") })
+
+	do := func(path, id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if id != "" {
+			req.Header.Set("X-Request-ID", id)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// An incoming ID is kept and tags every log line of the request
+	if w := do("/ok", "abc-123"); w.Header().Get("X-Request-ID") != "abc-123" {
+		t.Fatalf("incoming ID not echoed: %v", w.Header())
+	}
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected handler and access lines, got %q", lines)
+	}
+	for _, line := range lines {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("log line is not JSON: %s", line)
+		}
+		if record["request_id"] != "abc-123" {
+			t.Fatalf("log line without request ID: %s", line)
+		}
+	}
+
+	// A missing or unsafe ID is replaced
+	for _, id := range []string{"", "bad id\n", strings.Repeat("x", 65)} {
+		got := do("/ok", id).Header().Get("X-Request-ID")
+		if !validRequestID(got) || got == id {
+			t.Fatalf("ID %q replaced with %q", id, got)
+		}
+	}
+
+	// Errors quote the ID
+	var body struct {
+		RequestID string `json:"request_id"`
+	}
+	w := do("/panic", "err-1")
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.RequestID != "err-1" {
+		t.Fatalf("error without request ID: %s", w.Body.String())
+	}
+}
@@ -0,0 +1,188 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotatingFile is an append-only log file that rotates when it grows past
+// maxSize bytes or has been open longer than interval. Rotated files are
+// renamed to <name>-<timestamp><ext> and pruned by count and age.
+type RotatingFile struct {
+	path       string
+	maxSize    int64
+	interval   time.Duration
+	maxBackups int
+	maxAge     time.Duration
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+const rotateTimeFormat = "20060102T150405,000"
+
+func OpenRotatingFile(path string, maxSize int64, interval time.Duration, maxBackups int, maxAge time.Duration) (*RotatingFile, error) {
+	r := &RotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		interval:   interval,
+		maxBackups: maxBackups,
+		maxAge:     maxAge,
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	tooBig := r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize
+	tooOld := r.interval > 0 && time.Since(r.openedAt) >= r.interval && r.size > 0
+	if tooBig || tooOld {
+		if err := r.rotate(); err != nil {
+			// A file past its limit beats lost entries: keep appending to
+			// the current one and try again on a later write
+			log.Printf("Warning: Could not rotate %s: %v", r.path, err)
+			if r.file == nil {
+				return 0, err
+			}
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// Files returns the current file followed by rotated files, newest first
+func (r *RotatingFile) Files() []string {
+	return append([]string{r.path}, r.backups()...)
+}
+
+func (r *RotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file = file
+	r.size = info.Size()
+	r.openedAt = time.Now()
+	return nil
+}
+
+func (r *RotatingFile) rotate() error {
+	err := r.file.Close()
+	r.file = nil
+	if err != nil {
+		return errors.Join(err, r.open())
+	}
+
+	ext := filepath.Ext(r.path)
+	base := strings.TrimSuffix(r.path, ext)
+	backup := fmt.Sprintf("%s-%s%s", base, time.Now().Format(rotateTimeFormat), ext)
+	// Several rotations within one second must not overwrite each other
+	for i := 1; fileExists(backup); i++ {
+		backup = fmt.Sprintf("%s-%s.%d%s", base, time.Now().Format(rotateTimeFormat), i, ext)
+	}
+	if err := os.Rename(r.path, backup); err != nil {
+		// Reopen the current file so the log goes on
+		return errors.Join(err, r.open())
+	}
+
+	if err := r.open(); err != nil {
+		// Move the old file back and go on writing to it
+		if renameErr := os.Rename(backup, r.path); renameErr != nil {
+			return errors.Join(err, renameErr)
+		}
+		return errors.Join(err, r.open())
+	}
+	r.prune()
+	return nil
+}
+
+func (r *RotatingFile) prune() {
+	for i, backup := range r.backups() {
+		info, err := os.Stat(backup)
+		if err != nil {
+			continue
+		}
+		tooMany := r.maxBackups > 0 && i >= r.maxBackups
+		tooOld := r.maxAge > 0 && time.Since(info.ModTime()) > r.maxAge
+		if tooMany || tooOld {
+			os.Remove(backup)
+		}
+	}
+}
+
+// backups lists rotated files newest first. Only names rotate gives out
+// count, so other files next to the log are never pruned.
+func (r *RotatingFile) backups() []string {
+	ext := filepath.Ext(r.path)
+	base := strings.TrimSuffix(r.path, ext)
+	rotated := regexp.MustCompile(`^` + regexp.QuoteMeta(filepath.Base(base)) + `-\d{8}T\d{6},\d{3}(\.\d+)?` + regexp.QuoteMeta(ext) + `$`)
+
+	var matches []string
+	candidates, _ := filepath.Glob(base + "-*" + ext)
+	for _, candidate := range candidates {
+		if rotated.MatchString(filepath.Base(candidate)) {
+			matches = append(matches, candidate)
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, errA := os.Stat(matches[i])
+		b, errB := os.Stat(matches[j])
+		if errA != nil || errB != nil {
+			return matches[i] > matches[j]
+		}
+		if a.ModTime().Equal(b.ModTime()) {
+			return matches[i] > matches[j]
+		}
+		return a.ModTime().After(b.ModTime())
+	})
+	return matches
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
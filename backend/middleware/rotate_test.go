@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Only files named the way rotate names them are backups; anything else
+// next to the log is neither listed nor pruned
+func TestRotatingFileKeepsForeignFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "security.log")
+	foreign := []string{"security.log.bak", "security-archive.log", "security-2026.log"}
+	for _, name := range foreign {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("keep\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := OpenRotatingFile(path, 10, 0, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for i := 0; i < 4; i++ {
+		if _, err := r.Write([]byte("0123456789\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := r.Files()
+	if len(files) != 2 || !strings.HasPrefix(filepath.Base(files[1]), "security-") {
+		t.Fatalf("want the log and one backup, got %v", files)
+	}
+	for _, name := range foreign {
+		if !fileExists(filepath.Join(dir, name)) {
+			t.Errorf("%s was pruned", name)
+		}
+	}
+}
+
+// A failed rename leaves the log writable
+func TestRotatingFileSurvivesFailedRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "security.log")
+	r, err := OpenRotatingFile(path, 10, 0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := r.Write([]byte("0123456789\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Renaming a file that is gone fails
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Write([]byte("after\n")); err != nil {
+		t.Fatalf("write after failed rotation: %v", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil || string(content) != "after\n" {
+		t.Fatalf("log not reopened: %q %v", content, err)
+	}
+}
@@ -1,97 +1,13 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http"
-	"scandata/config"
-	"sync"
-	"time"
+	"scandata/apierror"
 
 	"github.com/gin-gonic/gin"
 )
 
-// RateLimiter implements a simple in-memory rate limiter
-type RateLimiter struct {
-	requests map[string][]time.Time
-	mu       sync.RWMutex
-	limit    int
-	window   time.Duration
-}
-
-func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
-	rl := &RateLimiter{
-		requests: make(map[string][]time.Time),
-		limit:    limit,
-		window:   window,
-	}
-	// Cleanup old entries every minute
-	go rl.cleanup()
-	return rl
-}
-
-func (rl *RateLimiter) cleanup() {
-	for {
-		time.Sleep(time.Minute)
-		rl.mu.Lock()
-		now := time.Now()
-		for ip, times := range rl.requests {
-			var valid []time.Time
-			for _, t := range times {
-				if now.Sub(t) < rl.window {
-					valid = append(valid, t)
-				}
-			}
-			if len(valid) == 0 {
-				delete(rl.requests, ip)
-			} else {
-				rl.requests[ip] = valid
-			}
-		}
-		rl.mu.Unlock()
-	}
-}
-
-func (rl *RateLimiter) Allow(ip string) bool {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	now := time.Now()
-	times := rl.requests[ip]
-
-	// Remove old entries
-	var valid []time.Time
-	for _, t := range times {
-		if now.Sub(t) < rl.window {
-			valid = append(valid, t)
-		}
-	}
-
-	if len(valid) >= rl.limit {
-		return false
-	}
-
-	rl.requests[ip] = append(valid, now)
-	return true
-}
-
-// RateLimitMiddleware limits requests per IP
-func RateLimitMiddleware(cfg *config.Config) gin.HandlerFunc {
-	limiter := NewRateLimiter(cfg.RateLimitRequests, cfg.RateLimitDuration)
-
-	return func(c *gin.Context) {
-		ip := c.ClientIP()
-
-		if !limiter.Allow(ip) {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error": "Too many requests. Please try again later.",
-			})
-			c.Abort()
-			return
-		}
-
-		c.Next()
-	}
-}
-
 // SecurityHeadersMiddleware adds security headers
 func SecurityHeadersMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -130,17 +46,13 @@ func ErrorHandlerMiddleware(isProduction bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
 			if err := recover(); err != nil {
-				if isProduction {
-					c.JSON(http.StatusInternalServerError, gin.H{
-						"error": "Internal server error",
-					})
-				} else {
-					c.JSON(http.StatusInternalServerError, gin.H{
-						"error":   "Internal server error",
-						"details": err,
-					})
+				// The request ID lets a user's error report be matched
+				// with the logs
+				e := apierror.Internal("Internal server error", fmt.Errorf("panic: %v", err))
+				if !isProduction {
+					e.WithDetail("panic", fmt.Sprint(err))
 				}
-				c.Abort()
+				apierror.Abort(c, e)
 			}
 		}()
 		c.Next()
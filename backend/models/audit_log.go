@@ -0,0 +1,56 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	AuditCreate         = "create"
+	AuditUpdate         = "update"
+	AuditDelete         = "delete"
+	AuditPasswordChange = "password_change"
+)
+
+var ErrAuditImmutable = errors.New("audit log entries cannot be changed")
+
+// AuditLog is one entry of the append-only audit trail. Each row stores the
+// hash of the previous row, so editing or removing a row breaks the chain.
+type AuditLog struct {
+	ID         uint            `gorm:"primaryKey" json:"id"`
+	ActorID    *uint           `gorm:"index" json:"actor_id"`
+	ActorName  string          `gorm:"size:50" json:"actor_name"`
+	Action     string          `gorm:"size:30;not null;index" json:"action"`
+	EntityType string          `gorm:"size:50;not null;index:idx_audit_logs_entity" json:"entity_type"`
+	EntityID   string          `gorm:"size:50;index:idx_audit_logs_entity" json:"entity_id"`
+	Before     json.RawMessage `gorm:"type:text" json:"before"`
+	After      json.RawMessage `gorm:"type:text" json:"after"`
+	Diff       json.RawMessage `gorm:"type:text" json:"diff"`
+	IP         string          `gorm:"size:45" json:"ip"`
+	RequestID  string          `gorm:"size:64;index" json:"request_id"`
+	PrevHash   string          `gorm:"size:64;not null" json:"prev_hash"`
+	Hash       string          `gorm:"size:64;not null;uniqueIndex" json:"hash"`
+	CreatedAt  time.Time       `gorm:"index" json:"created_at"`
+}
+
+// AuditChainHeadID is the id of the only AuditChainHead row
+const AuditChainHeadID = 1
+
+// AuditChainHead holds the hash of the newest audit entry. Appending locks
+// the row until commit, which serialises writers in every process, also
+// while audit_logs is still empty.
+type AuditChainHead struct {
+	ID   uint   `gorm:"primaryKey;autoIncrement:false"`
+	Hash string `gorm:"size:64;not null;default:''"`
+}
+
+func (a *AuditLog) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditImmutable
+}
+
+func (a *AuditLog) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditImmutable
+}
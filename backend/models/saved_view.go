@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// SavedView is a named scan filter set. Filters holds the JSON encoded criteria.
+type SavedView struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_saved_views_user_name" json:"user_id"`
+	Name      string    `gorm:"size:100;not null;uniqueIndex:idx_saved_views_user_name" json:"name"`
+	Filters   string    `gorm:"type:text;not null" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
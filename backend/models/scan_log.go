@@ -4,12 +4,35 @@ import (
 	"time"
 )
 
+// ScanLog is a single scan. DuplicateOfID points at the original scan when this
+// one was flagged as a repeat; DuplicateCount counts repeats merged into it.
+// Voided scans stay in the table but are excluded from reports. Revision counts
+// the corrections recorded in ScanRevision. The notes search index differs per
+// database and is created by the migrations, or by database.dialectIndexes
+// when a legacy database is adopted.
 type ScanLog struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Barcode   string    `gorm:"size:100;not null;index" json:"barcode"`
-	UserID    uint      `gorm:"not null;index" json:"user_id"`
-	IsMatch   bool      `gorm:"not null" json:"is_match"`
-	Notes     string    `gorm:"size:500" json:"notes"`
-	ScannedAt time.Time `gorm:"not null;index" json:"scanned_at"`
-	User      User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	Barcode        string     `gorm:"size:100;not null;index" json:"barcode"`
+	UserID         uint       `gorm:"not null;index" json:"user_id"`
+	UnitID         *uint      `gorm:"index" json:"unit_id"`
+	IsMatch        bool       `gorm:"not null" json:"is_match"`
+	Notes          string     `gorm:"size:500" json:"notes"`
+	Symbology      string     `gorm:"size:30" json:"symbology"`
+	GTIN           string     `gorm:"column:gtin;size:14;index" json:"gtin,omitempty"`
+	Lot            string     `gorm:"size:20;index" json:"lot,omitempty"`
+	SerialNumber   string     `gorm:"size:20" json:"serial_number,omitempty"`
+	ExpiryDate     *time.Time `gorm:"type:date" json:"expiry_date,omitempty"`
+	ScannedAt      time.Time  `gorm:"not null;index" json:"scanned_at"`
+	ShiftID        *uint      `gorm:"index" json:"shift_id"`
+	ShiftDate      *time.Time `gorm:"type:date;index" json:"shift_date"`
+	DuplicateOfID  *uint      `gorm:"index" json:"duplicate_of_id"`
+	DuplicateCount int        `gorm:"not null;default:0" json:"duplicate_count"`
+	IsVoided       bool       `gorm:"not null;default:false;index" json:"is_voided"`
+	VoidedAt       *time.Time `json:"voided_at,omitempty"`
+	VoidedBy       *uint      `json:"voided_by,omitempty"`
+	VoidReason     string     `gorm:"size:500" json:"void_reason,omitempty"`
+	Revision       int        `gorm:"not null;default:0" json:"revision"`
+	User           User       `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Unit           Unit       `gorm:"foreignKey:UnitID" json:"unit,omitempty"`
+	Shift          *Shift     `gorm:"foreignKey:ShiftID" json:"shift,omitempty"`
 }
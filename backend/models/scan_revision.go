@@ -0,0 +1,39 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	RevisionAmend = "amend"
+	RevisionVoid  = "void"
+)
+
+var ErrRevisionImmutable = errors.New("scan revisions cannot be changed")
+
+// ScanRevision records one correction of a ScanLog. Before and After hold JSON
+// snapshots of the editable fields. Rows are write-once.
+type ScanRevision struct {
+	ID        uint            `gorm:"primaryKey" json:"id"`
+	ScanLogID uint            `gorm:"not null;index" json:"scan_log_id"`
+	Revision  int             `gorm:"not null" json:"revision"`
+	Action    string          `gorm:"size:20;not null" json:"action"`
+	Reason    string          `gorm:"size:500;not null" json:"reason"`
+	ChangedBy uint            `gorm:"not null" json:"changed_by"`
+	Before    json.RawMessage `gorm:"type:text" json:"before"`
+	After     json.RawMessage `gorm:"type:text" json:"after"`
+	CreatedAt time.Time       `json:"created_at"`
+	User      User            `gorm:"foreignKey:ChangedBy" json:"user,omitempty"`
+}
+
+func (r *ScanRevision) BeforeUpdate(tx *gorm.DB) error {
+	return ErrRevisionImmutable
+}
+
+func (r *ScanRevision) BeforeDelete(tx *gorm.DB) error {
+	return ErrRevisionImmutable
+}
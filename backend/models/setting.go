@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// Setting is a runtime configuration value saved through the settings API.
+// Key is the environment variable it overrides, e.g. RATE_LIMIT_REQUESTS.
+type Setting struct {
+	Key       string    `gorm:"primaryKey;size:100" json:"key"`
+	Value     string    `gorm:"type:text;not null" json:"value"`
+	UpdatedBy *uint     `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
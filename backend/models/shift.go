@@ -0,0 +1,102 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Shift is a recurring work period. StartTime and EndTime are "HH:MM" in
+// server local time; an EndTime at or before StartTime crosses midnight.
+// Days lists the weekdays the shift starts on ("mon,tue,..."), empty means every day.
+type Shift struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	Name      string         `gorm:"uniqueIndex;size:50;not null" json:"name"`
+	StartTime string         `gorm:"size:5;not null" json:"start_time"`
+	EndTime   string         `gorm:"size:5;not null" json:"end_time"`
+	Days      string         `gorm:"size:50" json:"days"`
+	IsActive  bool           `gorm:"default:true" json:"is_active"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (s *Shift) Validate() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return errors.New("name is required")
+	}
+	if _, err := parseClock(s.StartTime); err != nil {
+		return fmt.Errorf("start_time: %w", err)
+	}
+	if _, err := parseClock(s.EndTime); err != nil {
+		return fmt.Errorf("end_time: %w", err)
+	}
+	for _, day := range s.dayList() {
+		if _, ok := weekdayNames[day]; !ok {
+			return fmt.Errorf("days: unknown day %q, use sun,mon,tue,wed,thu,fri,sat", day)
+		}
+	}
+	return nil
+}
+
+// Duration returns how long the shift lasts
+func (s *Shift) Duration() time.Duration {
+	start, _ := parseClock(s.StartTime)
+	end, _ := parseClock(s.EndTime)
+	if end <= start {
+		end += 24 * time.Hour
+	}
+	return end - start
+}
+
+// StartOn returns the moment the shift starts on the given calendar date
+func (s *Shift) StartOn(date time.Time) time.Time {
+	start, _ := parseClock(s.StartTime)
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	return day.Add(start)
+}
+
+// RunsOn reports whether the shift starts on the given weekday
+func (s *Shift) RunsOn(day time.Weekday) bool {
+	days := s.dayList()
+	if len(days) == 0 {
+		return true
+	}
+	for _, d := range days {
+		if weekdayNames[d] == day {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Shift) dayList() []string {
+	var days []string
+	for _, d := range strings.Split(s.Days, ",") {
+		if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+			days = append(days, d)
+		}
+	}
+	return days
+}
+
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, errors.New("must be in HH:MM format")
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
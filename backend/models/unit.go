@@ -10,6 +10,7 @@ type Unit struct {
 	ID            uint           `gorm:"primaryKey" json:"id"`
 	QRCode        string         `gorm:"uniqueIndex;size:100;not null" json:"qr_code"`
 	Name          string         `gorm:"size:200;not null" json:"name"`
+	Type          string         `gorm:"size:50;index" json:"type"`
 	ExpectedGrade string         `gorm:"size:100" json:"expected_grade"`
 	Location      string         `gorm:"size:200" json:"location"`
 	IsActive      bool           `gorm:"default:true" json:"is_active"`
@@ -19,7 +19,8 @@ type User struct {
 	Username     string         `gorm:"uniqueIndex;size:50;not null" json:"username"`
 	PasswordHash string         `gorm:"size:255;not null" json:"-"`
 	Name         string         `gorm:"size:100;not null" json:"name"`
-	Role         Role           `gorm:"type:enum('admin','user');default:'user'" json:"role"`
+	Role         Role           `gorm:"size:10;default:'user'" json:"role"`
+	Locale       string         `gorm:"size:5;not null;default:''" json:"locale"` // en or id; empty follows Accept-Language
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
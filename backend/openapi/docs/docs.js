@@ -0,0 +1,116 @@
+// Renders openapi.json as a list of operations. Kept dependency free so the
+// page works offline and under the API's Content-Security-Policy.
+(async function () {
+    const container = document.getElementById('operations');
+    const filter = document.getElementById('filter');
+
+    let spec;
+    try {
+        const response = await fetch('openapi.json');
+        spec = await response.json();
+    } catch (err) {
+        container.textContent = 'Failed to load openapi.json: ' + err;
+        return;
+    }
+
+    const schemas = spec.components.schemas;
+
+    // example builds a sample value of a schema, following $refs once
+    function example(schema, seen) {
+        if (!schema) return null;
+        if (schema.$ref) {
+            const name = schema.$ref.split('/').pop();
+            if (seen.has(name)) return '<' + name + '>';
+            return example(schemas[name], new Set([...seen, name]));
+        }
+        if (schema.enum) return schema.enum[0];
+        switch (schema.type) {
+            case 'object': {
+                if (!schema.properties) return {};
+                const value = {};
+                for (const [key, prop] of Object.entries(schema.properties)) {
+                    value[key] = example(prop, seen);
+                }
+                return value;
+            }
+            case 'array': return [example(schema.items, seen)];
+            case 'integer': return 0;
+            case 'number': return 0.0;
+            case 'boolean': return false;
+            case 'string':
+                return schema.format === 'date-time' ? '2024-01-01T00:00:00Z' : 'string';
+        }
+        return null;
+    }
+
+    function el(tag, attrs, ...children) {
+        const node = document.createElement(tag);
+        Object.assign(node, attrs);
+        for (const child of children) {
+            node.append(child);
+        }
+        return node;
+    }
+
+    function schemaBlock(schema) {
+        return el('pre', {}, JSON.stringify(example(schema, new Set()), null, 2));
+    }
+
+    function operation(path, method, op) {
+        const body = el('div');
+        if (op.parameters && op.parameters.length) {
+            const table = el('table', {}, el('tr', {}, el('th', {}, 'Parameter'), el('th', {}, 'In'), el('th', {}, 'Description')));
+            for (const p of op.parameters) {
+                const type = p.schema.enum ? p.schema.enum.join(' | ') : p.schema.type;
+                table.append(el('tr', {},
+                    el('td', {}, el('code', {}, p.name + (p.required ? ' *' : ''))),
+                    el('td', {}, p.in),
+                    el('td', {}, (p.description || '') + ' (' + type + ')')));
+            }
+            body.append(table);
+        }
+        if (op.requestBody) {
+            const media = Object.values(op.requestBody.content)[0];
+            body.append(el('h4', {}, 'Request body'), schemaBlock(media.schema));
+        }
+        for (const [status, response] of Object.entries(op.responses)) {
+            body.append(el('h4', {}, status + ' ' + response.description));
+            if (response.content) {
+                const [type, media] = Object.entries(response.content)[0];
+                body.append(type === 'application/json' ? schemaBlock(media.schema) : el('p', {}, type));
+            }
+        }
+
+        const summary = el('summary', {},
+            el('span', { className: 'method ' + method }, method),
+            el('code', {}, path), ' ', op.summary || '',
+            op.security ? el('span', { className: 'lock' }, ' 🔒') : '',
+            op.deprecated ? el('span', { className: 'badge' }, 'deprecated') : '');
+        const details = el('details', { className: op.deprecated ? 'deprecated' : '' }, summary, body);
+        details.dataset.search = (method + ' ' + path + ' ' + (op.summary || '')).toLowerCase();
+        return details;
+    }
+
+    const byTag = new Map();
+    for (const [path, item] of Object.entries(spec.paths)) {
+        for (const [method, op] of Object.entries(item)) {
+            const tag = (op.tags && op.tags[0]) || 'other';
+            if (!byTag.has(tag)) byTag.set(tag, []);
+            byTag.get(tag).push(operation(path, method, op));
+        }
+    }
+
+    container.textContent = '';
+    const order = (spec.tags || []).map(t => t.name);
+    const tags = [...byTag.keys()].sort((a, b) => (order.indexOf(a) + 1 || 99) - (order.indexOf(b) + 1 || 99));
+    for (const tag of tags) {
+        container.append(el('h2', {}, tag), ...byTag.get(tag));
+    }
+
+    filter.addEventListener('input', () => {
+        const q = filter.value.toLowerCase();
+        for (const details of container.querySelectorAll('details')) {
+            details.hidden = !details.dataset.search.includes(q);
+        }
+    });
+})();
@@ -0,0 +1,294 @@
+// Package openapi builds the OpenAPI 3 document of the API from a route
+// table and the Go types the handlers bind and return, so request and
+// response schemas follow the code instead of being written by hand.
+package openapi
+
+import (
+	"embed"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Docs holds the API docs page served next to the document
+//
+//go:embed docs
+var Docs embed.FS
+
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Tags       []Tag                `json:"tags,omitempty"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps lower case HTTP methods to operations
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	OperationID string                `json:"operationId"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]*Header   `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// Access is who may call a route
+type Access int
+
+const (
+	Public Access = iota
+	Authenticated
+	Admin
+)
+
+// Route describes one registered route. Request and Response are values of
+// the JSON body types, e.g. CreateUserRequest{} or []models.User{}.
+type Route struct {
+	Method  string
+	Path    string // as registered with gin, e.g. /api/units/:id
+	Tag     string
+	Summary string
+	Access  Access
+
+	Query    []Parameter
+	Request  interface{}
+	Response interface{}
+	// Status is the success status, 200 when zero
+	Status int
+	// ContentType of a response that is not JSON, e.g. an Excel file
+	ContentType string
+	// Sort lists the sort fields of a paginated list; it adds the limit,
+	// sort and cursor parameters and the paging headers
+	Sort []string
+	// Deprecated marks a route clients should move away from
+	Deprecated bool
+}
+
+// Query parameter helpers
+func QueryString(name, description string) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: &Schema{Type: "string"}}
+}
+
+func QueryInt(name, description string) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: &Schema{Type: "integer"}}
+}
+
+func QueryBool(name, description string) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: &Schema{Type: "boolean"}}
+}
+
+func QueryEnum(name, description string, values ...string) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: &Schema{Type: "string", Enum: values}}
+}
+
+// Build returns the document describing routes. errorBody is a value of
+// the type every error response has.
+func Build(info Info, tags []Tag, errorBody interface{}, routes []Route) *Document {
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Tags:    tags,
+		Paths:   make(map[string]*PathItem),
+		Components: Components{
+			Schemas: make(map[string]*Schema),
+			SecuritySchemes: map[string]*SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+	g := &generator{schemas: doc.Components.Schemas, names: make(map[string]string)}
+	errorSchema := g.schemaOf(errorBody)
+
+	for _, route := range routes {
+		path, params := pathParameters(route.Path)
+		item := doc.Paths[path]
+		if item == nil {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+
+		op := &Operation{
+			Summary:     route.Summary,
+			OperationID: operationID(route.Method, route.Path),
+			Parameters:  append(params, route.Query...),
+			Responses:   make(map[string]*Response),
+			Deprecated:  route.Deprecated,
+		}
+		if route.Tag != "" {
+			op.Tags = []string{route.Tag}
+		}
+		if route.Access != Public {
+			op.Security = []map[string][]string{{"bearerAuth": {}}}
+		}
+
+		status := route.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := &Response{Description: http.StatusText(status)}
+		switch {
+		case route.ContentType != "":
+			success.Content = map[string]MediaType{route.ContentType: {Schema: &Schema{Type: "string", Format: "binary"}}}
+		case route.Response != nil:
+			success.Content = map[string]MediaType{"application/json": {Schema: g.schemaOf(route.Response)}}
+		}
+		if route.Sort != nil {
+			op.Parameters = append(op.Parameters,
+				QueryInt("limit", "Page size, at most 500. Without limit and cursor users and units are listed whole, other lists return 100 rows"),
+				QueryEnum("sort", "Sort field, prefix with - for descending", sortValues(route.Sort)...),
+				QueryString("cursor", "X-Next-Cursor of the previous page"),
+			)
+			success.Headers = map[string]*Header{
+				"X-Total-Count": {Description: "Rows matching the filters", Schema: &Schema{Type: "integer"}},
+				"X-Next-Cursor": {Description: "Cursor of the next page, absent on the last", Schema: &Schema{Type: "string"}},
+				"Link":          {Description: `URL of the next page with rel="next"`, Schema: &Schema{Type: "string"}},
+			}
+		}
+		op.Responses[strconv.Itoa(status)] = success
+
+		errors := []int{http.StatusTooManyRequests, http.StatusInternalServerError}
+		if route.Request != nil || len(route.Query) > 0 || route.Sort != nil {
+			errors = append(errors, http.StatusBadRequest)
+		}
+		if route.Access != Public {
+			errors = append(errors, http.StatusUnauthorized)
+		}
+		if route.Access == Admin {
+			errors = append(errors, http.StatusForbidden)
+		}
+		if len(params) > 0 {
+			errors = append(errors, http.StatusNotFound)
+		}
+		for _, code := range errors {
+			op.Responses[strconv.Itoa(code)] = &Response{
+				Description: http.StatusText(code),
+				Content:     map[string]MediaType{"application/json": {Schema: errorSchema}},
+			}
+		}
+
+		if route.Request != nil {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]MediaType{"application/json": {Schema: g.schemaOf(route.Request)}},
+			}
+		}
+
+		(*item)[strings.ToLower(route.Method)] = op
+	}
+	return doc
+}
+
+// Has reports whether the document describes method on a gin path
+func (d *Document) Has(method, ginPath string) bool {
+	path, _ := pathParameters(ginPath)
+	item := d.Paths[path]
+	if item == nil {
+		return false
+	}
+	_, ok := (*item)[strings.ToLower(method)]
+	return ok
+}
+
+// pathParameters turns /units/:id into /units/{id} and returns its
+// parameters; "id" parameters are integers
+func pathParameters(ginPath string) (string, []Parameter) {
+	segments := strings.Split(ginPath, "/")
+	var params []Parameter
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			schema := &Schema{Type: "string"}
+			if name == "id" {
+				schema = &Schema{Type: "integer"}
+			}
+			params = append(params, Parameter{Name: name, In: "path", Required: true, Schema: schema})
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// operationID names an operation after its method and path, e.g.
+// GET /api/units/:id gives getApiUnitsId
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	upper := true
+	for _, r := range path {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func sortValues(fields []string) []string {
+	values := make([]string, 0, 2*len(fields))
+	for _, f := range fields {
+		values = append(values, f, "-"+f)
+	}
+	sort.Strings(values)
+	return values
+}
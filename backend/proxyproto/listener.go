@@ -0,0 +1,182 @@
+// Package proxyproto reads the PROXY protocol header (version 1 or 2) that a
+// load balancer sends ahead of the proxied connection, so the server sees
+// the client's address instead of the proxy's.
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// v2Signature starts every version 2 header
+var v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// maxV1Length is the longest version 1 header, CRLF included
+const maxV1Length = 107
+
+// Listener accepts connections from inner. Connections from trusted
+// networks must start with a PROXY header; the others are served as they
+// are, so a client cannot claim another address by sending one itself.
+type Listener struct {
+	net.Listener
+	trusted []*net.IPNet
+	timeout time.Duration
+}
+
+// NewListener wraps inner. timeout bounds the wait for the header.
+func NewListener(inner net.Listener, trusted []*net.IPNet, timeout time.Duration) *Listener {
+	return &Listener{Listener: inner, trusted: trusted, timeout: timeout}
+}
+
+func (l *Listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if !l.isTrusted(conn.RemoteAddr()) {
+		return conn, nil
+	}
+	return &Conn{Conn: conn, reader: bufio.NewReader(conn), timeout: l.timeout}, nil
+}
+
+func (l *Listener) isTrusted(addr net.Addr) bool {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, network := range l.trusted {
+		if network.Contains(tcp.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// Conn is a connection from a trusted proxy. The header is read on the
+// first Read or RemoteAddr call, in the goroutine serving the connection
+// rather than in Accept.
+type Conn struct {
+	net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+
+	once   sync.Once
+	remote net.Addr
+	err    error
+}
+
+func (c *Conn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.reader.Read(b)
+}
+
+// RemoteAddr returns the client address from the header. It is the proxy's
+// address for LOCAL (health check) and UNKNOWN headers, and when the header
+// is invalid; Read then fails and the connection is dropped.
+func (c *Conn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *Conn) readHeader() {
+	if c.timeout > 0 {
+		c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+		defer c.Conn.SetReadDeadline(time.Time{})
+	}
+	c.remote, c.err = ReadHeader(c.reader)
+	if c.err != nil {
+		c.err = fmt.Errorf("proxyproto: %w", c.err)
+	}
+}
+
+// ReadHeader reads a version 1 or 2 header from r and returns the source
+// address, or nil when the header carries none.
+func ReadHeader(r *bufio.Reader) (net.Addr, error) {
+	start, err := r.Peek(len(v2Signature))
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case bytes.Equal(start, v2Signature):
+		return readV2(r)
+	case bytes.HasPrefix(start, []byte("PROXY ")):
+		return readV1(r)
+	}
+	return nil, errors.New("missing PROXY protocol header")
+}
+
+// readV1 reads "PROXY TCP4|TCP6 <src> <dst> <src port> <dst port>\r\n" or
+// "PROXY UNKNOWN ...\r\n"
+func readV1(r *bufio.Reader) (net.Addr, error) {
+	line, err := r.ReadSlice('\n')
+	if err != nil || len(line) > maxV1Length || !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, errors.New("invalid version 1 header")
+	}
+	fields := strings.Fields(string(line))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, errors.New("invalid version 1 header")
+	}
+	ip := net.ParseIP(fields[2])
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if ip == nil || err != nil || (fields[1] == "TCP4") != (ip.To4() != nil) {
+		return nil, errors.New("invalid version 1 address")
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// readV2 reads the binary header: signature, version and command, address
+// family, length, then the addresses and optional TLVs (ignored)
+func readV2(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if header[12]>>4 != 2 {
+		return nil, fmt.Errorf("unsupported version %d", header[12]>>4)
+	}
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+
+	switch header[12] & 0x0f {
+	case 0x0: // LOCAL: the proxy's own connection, e.g. a health check
+		return nil, nil
+	case 0x1: // PROXY
+	default:
+		return nil, fmt.Errorf("unsupported command %d", header[12]&0x0f)
+	}
+
+	switch header[13] >> 4 {
+	case 0x1: // AF_INET: src, dst, src port, dst port
+		if len(payload) < 12 {
+			return nil, errors.New("short IPv4 address block")
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:4]), Port: int(binary.BigEndian.Uint16(payload[8:10]))}, nil
+	case 0x2: // AF_INET6
+		if len(payload) < 36 {
+			return nil, errors.New("short IPv6 address block")
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:16]), Port: int(binary.BigEndian.Uint16(payload[32:34]))}, nil
+	}
+	// AF_UNSPEC or AF_UNIX: no usable address
+	return nil, nil
+}
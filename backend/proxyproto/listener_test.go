@@ -0,0 +1,106 @@
+package proxyproto
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func v2Header(command, family byte, addresses []byte) []byte {
+	header := append([]byte{}, v2Signature...)
+	header = append(header, 0x20|command, family, 0, 0)
+	binary.BigEndian.PutUint16(header[14:16], uint16(len(addresses)))
+	return append(header, addresses...)
+}
+
+func TestReadHeader(t *testing.T) {
+	ipv4 := []byte{198, 51, 100, 7, 10, 0, 0, 2, 0x1f, 0x90, 0x01, 0xbb}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"v1 tcp4", "PROXY TCP4 198.51.100.7 10.0.0.2 8080 443\r\nGET /", "198.51.100.7:8080", false},
+		{"v1 tcp6", "PROXY TCP6 2001:db8::1 2001:db8::2 8080 443\r\nGET /", "[2001:db8::1]:8080", false},
+		{"v1 unknown", "PROXY UNKNOWN\r\nGET /", "", false},
+		{"v1 family mismatch", "PROXY TCP4 2001:db8::1 10.0.0.2 8080 443\r\n", "", true},
+		{"v1 without CRLF", "PROXY TCP4 198.51.100.7 10.0.0.2 8080 443\nGET /", "", true},
+		{"v2 proxy", string(v2Header(0x1, 0x11, ipv4)) + "GET /", "198.51.100.7:8080", false},
+		{"v2 local", string(v2Header(0x0, 0x00, nil)) + "GET /", "", false},
+		{"v2 short", string(v2Header(0x1, 0x11, ipv4[:6])) + "GET /", "", true},
+		{"missing", "GET / HTTP/1.1\r\n\r\n", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tt.input))
+			addr, err := ReadHeader(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := ""
+			if addr != nil {
+				got = addr.String()
+			}
+			if got != tt.want {
+				t.Fatalf("address %q, want %q", got, tt.want)
+			}
+			// The request behind the header is left for the server
+			if rest, _ := io.ReadAll(r); string(rest) != "GET /" {
+				t.Fatalf("remaining %q", rest)
+			}
+		})
+	}
+}
+
+func TestListener(t *testing.T) {
+	serve := func(trusted string, send string) (net.Addr, string) {
+		inner, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer inner.Close()
+		_, network, _ := net.ParseCIDR(trusted)
+		ln := NewListener(inner, []*net.IPNet{network}, time.Second)
+
+		go func() {
+			conn, err := net.Dial("tcp", inner.Addr().String())
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			conn.Write([]byte(send))
+		}()
+
+		conn, err := ln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		remote := conn.RemoteAddr()
+		data, _ := io.ReadAll(conn)
+		return remote, string(data)
+	}
+
+	header := "PROXY TCP4 198.51.100.7 127.0.0.1 5000 80\r\n"
+
+	// From a trusted proxy the header sets the client address
+	remote, data := serve("127.0.0.0/8", header+"hello")
+	if remote.String() != "198.51.100.7:5000" || data != "hello" {
+		t.Fatalf("trusted: %s %q", remote, data)
+	}
+
+	// From anyone else it is just data
+	remote, data = serve("10.0.0.0/8", header+"hello")
+	if !strings.HasPrefix(remote.String(), "127.0.0.1:") || data != header+"hello" {
+		t.Fatalf("untrusted: %s %q", remote, data)
+	}
+}
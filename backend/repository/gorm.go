@@ -0,0 +1,332 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"scandata/models"
+	"scandata/services"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore returns a Store backed by db
+func NewGormStore(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+func (s *gormStore) Users() UserRepository           { return gormUsers{s.db} }
+func (s *gormStore) Units() UnitRepository           { return gormUnits{s.db} }
+func (s *gormStore) Shifts() ShiftRepository         { return gormShifts{s.db} }
+func (s *gormStore) Scans() ScanRepository           { return gormScans{s.db} }
+func (s *gormStore) SavedViews() SavedViewRepository { return gormSavedViews{s.db} }
+func (s *gormStore) Audit() AuditRepository          { return gormAudit{s.db} }
+func (s *gormStore) Settings() SettingRepository     { return gormSettings{s.db} }
+
+func (s *gormStore) Transaction(ctx context.Context, fn func(tx Store) error) error {
+	return translate(s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&gormStore{db: tx})
+	}))
+}
+
+// translate maps GORM and driver errors to the repository errors. The
+// dialects report unique and foreign key violations as gorm errors because
+// the connection is opened with TranslateError; anything else that is not
+// a lost connection is returned as is.
+func translate(err error) error {
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return fmt.Errorf("%w: %v", ErrDuplicate, err)
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return fmt.Errorf("%w: %v", ErrReferenced, err)
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone), errors.As(err, &netErr):
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return err
+}
+
+// findPage counts every row matching query and loads one page of it.
+// Associations are preloaded here rather than on query so the count stays a
+// plain COUNT(*).
+func findPage[T any](query *gorm.DB, fields map[string]SortField, page Page, preloads ...string) ([]T, int64, error) {
+	field, ok := fields[page.Sort]
+	if !ok {
+		return nil, 0, fmt.Errorf("unknown sort field %q", page.Sort)
+	}
+	base := query.Session(&gorm.Session{})
+
+	var total int64
+	if err := base.Model(new(T)).Count(&total).Error; err != nil {
+		return nil, 0, translate(err)
+	}
+
+	op, dir := ">", "ASC"
+	if page.Desc {
+		op, dir = "<", "DESC"
+	}
+
+	find := base
+	if page.After != nil {
+		var value interface{} = page.After.Value
+		if field.IsTime {
+			if t, err := time.Parse(time.RFC3339Nano, page.After.Value); err == nil {
+				value = t
+			}
+		}
+		find = find.Where(
+			fmt.Sprintf("(%s %s ? OR (%s = ? AND id %s ?))", field.Column, op, field.Column, op),
+			value, value, page.After.ID,
+		)
+	}
+	find = find.Order(fmt.Sprintf("%s %s, id %s", field.Column, dir, dir))
+	if page.Limit > 0 {
+		find = find.Limit(page.Limit)
+	}
+	for _, preload := range preloads {
+		find = find.Preload(preload)
+	}
+
+	items := []T{}
+	if err := find.Find(&items).Error; err != nil {
+		return nil, 0, translate(err)
+	}
+	return items, total, nil
+}
+
+// likeOperator returns a case-insensitive LIKE. MySQL and SQLite compare
+// case-insensitively already; Postgres needs ILIKE.
+func likeOperator(db *gorm.DB) string {
+	if db.Dialector.Name() == "postgres" {
+		return "ILIKE"
+	}
+	return "LIKE"
+}
+
+type gormUsers struct{ db *gorm.DB }
+
+func (r gormUsers) List(ctx context.Context, page Page) ([]models.User, int64, error) {
+	return findPage[models.User](r.db.WithContext(ctx), UserSortFields, page)
+}
+
+func (r gormUsers) ListByRole(ctx context.Context, role models.Role) ([]models.User, error) {
+	users := []models.User{}
+	err := r.db.WithContext(ctx).Where("role = ?", role).Find(&users).Error
+	return users, translate(err)
+}
+
+func (r gormUsers) Get(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).First(&user, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (r gormUsers) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (r gormUsers) Lock(ctx context.Context, id uint) error {
+	// SQLite has no row locks; its single writer serialises transactions already
+	var user models.User
+	return translate(r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, id).Error)
+}
+
+func (r gormUsers) Create(ctx context.Context, user *models.User) error {
+	return translate(r.db.WithContext(ctx).Create(user).Error)
+}
+
+func (r gormUsers) Update(ctx context.Context, user *models.User) error {
+	return translate(r.db.WithContext(ctx).Save(user).Error)
+}
+
+func (r gormUsers) Delete(ctx context.Context, user *models.User) error {
+	return translate(r.db.WithContext(ctx).Delete(user).Error)
+}
+
+type gormUnits struct{ db *gorm.DB }
+
+func (r gormUnits) List(ctx context.Context, filter UnitFilter, page Page) ([]models.Unit, int64, error) {
+	query := r.db.WithContext(ctx)
+	if filter.Active != nil {
+		query = query.Where("is_active = ?", *filter.Active)
+	}
+	if filter.Search != "" {
+		like := likeOperator(query)
+		query = query.Where("name "+like+" ? OR qr_code "+like+" ?", "%"+filter.Search+"%", "%"+filter.Search+"%")
+	}
+	return findPage[models.Unit](query, UnitSortFields, page)
+}
+
+func (r gormUnits) Get(ctx context.Context, id uint) (*models.Unit, error) {
+	var unit models.Unit
+	if err := r.db.WithContext(ctx).First(&unit, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &unit, nil
+}
+
+func (r gormUnits) GetActiveByQRCode(ctx context.Context, qrCode string) (*models.Unit, error) {
+	var unit models.Unit
+	if err := r.db.WithContext(ctx).Where("qr_code = ? AND is_active = ?", qrCode, true).First(&unit).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &unit, nil
+}
+
+func (r gormUnits) Create(ctx context.Context, unit *models.Unit) error {
+	return translate(r.db.WithContext(ctx).Create(unit).Error)
+}
+
+func (r gormUnits) Update(ctx context.Context, unit *models.Unit) error {
+	return translate(r.db.WithContext(ctx).Save(unit).Error)
+}
+
+func (r gormUnits) Delete(ctx context.Context, unit *models.Unit) error {
+	return translate(r.db.WithContext(ctx).Delete(unit).Error)
+}
+
+type gormShifts struct{ db *gorm.DB }
+
+func (r gormShifts) List(ctx context.Context) ([]models.Shift, error) {
+	shifts := []models.Shift{}
+	err := r.db.WithContext(ctx).Order("start_time ASC").Find(&shifts).Error
+	return shifts, translate(err)
+}
+
+func (r gormShifts) ListActive(ctx context.Context) ([]models.Shift, error) {
+	var shifts []models.Shift
+	err := r.db.WithContext(ctx).Where("is_active = ?", true).Find(&shifts).Error
+	return shifts, translate(err)
+}
+
+func (r gormShifts) ListWithDeleted(ctx context.Context) ([]models.Shift, error) {
+	var shifts []models.Shift
+	err := r.db.WithContext(ctx).Unscoped().Find(&shifts).Error
+	return shifts, translate(err)
+}
+
+func (r gormShifts) Get(ctx context.Context, id uint) (*models.Shift, error) {
+	var shift models.Shift
+	if err := r.db.WithContext(ctx).First(&shift, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &shift, nil
+}
+
+func (r gormShifts) Create(ctx context.Context, shift *models.Shift) error {
+	return translate(r.db.WithContext(ctx).Create(shift).Error)
+}
+
+func (r gormShifts) Update(ctx context.Context, shift *models.Shift) error {
+	return translate(r.db.WithContext(ctx).Save(shift).Error)
+}
+
+func (r gormShifts) Delete(ctx context.Context, shift *models.Shift) error {
+	return translate(r.db.WithContext(ctx).Delete(shift).Error)
+}
+
+type gormSavedViews struct{ db *gorm.DB }
+
+func (r gormSavedViews) List(ctx context.Context, userID uint) ([]models.SavedView, error) {
+	var views []models.SavedView
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("name ASC").Find(&views).Error
+	return views, translate(err)
+}
+
+func (r gormSavedViews) Get(ctx context.Context, userID, id uint) (*models.SavedView, error) {
+	var view models.SavedView
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&view).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &view, nil
+}
+
+func (r gormSavedViews) Create(ctx context.Context, view *models.SavedView) error {
+	return translate(r.db.WithContext(ctx).Create(view).Error)
+}
+
+func (r gormSavedViews) Delete(ctx context.Context, view *models.SavedView) error {
+	return translate(r.db.WithContext(ctx).Delete(view).Error)
+}
+
+type gormSettings struct{ db *gorm.DB }
+
+func (r gormSettings) List(ctx context.Context) ([]models.Setting, error) {
+	settings := []models.Setting{}
+	// key is a reserved word in MySQL, so let the dialect quote it
+	err := r.db.WithContext(ctx).Order(clause.OrderByColumn{Column: clause.Column{Name: "key"}}).Find(&settings).Error
+	return settings, translate(err)
+}
+
+func (r gormSettings) Save(ctx context.Context, setting *models.Setting) error {
+	return translate(r.db.WithContext(ctx).Save(setting).Error)
+}
+
+func (r gormSettings) Delete(ctx context.Context, key string) error {
+	return translate(r.db.WithContext(ctx).Delete(&models.Setting{Key: key}).Error)
+}
+
+type gormAudit struct{ db *gorm.DB }
+
+func (r gormAudit) Record(ctx context.Context, entry services.AuditEntry) error {
+	return translate(services.RecordAudit(r.db.WithContext(ctx), entry))
+}
+
+func (r gormAudit) List(ctx context.Context, filter AuditFilter, page Page) ([]models.AuditLog, int64, error) {
+	return findPage[models.AuditLog](r.query(ctx, filter), AuditSortFields, page)
+}
+
+func (r gormAudit) Find(ctx context.Context, filter AuditFilter) ([]models.AuditLog, error) {
+	var entries []models.AuditLog
+	err := r.query(ctx, filter).Order("id ASC").Find(&entries).Error
+	return entries, translate(err)
+}
+
+func (r gormAudit) Verify(ctx context.Context) (services.AuditVerification, error) {
+	return services.VerifyAuditChain(r.db.WithContext(ctx))
+}
+
+func (r gormAudit) query(ctx context.Context, f AuditFilter) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&models.AuditLog{})
+	if f.ActorID != "" {
+		query = query.Where("actor_id = ?", f.ActorID)
+	}
+	if f.Action != "" {
+		query = query.Where("action = ?", f.Action)
+	}
+	if f.EntityType != "" {
+		query = query.Where("entity_type = ?", f.EntityType)
+	}
+	if f.EntityID != "" {
+		query = query.Where("entity_id = ?", f.EntityID)
+	}
+	if f.RequestID != "" {
+		query = query.Where("request_id = ?", f.RequestID)
+	}
+	if f.From != nil {
+		query = query.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		query = query.Where("created_at < ?", *f.To)
+	}
+	return query
+}
@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"context"
+	"scandata/models"
+	"scandata/services"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type gormScans struct{ db *gorm.DB }
+
+func (r gormScans) Create(ctx context.Context, scan *models.ScanLog) error {
+	return translate(r.db.WithContext(ctx).Create(scan).Error)
+}
+
+func (r gormScans) Get(ctx context.Context, id uint) (*models.ScanLog, error) {
+	var scan models.ScanLog
+	if err := r.db.WithContext(ctx).Preload("User").Preload("Unit").Preload("Shift").First(&scan, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &scan, nil
+}
+
+func (r gormScans) LatestSince(ctx context.Context, userID uint, barcode string, since time.Time) (*models.ScanLog, error) {
+	var scan models.ScanLog
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND barcode = ? AND scanned_at >= ? AND is_voided = ?", userID, barcode, since, false).
+		Order("scanned_at DESC").First(&scan).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &scan, nil
+}
+
+func (r gormScans) IncrementDuplicates(ctx context.Context, id uint) error {
+	return translate(r.db.WithContext(ctx).Model(&models.ScanLog{}).Where("id = ?", id).
+		UpdateColumn("duplicate_count", gorm.Expr("duplicate_count + ?", 1)).Error)
+}
+
+func (r gormScans) SaveEdit(ctx context.Context, scan *models.ScanLog, fromRevision int) error {
+	result := r.db.WithContext(ctx).Model(&models.ScanLog{}).
+		Where("id = ? AND revision = ? AND is_voided = ?", scan.ID, fromRevision, false).
+		Updates(map[string]interface{}{
+			"is_match":    scan.IsMatch,
+			"notes":       scan.Notes,
+			"is_voided":   scan.IsVoided,
+			"voided_at":   scan.VoidedAt,
+			"voided_by":   scan.VoidedBy,
+			"void_reason": scan.VoidReason,
+			"revision":    scan.Revision,
+		})
+	if result.Error != nil {
+		return translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (r gormScans) Retag(ctx context.Context, start, end time.Time, assign func(*models.ScanLog)) (int64, error) {
+	db := r.db.WithContext(ctx)
+	var updated int64
+
+	var batch []models.ScanLog
+	err := db.Select("id, scanned_at, shift_id, shift_date").
+		Where("scanned_at >= ? AND scanned_at < ?", start, end).
+		FindInBatches(&batch, 500, func(_ *gorm.DB, _ int) error {
+			var changed []models.ScanLog
+			for i := range batch {
+				before := batch[i]
+				assign(&batch[i])
+				if shiftChanged(before, batch[i]) {
+					changed = append(changed, batch[i])
+				}
+			}
+			if len(changed) == 0 {
+				return nil
+			}
+
+			err := db.Transaction(func(tx *gorm.DB) error {
+				for i := range changed {
+					if err := tx.Model(&models.ScanLog{}).Where("id = ?", changed[i].ID).
+						Updates(map[string]interface{}{"shift_id": changed[i].ShiftID, "shift_date": changed[i].ShiftDate}).Error; err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err == nil {
+				updated += int64(len(changed))
+			}
+			return err
+		}).Error
+	return updated, translate(err)
+}
+
+// shiftChanged reports whether assign moved a scan to another shift or
+// shift date. Dates compare by calendar day, as the driver may load them in
+// a different location than assign sets them.
+func shiftChanged(before, after models.ScanLog) bool {
+	if (before.ShiftID == nil) != (after.ShiftID == nil) || (before.ShiftID != nil && *before.ShiftID != *after.ShiftID) {
+		return true
+	}
+	if (before.ShiftDate == nil) != (after.ShiftDate == nil) {
+		return true
+	}
+	return before.ShiftDate != nil && before.ShiftDate.Format("2006-01-02") != after.ShiftDate.Format("2006-01-02")
+}
+
+func (r gormScans) List(ctx context.Context, filter ScanFilter, page Page) ([]models.ScanLog, int64, error) {
+	query := applyScanFilter(r.db.WithContext(ctx).Model(&models.ScanLog{}), filter)
+	return findPage[models.ScanLog](query, ScanSortFields, page, "User", "Unit")
+}
+
+func (r gormScans) Find(ctx context.Context, filter ScanFilter) ([]models.ScanLog, error) {
+	var scans []models.ScanLog
+	err := applyScanFilter(r.db.WithContext(ctx).Model(&models.ScanLog{}).Preload("Unit").Preload("User"), filter).
+		Order("scanned_at DESC").Find(&scans).Error
+	return scans, translate(err)
+}
+
+func (r gormScans) Count(ctx context.Context, filter ScanFilter) (ScanCounts, error) {
+	var row struct {
+		Total         int64
+		Matched       int64
+		DistinctUnits int64
+		Merged        int64
+		Flagged       int64
+	}
+	err := applyScanFilter(r.db.WithContext(ctx).Model(&models.ScanLog{}), filter).
+		Select("COUNT(*) AS total, " +
+			"COALESCE(SUM(CASE WHEN is_match THEN 1 ELSE 0 END), 0) AS matched, " +
+			"COUNT(DISTINCT barcode) AS distinct_units, " +
+			"COALESCE(SUM(duplicate_count), 0) AS merged, " +
+			"COALESCE(SUM(CASE WHEN duplicate_of_id IS NOT NULL THEN 1 ELSE 0 END), 0) AS flagged").
+		Scan(&row).Error
+	if err != nil {
+		return ScanCounts{}, translate(err)
+	}
+
+	return ScanCounts{
+		Total:         row.Total,
+		Match:         row.Matched,
+		NotMatch:      row.Total - row.Matched,
+		DistinctUnits: row.DistinctUnits,
+		RawEvents:     row.Total + row.Merged,
+		Duplicates:    row.Flagged + row.Merged,
+	}, nil
+}
+
+func (r gormScans) EachRow(ctx context.Context, filter ScanFilter, fn func(ScanRow) error) error {
+	db := r.db.WithContext(ctx)
+	rows, err := applyScanFilter(db.Model(&models.ScanLog{}), filter).
+		Select("scan_logs.scanned_at, scan_logs.barcode, scan_logs.is_match, scan_logs.user_id, scan_logs.unit_id, " +
+			"users.name AS user_name, units.name AS unit_name, units.location AS unit_location, units.expected_grade AS unit_grade").
+		Joins("LEFT JOIN users ON users.id = scan_logs.user_id").
+		Joins("LEFT JOIN units ON units.id = scan_logs.unit_id").
+		Rows()
+	if err != nil {
+		return translate(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row ScanRow
+		if err := db.ScanRows(rows, &row); err != nil {
+			return translate(err)
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return translate(rows.Err())
+}
+
+func (r gormScans) ShiftEvents(ctx context.Context, start, end time.Time) ([]services.ShiftEvent, error) {
+	var events []services.ShiftEvent
+	err := r.db.WithContext(ctx).Model(&models.ScanLog{}).
+		Select("shift_id, shift_date, user_id, scanned_at, is_match").
+		Where("is_voided = ? AND shift_id IS NOT NULL AND shift_date >= ? AND shift_date < ?", false, start, end).
+		Scan(&events).Error
+	return events, translate(err)
+}
+
+func (r gormScans) Revisions(ctx context.Context, scanID uint) ([]models.ScanRevision, error) {
+	revisions := []models.ScanRevision{}
+	err := r.db.WithContext(ctx).Preload("User").Where("scan_log_id = ?", scanID).Order("revision ASC").Find(&revisions).Error
+	return revisions, translate(err)
+}
+
+func (r gormScans) CreateRevision(ctx context.Context, revision *models.ScanRevision) error {
+	return translate(r.db.WithContext(ctx).Create(revision).Error)
+}
+
+// applyScanFilter adds the filter conditions to a scan_logs query. Voided
+// scans are excluded unless asked for. Unit location and grade use a
+// subquery so the query stays free of joins.
+func applyScanFilter(query *gorm.DB, f ScanFilter) *gorm.DB {
+	if !f.IncludeVoided {
+		query = query.Where("scan_logs.is_voided = ?", false)
+	}
+	if f.OwnerID != nil {
+		query = query.Where("scan_logs.user_id = ?", *f.OwnerID)
+	}
+	if f.From != nil {
+		query = query.Where("scan_logs.scanned_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		query = query.Where("scan_logs.scanned_at < ?", *f.To)
+	}
+	if len(f.UserIDs) > 0 {
+		query = query.Where("scan_logs.user_id IN ?", f.UserIDs)
+	}
+	if len(f.UnitIDs) > 0 {
+		query = query.Where("scan_logs.unit_id IN ?", f.UnitIDs)
+	}
+	if len(f.Locations) > 0 {
+		query = query.Where("scan_logs.unit_id IN (?)", query.Session(&gorm.Session{NewDB: true}).
+			Table("units").Select("id").Where("location IN ?", f.Locations))
+	}
+	if len(f.Grades) > 0 {
+		query = query.Where("scan_logs.unit_id IN (?)", query.Session(&gorm.Session{NewDB: true}).
+			Table("units").Select("id").Where("expected_grade IN ?", f.Grades))
+	}
+	if f.Barcode != "" {
+		query = query.Where("scan_logs.barcode "+likeOperator(query)+" ?", "%"+f.Barcode+"%")
+	}
+	if f.GTIN != "" {
+		query = query.Where("scan_logs.gtin = ?", f.GTIN)
+	}
+	if f.Lot != "" {
+		query = query.Where("scan_logs.lot = ?", f.Lot)
+	}
+	if f.IsMatch != nil {
+		query = query.Where("scan_logs.is_match = ?", *f.IsMatch)
+	}
+	if f.Notes != "" {
+		switch query.Dialector.Name() {
+		case "mysql":
+			query = query.Where("MATCH(scan_logs.notes) AGAINST(? IN BOOLEAN MODE)", f.Notes)
+		case "postgres":
+			// Same expression as the idx_scan_logs_notes GIN index
+			query = query.Where("to_tsvector('simple', coalesce(scan_logs.notes, '')) @@ plainto_tsquery('simple', ?)", f.Notes)
+		default:
+			query = query.Where("scan_logs.notes LIKE ?", "%"+f.Notes+"%")
+		}
+	}
+	return query
+}
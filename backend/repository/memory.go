@@ -0,0 +1,682 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"scandata/models"
+	"scandata/services"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// memoryData holds every table. Rows are stored by value and replaced as a
+// whole on write, so a shallow copy is enough to snapshot it.
+type memoryData struct {
+	users     map[uint]models.User
+	units     map[uint]models.Unit
+	shifts    map[uint]models.Shift
+	scans     map[uint]models.ScanLog
+	revisions []models.ScanRevision
+	views     map[uint]models.SavedView
+	audit     []models.AuditLog
+	settings  map[string]models.Setting
+	lastID    map[string]uint
+}
+
+func (d *memoryData) clone() *memoryData {
+	c := &memoryData{
+		users:     make(map[uint]models.User, len(d.users)),
+		units:     make(map[uint]models.Unit, len(d.units)),
+		shifts:    make(map[uint]models.Shift, len(d.shifts)),
+		scans:     make(map[uint]models.ScanLog, len(d.scans)),
+		revisions: append([]models.ScanRevision(nil), d.revisions...),
+		views:     make(map[uint]models.SavedView, len(d.views)),
+		audit:     append([]models.AuditLog(nil), d.audit...),
+		settings:  make(map[string]models.Setting, len(d.settings)),
+		lastID:    make(map[string]uint, len(d.lastID)),
+	}
+	for k, v := range d.users {
+		c.users[k] = v
+	}
+	for k, v := range d.units {
+		c.units[k] = v
+	}
+	for k, v := range d.shifts {
+		c.shifts[k] = v
+	}
+	for k, v := range d.scans {
+		c.scans[k] = v
+	}
+	for k, v := range d.views {
+		c.views[k] = v
+	}
+	for k, v := range d.settings {
+		c.settings[k] = v
+	}
+	for k, v := range d.lastID {
+		c.lastID[k] = v
+	}
+	return c
+}
+
+func (d *memoryData) nextID(table string) uint {
+	d.lastID[table]++
+	return d.lastID[table]
+}
+
+type memoryStore struct {
+	mu   *sync.Mutex
+	data *memoryData
+	inTx bool
+}
+
+// NewMemoryStore returns a Store that keeps everything in memory. It follows
+// the GORM store closely enough to run the handler tests without a database:
+// soft deletes, unique fields and transaction rollback behave the same.
+func NewMemoryStore() Store {
+	return &memoryStore{
+		mu: &sync.Mutex{},
+		data: &memoryData{
+			users:    make(map[uint]models.User),
+			units:    make(map[uint]models.Unit),
+			shifts:   make(map[uint]models.Shift),
+			scans:    make(map[uint]models.ScanLog),
+			views:    make(map[uint]models.SavedView),
+			settings: make(map[string]models.Setting),
+			lastID:   make(map[string]uint),
+		},
+	}
+}
+
+func (s *memoryStore) Users() UserRepository           { return memoryUsers{s} }
+func (s *memoryStore) Units() UnitRepository           { return memoryUnits{s} }
+func (s *memoryStore) Shifts() ShiftRepository         { return memoryShifts{s} }
+func (s *memoryStore) Scans() ScanRepository           { return memoryScans{s} }
+func (s *memoryStore) SavedViews() SavedViewRepository { return memorySavedViews{s} }
+func (s *memoryStore) Audit() AuditRepository          { return memoryAudit{s} }
+func (s *memoryStore) Settings() SettingRepository     { return memorySettings{s} }
+
+// Transaction holds the lock for the whole of fn and restores a snapshot
+// when it fails
+func (s *memoryStore) Transaction(ctx context.Context, fn func(tx Store) error) error {
+	if s.inTx {
+		return fn(s)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := s.data.clone()
+	if err := fn(&memoryStore{mu: s.mu, data: s.data, inTx: true}); err != nil {
+		*s.data = *snapshot
+		return err
+	}
+	return nil
+}
+
+// do runs fn under the lock, unless a transaction already holds it
+func (s *memoryStore) do(fn func(d *memoryData) error) error {
+	if !s.inTx {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+	}
+	return fn(s.data)
+}
+
+func deletedNow() gorm.DeletedAt {
+	return gorm.DeletedAt{Time: time.Now(), Valid: true}
+}
+
+// memoryPage sorts items by the page's field then id, skips rows up to the
+// cursor and cuts the page. key returns the sort value and id of an item.
+func memoryPage[T any](items []T, page Page, fields map[string]SortField, key func(T) (interface{}, uint)) ([]T, int64, error) {
+	if _, ok := fields[page.Sort]; !ok {
+		return nil, 0, fmt.Errorf("unknown sort field %q", page.Sort)
+	}
+	total := int64(len(items))
+
+	less := func(a, b T) bool {
+		va, ida := key(a)
+		vb, idb := key(b)
+		if c := compareValues(va, vb); c != 0 {
+			return (c < 0) != page.Desc
+		}
+		return (ida < idb) != page.Desc
+	}
+	sort.SliceStable(items, func(i, j int) bool { return less(items[i], items[j]) })
+
+	result := []T{}
+	for _, item := range items {
+		if page.After != nil {
+			value, id := key(item)
+			c := compareValues(value, parseCursorValue(page.After.Value, value))
+			if c == 0 {
+				c = compareValues(id, page.After.ID)
+			}
+			if (c <= 0 && !page.Desc) || (c >= 0 && page.Desc) {
+				continue
+			}
+		}
+		if page.Limit > 0 && len(result) >= page.Limit {
+			break
+		}
+		result = append(result, item)
+	}
+	return result, total, nil
+}
+
+// parseCursorValue converts a cursor value to the type of like
+func parseCursorValue(value string, like interface{}) interface{} {
+	switch like.(type) {
+	case uint:
+		n, _ := strconv.ParseUint(value, 10, 64)
+		return uint(n)
+	case time.Time:
+		t, _ := time.Parse(time.RFC3339Nano, value)
+		return t
+	}
+	return value
+}
+
+func compareValues(a, b interface{}) int {
+	switch a := a.(type) {
+	case uint:
+		b := b.(uint)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case string:
+		return strings.Compare(a, b.(string))
+	}
+	return 0
+}
+
+// containsFold is a case-insensitive LIKE '%sub%'
+func containsFold(s, sub string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(sub))
+}
+
+type memoryUsers struct{ s *memoryStore }
+
+func (r memoryUsers) List(ctx context.Context, page Page) (users []models.User, total int64, err error) {
+	err = r.s.do(func(d *memoryData) error {
+		all := []models.User{}
+		for _, u := range d.users {
+			if !u.DeletedAt.Valid {
+				all = append(all, u)
+			}
+		}
+		users, total, err = memoryPage(all, page, UserSortFields, func(u models.User) (interface{}, uint) {
+			switch page.Sort {
+			case "username":
+				return u.Username, u.ID
+			case "name":
+				return u.Name, u.ID
+			case "created_at":
+				return u.CreatedAt, u.ID
+			}
+			return u.ID, u.ID
+		})
+		return err
+	})
+	return users, total, err
+}
+
+func (r memoryUsers) ListByRole(ctx context.Context, role models.Role) ([]models.User, error) {
+	users := []models.User{}
+	err := r.s.do(func(d *memoryData) error {
+		for _, u := range d.users {
+			if !u.DeletedAt.Valid && u.Role == role {
+				users = append(users, u)
+			}
+		}
+		sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+		return nil
+	})
+	return users, err
+}
+
+func (r memoryUsers) Get(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	err := r.s.do(func(d *memoryData) error {
+		u, ok := d.users[id]
+		if !ok || u.DeletedAt.Valid {
+			return ErrNotFound
+		}
+		user = u
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r memoryUsers) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	var user *models.User
+	err := r.s.do(func(d *memoryData) error {
+		for _, u := range d.users {
+			if !u.DeletedAt.Valid && u.Username == username {
+				user = &u
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+	return user, err
+}
+
+// Lock only checks the user exists; a transaction holds the store lock already
+func (r memoryUsers) Lock(ctx context.Context, id uint) error {
+	return r.s.do(func(d *memoryData) error {
+		if u, ok := d.users[id]; !ok || u.DeletedAt.Valid {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (r memoryUsers) Create(ctx context.Context, user *models.User) error {
+	return r.s.do(func(d *memoryData) error {
+		if err := checkUsername(d, user); err != nil {
+			return err
+		}
+		if user.Role == "" {
+			user.Role = models.RoleUser
+		}
+		now := time.Now()
+		user.ID = d.nextID("users")
+		user.CreatedAt, user.UpdatedAt = now, now
+		d.users[user.ID] = *user
+		return nil
+	})
+}
+
+func (r memoryUsers) Update(ctx context.Context, user *models.User) error {
+	return r.s.do(func(d *memoryData) error {
+		if err := checkUsername(d, user); err != nil {
+			return err
+		}
+		user.UpdatedAt = time.Now()
+		d.users[user.ID] = *user
+		return nil
+	})
+}
+
+func (r memoryUsers) Delete(ctx context.Context, user *models.User) error {
+	return r.s.do(func(d *memoryData) error {
+		if u, ok := d.users[user.ID]; ok {
+			u.DeletedAt = deletedNow()
+			d.users[user.ID] = u
+		}
+		return nil
+	})
+}
+
+// checkUsername mirrors the unique index, which also covers deleted rows
+func checkUsername(d *memoryData, user *models.User) error {
+	for _, u := range d.users {
+		if u.ID != user.ID && u.Username == user.Username {
+			return ErrDuplicate
+		}
+	}
+	return nil
+}
+
+type memoryUnits struct{ s *memoryStore }
+
+func (r memoryUnits) List(ctx context.Context, filter UnitFilter, page Page) (units []models.Unit, total int64, err error) {
+	err = r.s.do(func(d *memoryData) error {
+		all := []models.Unit{}
+		for _, u := range d.units {
+			if u.DeletedAt.Valid {
+				continue
+			}
+			if filter.Active != nil && u.IsActive != *filter.Active {
+				continue
+			}
+			if filter.Search != "" && !containsFold(u.Name, filter.Search) && !containsFold(u.QRCode, filter.Search) {
+				continue
+			}
+			all = append(all, u)
+		}
+		units, total, err = memoryPage(all, page, UnitSortFields, func(u models.Unit) (interface{}, uint) {
+			switch page.Sort {
+			case "name":
+				return u.Name, u.ID
+			case "qr_code":
+				return u.QRCode, u.ID
+			case "id":
+				return u.ID, u.ID
+			}
+			return u.CreatedAt, u.ID
+		})
+		return err
+	})
+	return units, total, err
+}
+
+func (r memoryUnits) Get(ctx context.Context, id uint) (*models.Unit, error) {
+	var unit models.Unit
+	err := r.s.do(func(d *memoryData) error {
+		u, ok := d.units[id]
+		if !ok || u.DeletedAt.Valid {
+			return ErrNotFound
+		}
+		unit = u
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &unit, nil
+}
+
+func (r memoryUnits) GetActiveByQRCode(ctx context.Context, qrCode string) (*models.Unit, error) {
+	var unit *models.Unit
+	err := r.s.do(func(d *memoryData) error {
+		for _, u := range d.units {
+			if !u.DeletedAt.Valid && u.IsActive && u.QRCode == qrCode {
+				unit = &u
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+	return unit, err
+}
+
+func (r memoryUnits) Create(ctx context.Context, unit *models.Unit) error {
+	return r.s.do(func(d *memoryData) error {
+		if err := checkQRCode(d, unit); err != nil {
+			return err
+		}
+		now := time.Now()
+		unit.ID = d.nextID("units")
+		unit.CreatedAt, unit.UpdatedAt = now, now
+		d.units[unit.ID] = *unit
+		return nil
+	})
+}
+
+func (r memoryUnits) Update(ctx context.Context, unit *models.Unit) error {
+	return r.s.do(func(d *memoryData) error {
+		if err := checkQRCode(d, unit); err != nil {
+			return err
+		}
+		unit.UpdatedAt = time.Now()
+		d.units[unit.ID] = *unit
+		return nil
+	})
+}
+
+func (r memoryUnits) Delete(ctx context.Context, unit *models.Unit) error {
+	return r.s.do(func(d *memoryData) error {
+		if u, ok := d.units[unit.ID]; ok {
+			u.DeletedAt = deletedNow()
+			d.units[unit.ID] = u
+		}
+		return nil
+	})
+}
+
+func checkQRCode(d *memoryData, unit *models.Unit) error {
+	for _, u := range d.units {
+		if u.ID != unit.ID && u.QRCode == unit.QRCode {
+			return ErrDuplicate
+		}
+	}
+	return nil
+}
+
+type memoryShifts struct{ s *memoryStore }
+
+func (r memoryShifts) find(keep func(models.Shift) bool) ([]models.Shift, error) {
+	shifts := []models.Shift{}
+	err := r.s.do(func(d *memoryData) error {
+		for _, shift := range d.shifts {
+			if keep(shift) {
+				shifts = append(shifts, shift)
+			}
+		}
+		sort.Slice(shifts, func(i, j int) bool { return shifts[i].ID < shifts[j].ID })
+		return nil
+	})
+	return shifts, err
+}
+
+func (r memoryShifts) List(ctx context.Context) ([]models.Shift, error) {
+	shifts, err := r.find(func(s models.Shift) bool { return !s.DeletedAt.Valid })
+	sort.SliceStable(shifts, func(i, j int) bool { return shifts[i].StartTime < shifts[j].StartTime })
+	return shifts, err
+}
+
+func (r memoryShifts) ListActive(ctx context.Context) ([]models.Shift, error) {
+	return r.find(func(s models.Shift) bool { return !s.DeletedAt.Valid && s.IsActive })
+}
+
+func (r memoryShifts) ListWithDeleted(ctx context.Context) ([]models.Shift, error) {
+	return r.find(func(models.Shift) bool { return true })
+}
+
+func (r memoryShifts) Get(ctx context.Context, id uint) (*models.Shift, error) {
+	var shift models.Shift
+	err := r.s.do(func(d *memoryData) error {
+		s, ok := d.shifts[id]
+		if !ok || s.DeletedAt.Valid {
+			return ErrNotFound
+		}
+		shift = s
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &shift, nil
+}
+
+func (r memoryShifts) Create(ctx context.Context, shift *models.Shift) error {
+	return r.s.do(func(d *memoryData) error {
+		if err := checkShiftName(d, shift); err != nil {
+			return err
+		}
+		now := time.Now()
+		shift.ID = d.nextID("shifts")
+		shift.CreatedAt, shift.UpdatedAt = now, now
+		d.shifts[shift.ID] = *shift
+		return nil
+	})
+}
+
+func (r memoryShifts) Update(ctx context.Context, shift *models.Shift) error {
+	return r.s.do(func(d *memoryData) error {
+		if err := checkShiftName(d, shift); err != nil {
+			return err
+		}
+		shift.UpdatedAt = time.Now()
+		d.shifts[shift.ID] = *shift
+		return nil
+	})
+}
+
+func (r memoryShifts) Delete(ctx context.Context, shift *models.Shift) error {
+	return r.s.do(func(d *memoryData) error {
+		if s, ok := d.shifts[shift.ID]; ok {
+			s.DeletedAt = deletedNow()
+			d.shifts[shift.ID] = s
+		}
+		return nil
+	})
+}
+
+func checkShiftName(d *memoryData, shift *models.Shift) error {
+	for _, s := range d.shifts {
+		if s.ID != shift.ID && s.Name == shift.Name {
+			return ErrDuplicate
+		}
+	}
+	return nil
+}
+
+type memorySavedViews struct{ s *memoryStore }
+
+func (r memorySavedViews) List(ctx context.Context, userID uint) ([]models.SavedView, error) {
+	views := []models.SavedView{}
+	err := r.s.do(func(d *memoryData) error {
+		for _, view := range d.views {
+			if view.UserID == userID {
+				views = append(views, view)
+			}
+		}
+		sort.Slice(views, func(i, j int) bool { return views[i].Name < views[j].Name })
+		return nil
+	})
+	return views, err
+}
+
+func (r memorySavedViews) Get(ctx context.Context, userID, id uint) (*models.SavedView, error) {
+	var view models.SavedView
+	err := r.s.do(func(d *memoryData) error {
+		v, ok := d.views[id]
+		if !ok || v.UserID != userID {
+			return ErrNotFound
+		}
+		view = v
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+func (r memorySavedViews) Create(ctx context.Context, view *models.SavedView) error {
+	return r.s.do(func(d *memoryData) error {
+		for _, v := range d.views {
+			if v.UserID == view.UserID && v.Name == view.Name {
+				return ErrDuplicate
+			}
+		}
+		now := time.Now()
+		view.ID = d.nextID("saved_views")
+		view.CreatedAt, view.UpdatedAt = now, now
+		d.views[view.ID] = *view
+		return nil
+	})
+}
+
+func (r memorySavedViews) Delete(ctx context.Context, view *models.SavedView) error {
+	return r.s.do(func(d *memoryData) error {
+		delete(d.views, view.ID)
+		return nil
+	})
+}
+
+type memorySettings struct{ s *memoryStore }
+
+func (r memorySettings) List(ctx context.Context) ([]models.Setting, error) {
+	settings := []models.Setting{}
+	err := r.s.do(func(d *memoryData) error {
+		for _, setting := range d.settings {
+			settings = append(settings, setting)
+		}
+		sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+		return nil
+	})
+	return settings, err
+}
+
+func (r memorySettings) Save(ctx context.Context, setting *models.Setting) error {
+	return r.s.do(func(d *memoryData) error {
+		setting.UpdatedAt = time.Now()
+		d.settings[setting.Key] = *setting
+		return nil
+	})
+}
+
+func (r memorySettings) Delete(ctx context.Context, key string) error {
+	return r.s.do(func(d *memoryData) error {
+		delete(d.settings, key)
+		return nil
+	})
+}
+
+type memoryAudit struct{ s *memoryStore }
+
+func (r memoryAudit) Record(ctx context.Context, entry services.AuditEntry) error {
+	return r.s.do(func(d *memoryData) error {
+		prevHash := ""
+		if n := len(d.audit); n > 0 {
+			prevHash = d.audit[n-1].Hash
+		}
+		row, err := services.NewAuditLog(entry, prevHash)
+		if err != nil {
+			return err
+		}
+		row.ID = d.nextID("audit_logs")
+		d.audit = append(d.audit, *row)
+		return nil
+	})
+}
+
+func (r memoryAudit) List(ctx context.Context, filter AuditFilter, page Page) (entries []models.AuditLog, total int64, err error) {
+	err = r.s.do(func(d *memoryData) error {
+		entries, total, err = memoryPage(filterAudit(d.audit, filter), page, AuditSortFields, func(a models.AuditLog) (interface{}, uint) {
+			if page.Sort == "created_at" {
+				return a.CreatedAt, a.ID
+			}
+			return a.ID, a.ID
+		})
+		return err
+	})
+	return entries, total, err
+}
+
+func (r memoryAudit) Find(ctx context.Context, filter AuditFilter) (entries []models.AuditLog, err error) {
+	err = r.s.do(func(d *memoryData) error {
+		entries = filterAudit(d.audit, filter)
+		return nil
+	})
+	return entries, err
+}
+
+func (r memoryAudit) Verify(ctx context.Context) (result services.AuditVerification, err error) {
+	err = r.s.do(func(d *memoryData) error {
+		result = services.VerifyAuditLogs(d.audit)
+		return nil
+	})
+	return result, err
+}
+
+func filterAudit(rows []models.AuditLog, f AuditFilter) []models.AuditLog {
+	result := []models.AuditLog{}
+	for _, a := range rows {
+		actorID := ""
+		if a.ActorID != nil {
+			actorID = fmt.Sprint(*a.ActorID)
+		}
+		switch {
+		case f.ActorID != "" && actorID != f.ActorID,
+			f.Action != "" && a.Action != f.Action,
+			f.EntityType != "" && a.EntityType != f.EntityType,
+			f.EntityID != "" && a.EntityID != f.EntityID,
+			f.RequestID != "" && a.RequestID != f.RequestID,
+			f.From != nil && a.CreatedAt.Before(*f.From),
+			f.To != nil && !a.CreatedAt.Before(*f.To):
+			continue
+		}
+		result = append(result, a)
+	}
+	return result
+}
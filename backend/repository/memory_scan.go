@@ -0,0 +1,347 @@
+package repository
+
+import (
+	"context"
+	"scandata/models"
+	"scandata/services"
+	"sort"
+	"time"
+)
+
+type memoryScans struct{ s *memoryStore }
+
+// sortedScans returns the stored scans in id order
+func (d *memoryData) sortedScans() []models.ScanLog {
+	scans := make([]models.ScanLog, 0, len(d.scans))
+	for _, scan := range d.scans {
+		scans = append(scans, scan)
+	}
+	sort.Slice(scans, func(i, j int) bool { return scans[i].ID < scans[j].ID })
+	return scans
+}
+
+// preload fills in the associations the GORM store preloads; deleted users,
+// units and shifts are left empty the same way
+func (d *memoryData) preload(scan *models.ScanLog, withShift bool) {
+	if user, ok := d.users[scan.UserID]; ok && !user.DeletedAt.Valid {
+		scan.User = user
+	}
+	if scan.UnitID != nil {
+		if unit, ok := d.units[*scan.UnitID]; ok && !unit.DeletedAt.Valid {
+			scan.Unit = unit
+		}
+	}
+	if withShift && scan.ShiftID != nil {
+		if shift, ok := d.shifts[*scan.ShiftID]; ok && !shift.DeletedAt.Valid {
+			scan.Shift = &shift
+		}
+	}
+}
+
+// matches is applyScanFilter for a single scan. Locations and grades look
+// at deleted units too, like the subquery does.
+func (d *memoryData) matches(scan models.ScanLog, f ScanFilter) bool {
+	if !f.IncludeVoided && scan.IsVoided {
+		return false
+	}
+	if f.OwnerID != nil && scan.UserID != *f.OwnerID {
+		return false
+	}
+	if f.From != nil && scan.ScannedAt.Before(*f.From) {
+		return false
+	}
+	if f.To != nil && !scan.ScannedAt.Before(*f.To) {
+		return false
+	}
+	if len(f.UserIDs) > 0 && !containsID(f.UserIDs, scan.UserID) {
+		return false
+	}
+	if len(f.UnitIDs) > 0 && (scan.UnitID == nil || !containsID(f.UnitIDs, *scan.UnitID)) {
+		return false
+	}
+	if len(f.Locations) > 0 || len(f.Grades) > 0 {
+		if scan.UnitID == nil {
+			return false
+		}
+		unit, ok := d.units[*scan.UnitID]
+		if !ok {
+			return false
+		}
+		if len(f.Locations) > 0 && !containsString(f.Locations, unit.Location) {
+			return false
+		}
+		if len(f.Grades) > 0 && !containsString(f.Grades, unit.ExpectedGrade) {
+			return false
+		}
+	}
+	if f.Barcode != "" && !containsFold(scan.Barcode, f.Barcode) {
+		return false
+	}
+	if f.GTIN != "" && scan.GTIN != f.GTIN {
+		return false
+	}
+	if f.Lot != "" && scan.Lot != f.Lot {
+		return false
+	}
+	if f.IsMatch != nil && scan.IsMatch != *f.IsMatch {
+		return false
+	}
+	if f.Notes != "" && !containsFold(scan.Notes, f.Notes) {
+		return false
+	}
+	return true
+}
+
+func (d *memoryData) filterScans(f ScanFilter) []models.ScanLog {
+	result := []models.ScanLog{}
+	for _, scan := range d.sortedScans() {
+		if d.matches(scan, f) {
+			result = append(result, scan)
+		}
+	}
+	return result
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (r memoryScans) Create(ctx context.Context, scan *models.ScanLog) error {
+	return r.s.do(func(d *memoryData) error {
+		scan.ID = d.nextID("scan_logs")
+		stored := *scan
+		stored.User, stored.Unit, stored.Shift = models.User{}, models.Unit{}, nil
+		d.scans[scan.ID] = stored
+		return nil
+	})
+}
+
+func (r memoryScans) Get(ctx context.Context, id uint) (*models.ScanLog, error) {
+	var scan models.ScanLog
+	err := r.s.do(func(d *memoryData) error {
+		s, ok := d.scans[id]
+		if !ok {
+			return ErrNotFound
+		}
+		d.preload(&s, true)
+		scan = s
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &scan, nil
+}
+
+func (r memoryScans) LatestSince(ctx context.Context, userID uint, barcode string, since time.Time) (*models.ScanLog, error) {
+	var latest *models.ScanLog
+	err := r.s.do(func(d *memoryData) error {
+		for _, scan := range d.sortedScans() {
+			if scan.UserID != userID || scan.Barcode != barcode || scan.IsVoided || scan.ScannedAt.Before(since) {
+				continue
+			}
+			if latest == nil || scan.ScannedAt.After(latest.ScannedAt) {
+				latest = &scan
+			}
+		}
+		if latest == nil {
+			return ErrNotFound
+		}
+		return nil
+	})
+	return latest, err
+}
+
+func (r memoryScans) IncrementDuplicates(ctx context.Context, id uint) error {
+	return r.s.do(func(d *memoryData) error {
+		if scan, ok := d.scans[id]; ok {
+			scan.DuplicateCount++
+			d.scans[id] = scan
+		}
+		return nil
+	})
+}
+
+func (r memoryScans) SaveEdit(ctx context.Context, scan *models.ScanLog, fromRevision int) error {
+	return r.s.do(func(d *memoryData) error {
+		stored, ok := d.scans[scan.ID]
+		if !ok || stored.Revision != fromRevision || stored.IsVoided {
+			return ErrConflict
+		}
+		stored.IsMatch = scan.IsMatch
+		stored.Notes = scan.Notes
+		stored.IsVoided = scan.IsVoided
+		stored.VoidedAt = scan.VoidedAt
+		stored.VoidedBy = scan.VoidedBy
+		stored.VoidReason = scan.VoidReason
+		stored.Revision = scan.Revision
+		d.scans[scan.ID] = stored
+		return nil
+	})
+}
+
+func (r memoryScans) Retag(ctx context.Context, start, end time.Time, assign func(*models.ScanLog)) (int64, error) {
+	var updated int64
+	err := r.s.do(func(d *memoryData) error {
+		for _, scan := range d.sortedScans() {
+			if scan.ScannedAt.Before(start) || !scan.ScannedAt.Before(end) {
+				continue
+			}
+			before := scan
+			assign(&scan)
+			if shiftChanged(before, scan) {
+				d.scans[scan.ID] = scan
+				updated++
+			}
+		}
+		return nil
+	})
+	return updated, err
+}
+
+func (r memoryScans) List(ctx context.Context, filter ScanFilter, page Page) (scans []models.ScanLog, total int64, err error) {
+	err = r.s.do(func(d *memoryData) error {
+		scans, total, err = memoryPage(d.filterScans(filter), page, ScanSortFields, func(s models.ScanLog) (interface{}, uint) {
+			switch page.Sort {
+			case "barcode":
+				return s.Barcode, s.ID
+			case "id":
+				return s.ID, s.ID
+			}
+			return s.ScannedAt, s.ID
+		})
+		for i := range scans {
+			d.preload(&scans[i], false)
+		}
+		return err
+	})
+	return scans, total, err
+}
+
+func (r memoryScans) Find(ctx context.Context, filter ScanFilter) (scans []models.ScanLog, err error) {
+	err = r.s.do(func(d *memoryData) error {
+		scans = d.filterScans(filter)
+		sort.SliceStable(scans, func(i, j int) bool { return scans[i].ScannedAt.After(scans[j].ScannedAt) })
+		for i := range scans {
+			d.preload(&scans[i], false)
+		}
+		return nil
+	})
+	return scans, err
+}
+
+func (r memoryScans) Count(ctx context.Context, filter ScanFilter) (counts ScanCounts, err error) {
+	err = r.s.do(func(d *memoryData) error {
+		barcodes := make(map[string]struct{})
+		var merged, flagged int64
+		for _, scan := range d.filterScans(filter) {
+			counts.Total++
+			if scan.IsMatch {
+				counts.Match++
+			}
+			if scan.DuplicateOfID != nil {
+				flagged++
+			}
+			merged += int64(scan.DuplicateCount)
+			barcodes[scan.Barcode] = struct{}{}
+		}
+		counts.NotMatch = counts.Total - counts.Match
+		counts.DistinctUnits = int64(len(barcodes))
+		counts.RawEvents = counts.Total + merged
+		counts.Duplicates = flagged + merged
+		return nil
+	})
+	return counts, err
+}
+
+// Rows joins users and units without looking at deleted_at, as the SQL does
+func (r memoryScans) EachRow(ctx context.Context, filter ScanFilter, fn func(ScanRow) error) error {
+	return r.s.do(func(d *memoryData) error {
+		for _, scan := range d.filterScans(filter) {
+			row := ScanRow{
+				ScannedAt: scan.ScannedAt,
+				Barcode:   scan.Barcode,
+				IsMatch:   scan.IsMatch,
+				UserID:    scan.UserID,
+				UnitID:    scan.UnitID,
+			}
+			if user, ok := d.users[scan.UserID]; ok {
+				row.UserName = &user.Name
+			}
+			if scan.UnitID != nil {
+				if unit, ok := d.units[*scan.UnitID]; ok {
+					row.UnitName = &unit.Name
+					row.UnitLocation = &unit.Location
+					row.UnitGrade = &unit.ExpectedGrade
+				}
+			}
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r memoryScans) ShiftEvents(ctx context.Context, start, end time.Time) (events []services.ShiftEvent, err error) {
+	err = r.s.do(func(d *memoryData) error {
+		for _, scan := range d.sortedScans() {
+			if scan.IsVoided || scan.ShiftID == nil || scan.ShiftDate == nil ||
+				scan.ShiftDate.Before(start) || !scan.ShiftDate.Before(end) {
+				continue
+			}
+			events = append(events, services.ShiftEvent{
+				ShiftID:   *scan.ShiftID,
+				ShiftDate: *scan.ShiftDate,
+				UserID:    scan.UserID,
+				ScannedAt: scan.ScannedAt,
+				IsMatch:   scan.IsMatch,
+			})
+		}
+		return nil
+	})
+	return events, err
+}
+
+func (r memoryScans) Revisions(ctx context.Context, scanID uint) ([]models.ScanRevision, error) {
+	revisions := []models.ScanRevision{}
+	err := r.s.do(func(d *memoryData) error {
+		for _, revision := range d.revisions {
+			if revision.ScanLogID != scanID {
+				continue
+			}
+			if user, ok := d.users[revision.ChangedBy]; ok && !user.DeletedAt.Valid {
+				revision.User = user
+			}
+			revisions = append(revisions, revision)
+		}
+		sort.SliceStable(revisions, func(i, j int) bool { return revisions[i].Revision < revisions[j].Revision })
+		return nil
+	})
+	return revisions, err
+}
+
+func (r memoryScans) CreateRevision(ctx context.Context, revision *models.ScanRevision) error {
+	return r.s.do(func(d *memoryData) error {
+		revision.ID = d.nextID("scan_revisions")
+		revision.CreatedAt = time.Now()
+		stored := *revision
+		stored.User = models.User{}
+		d.revisions = append(d.revisions, stored)
+		return nil
+	})
+}
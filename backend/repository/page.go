@@ -0,0 +1,47 @@
+package repository
+
+// Page asks for rows in keyset order: sorted by Sort then id, starting after
+// the row After points at. Limit is the most rows returned, 0 for all.
+type Page struct {
+	Limit int
+	Sort  string
+	Desc  bool
+	After *Cursor
+}
+
+// Cursor is the sort value and id of the last row of the previous page.
+// Times are RFC3339 with nanoseconds.
+type Cursor struct {
+	Value string
+	ID    uint
+}
+
+// SortField is a column a list may be sorted by
+type SortField struct {
+	Column string
+	IsTime bool
+}
+
+var (
+	UserSortFields = map[string]SortField{
+		"id":         {Column: "id"},
+		"username":   {Column: "username"},
+		"name":       {Column: "name"},
+		"created_at": {Column: "created_at", IsTime: true},
+	}
+	UnitSortFields = map[string]SortField{
+		"id":         {Column: "id"},
+		"name":       {Column: "name"},
+		"qr_code":    {Column: "qr_code"},
+		"created_at": {Column: "created_at", IsTime: true},
+	}
+	ScanSortFields = map[string]SortField{
+		"scanned_at": {Column: "scanned_at", IsTime: true},
+		"barcode":    {Column: "barcode"},
+		"id":         {Column: "id"},
+	}
+	AuditSortFields = map[string]SortField{
+		"id":         {Column: "id"},
+		"created_at": {Column: "created_at", IsTime: true},
+	}
+)
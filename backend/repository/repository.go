@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"scandata/models"
+	"scandata/services"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when the requested row does not exist
+	ErrNotFound error = notFoundError{}
+	// ErrConflict is returned when a guarded update lost against a
+	// concurrent change
+	ErrConflict = errors.New("record changed concurrently")
+	// ErrDuplicate is returned when a unique field is already taken
+	ErrDuplicate = errors.New("duplicate key")
+	// ErrReferenced is returned when a foreign key does not match a row, or
+	// a row is still referenced
+	ErrReferenced = errors.New("foreign key violated")
+	// ErrUnavailable is returned when the database cannot be reached
+	ErrUnavailable error = unavailableError{}
+)
+
+// The response an error maps to is told by its methods (see apierror), so
+// the API layer does not depend on these values
+
+type notFoundError struct{}
+
+func (notFoundError) Error() string  { return "record not found" }
+func (notFoundError) NotFound() bool { return true }
+
+type unavailableError struct{}
+
+func (unavailableError) Error() string     { return "database unavailable" }
+func (unavailableError) Unavailable() bool { return true }
+
+// Store gives access to every repository. Transaction runs fn against a
+// store whose writes commit together or not at all.
+type Store interface {
+	Users() UserRepository
+	Units() UnitRepository
+	Shifts() ShiftRepository
+	Scans() ScanRepository
+	SavedViews() SavedViewRepository
+	Audit() AuditRepository
+	Settings() SettingRepository
+	Transaction(ctx context.Context, fn func(tx Store) error) error
+}
+
+type UserRepository interface {
+	List(ctx context.Context, page Page) ([]models.User, int64, error)
+	ListByRole(ctx context.Context, role models.Role) ([]models.User, error)
+	Get(ctx context.Context, id uint) (*models.User, error)
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	// Lock holds the row of the user until the transaction ends, so writes
+	// made on their behalf run one after another
+	Lock(ctx context.Context, id uint) error
+	Create(ctx context.Context, user *models.User) error
+	Update(ctx context.Context, user *models.User) error
+	Delete(ctx context.Context, user *models.User) error
+}
+
+// UnitFilter narrows the unit list; empty fields do not filter
+type UnitFilter struct {
+	Active *bool
+	// Search matches name or QR code, case-insensitively
+	Search string
+}
+
+type UnitRepository interface {
+	List(ctx context.Context, filter UnitFilter, page Page) ([]models.Unit, int64, error)
+	Get(ctx context.Context, id uint) (*models.Unit, error)
+	GetActiveByQRCode(ctx context.Context, qrCode string) (*models.Unit, error)
+	Create(ctx context.Context, unit *models.Unit) error
+	Update(ctx context.Context, unit *models.Unit) error
+	Delete(ctx context.Context, unit *models.Unit) error
+}
+
+type ShiftRepository interface {
+	// List returns the shifts ordered by start time
+	List(ctx context.Context) ([]models.Shift, error)
+	ListActive(ctx context.Context) ([]models.Shift, error)
+	// ListWithDeleted includes deleted shifts so historic scans keep their names
+	ListWithDeleted(ctx context.Context) ([]models.Shift, error)
+	Get(ctx context.Context, id uint) (*models.Shift, error)
+	Create(ctx context.Context, shift *models.Shift) error
+	Update(ctx context.Context, shift *models.Shift) error
+	Delete(ctx context.Context, shift *models.Shift) error
+}
+
+// ScanCounts separates stored scans from raw scanner events. RawEvents adds
+// repeats merged into an earlier scan, DistinctUnits counts unique barcodes and
+// Duplicates counts flagged plus merged repeats.
+type ScanCounts struct {
+	Total         int64 `json:"total"`
+	Match         int64 `json:"match"`
+	NotMatch      int64 `json:"not_match"`
+	DistinctUnits int64 `json:"distinct_units"`
+	RawEvents     int64 `json:"raw_events"`
+	Duplicates    int64 `json:"duplicates"`
+}
+
+// ScanRow is a scan with the user and unit fields reports group by. The
+// names are nil when the user or unit no longer exists.
+type ScanRow struct {
+	ScannedAt    time.Time
+	Barcode      string
+	IsMatch      bool
+	UserID       uint
+	UnitID       *uint
+	UserName     *string
+	UnitName     *string
+	UnitLocation *string
+	UnitGrade    *string
+}
+
+type ScanRepository interface {
+	Create(ctx context.Context, scan *models.ScanLog) error
+	// Get loads a scan with its user, unit and shift
+	Get(ctx context.Context, id uint) (*models.ScanLog, error)
+	// LatestSince returns the newest live scan of barcode by the user at or
+	// after since
+	LatestSince(ctx context.Context, userID uint, barcode string, since time.Time) (*models.ScanLog, error)
+	IncrementDuplicates(ctx context.Context, id uint) error
+	// SaveEdit writes the editable fields of scan (match, notes, void state
+	// and revision) if the stored row is still live and at fromRevision,
+	// otherwise it returns ErrConflict
+	SaveEdit(ctx context.Context, scan *models.ScanLog, fromRevision int) error
+	// Retag calls assign for every scan in [start, end), saves the scans
+	// whose shift changed and returns how many did
+	Retag(ctx context.Context, start, end time.Time, assign func(*models.ScanLog)) (int64, error)
+
+	// List returns one page of matching scans with their user and unit
+	List(ctx context.Context, filter ScanFilter, page Page) ([]models.ScanLog, int64, error)
+	// Find returns every matching scan with its user and unit, newest first
+	Find(ctx context.Context, filter ScanFilter) ([]models.ScanLog, error)
+	Count(ctx context.Context, filter ScanFilter) (ScanCounts, error)
+	// EachRow calls fn for every matching scan without loading them all at
+	// once; fn must not use the store. An error from fn stops the iteration.
+	EachRow(ctx context.Context, filter ScanFilter, fn func(ScanRow) error) error
+	// ShiftEvents returns live shift-tagged scans whose shift started in [start, end)
+	ShiftEvents(ctx context.Context, start, end time.Time) ([]services.ShiftEvent, error)
+
+	// Revisions returns the corrections of a scan, oldest first
+	Revisions(ctx context.Context, scanID uint) ([]models.ScanRevision, error)
+	CreateRevision(ctx context.Context, revision *models.ScanRevision) error
+}
+
+type SavedViewRepository interface {
+	// List returns the views of a user ordered by name
+	List(ctx context.Context, userID uint) ([]models.SavedView, error)
+	Get(ctx context.Context, userID, id uint) (*models.SavedView, error)
+	Create(ctx context.Context, view *models.SavedView) error
+	Delete(ctx context.Context, view *models.SavedView) error
+}
+
+type SettingRepository interface {
+	// List returns the saved settings ordered by key
+	List(ctx context.Context) ([]models.Setting, error)
+	// Save inserts the setting or replaces the value stored under its key
+	Save(ctx context.Context, setting *models.Setting) error
+	Delete(ctx context.Context, key string) error
+}
+
+// AuditFilter narrows the audit log; empty fields do not filter
+type AuditFilter struct {
+	ActorID    string
+	Action     string
+	EntityType string
+	EntityID   string
+	RequestID  string
+	From       *time.Time
+	To         *time.Time
+}
+
+type AuditRepository interface {
+	// Record appends an entry to the hash chain; call it in a Transaction
+	Record(ctx context.Context, entry services.AuditEntry) error
+	List(ctx context.Context, filter AuditFilter, page Page) ([]models.AuditLog, int64, error)
+	// Find returns every matching entry in id order
+	Find(ctx context.Context, filter AuditFilter) ([]models.AuditLog, error)
+	Verify(ctx context.Context) (services.AuditVerification, error)
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"time"
+)
+
+// ScanFilter holds the scan search criteria shared by the history list,
+// saved views, reports and the Excel export. Empty fields do not filter.
+type ScanFilter struct {
+	From      *time.Time `json:"from,omitempty"`
+	To        *time.Time `json:"to,omitempty"`
+	UserIDs   []uint     `json:"user_ids,omitempty"`
+	UnitIDs   []uint     `json:"unit_ids,omitempty"`
+	Locations []string   `json:"locations,omitempty"`
+	Grades    []string   `json:"grades,omitempty"`
+	Barcode   string     `json:"barcode,omitempty"`
+	GTIN      string     `json:"gtin,omitempty"`
+	Lot       string     `json:"lot,omitempty"`
+	IsMatch   *bool      `json:"is_match,omitempty"`
+	Notes     string     `json:"notes,omitempty"`
+	// IncludeVoided also returns voided scans, which are hidden by default
+	IncludeVoided bool `json:"include_voided,omitempty"`
+	// OwnerID limits the result to one user's scans regardless of UserIDs;
+	// it is set by the server for non-admins and never saved
+	OwnerID *uint `json:"-"`
+}
+
+// Merge returns f with every field set in override replacing the saved value
+func (f ScanFilter) Merge(override ScanFilter) ScanFilter {
+	if override.From != nil {
+		f.From = override.From
+	}
+	if override.To != nil {
+		f.To = override.To
+	}
+	if len(override.UserIDs) > 0 {
+		f.UserIDs = override.UserIDs
+	}
+	if len(override.UnitIDs) > 0 {
+		f.UnitIDs = override.UnitIDs
+	}
+	if len(override.Locations) > 0 {
+		f.Locations = override.Locations
+	}
+	if len(override.Grades) > 0 {
+		f.Grades = override.Grades
+	}
+	if override.Barcode != "" {
+		f.Barcode = override.Barcode
+	}
+	if override.GTIN != "" {
+		f.GTIN = override.GTIN
+	}
+	if override.Lot != "" {
+		f.Lot = override.Lot
+	}
+	if override.IsMatch != nil {
+		f.IsMatch = override.IsMatch
+	}
+	if override.Notes != "" {
+		f.Notes = override.Notes
+	}
+	if override.IncludeVoided {
+		f.IncludeVoided = true
+	}
+	if override.OwnerID != nil {
+		f.OwnerID = override.OwnerID
+	}
+	return f
+}
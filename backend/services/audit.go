@@ -0,0 +1,211 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"scandata/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AuditEntry describes a mutation to record. Before and After are marshalled
+// to JSON; either may be nil for creates and deletes.
+type AuditEntry struct {
+	ActorID    *uint
+	ActorName  string
+	Action     string
+	EntityType string
+	EntityID   string
+	Before     interface{}
+	After      interface{}
+	IP         string
+	RequestID  string
+}
+
+// RecordAudit appends an entry to the hash chain. Pass the transaction that
+// performed the mutation so both commit or roll back together. The chain
+// head stays locked until that transaction ends, so concurrent appends wait
+// for it and a second append in the same transaction already holds it.
+func RecordAudit(tx *gorm.DB, e AuditEntry) error {
+	var head models.AuditChainHead
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&head, models.AuditChainHeadID).Error; err != nil {
+		return fmt.Errorf("lock audit chain head: %w", err)
+	}
+
+	entry, err := NewAuditLog(e, head.Hash)
+	if err != nil {
+		return err
+	}
+	if err := tx.Create(entry).Error; err != nil {
+		return err
+	}
+	return tx.Model(&head).Update("hash", entry.Hash).Error
+}
+
+// NewAuditLog builds the row for e linked to the entry with prevHash
+func NewAuditLog(e AuditEntry, prevHash string) (*models.AuditLog, error) {
+	before, err := marshalAudit(e.Before)
+	if err != nil {
+		return nil, err
+	}
+	after, err := marshalAudit(e.After)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := auditDiff(before, after)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &models.AuditLog{
+		ActorID:    e.ActorID,
+		ActorName:  e.ActorName,
+		Action:     e.Action,
+		EntityType: e.EntityType,
+		EntityID:   e.EntityID,
+		Before:     before,
+		After:      after,
+		Diff:       diff,
+		IP:         e.IP,
+		RequestID:  e.RequestID,
+		PrevHash:   prevHash,
+		CreatedAt:  time.Now().Truncate(time.Millisecond),
+	}
+	entry.Hash = AuditHash(entry)
+	return entry, nil
+}
+
+// AuditHash computes the chain hash of an entry from its content and PrevHash
+func AuditHash(a *models.AuditLog) string {
+	actorID := ""
+	if a.ActorID != nil {
+		actorID = fmt.Sprint(*a.ActorID)
+	}
+	fields := []string{
+		a.PrevHash,
+		fmt.Sprint(a.CreatedAt.UnixMilli()),
+		actorID,
+		a.ActorName,
+		a.Action,
+		a.EntityType,
+		a.EntityID,
+		string(a.Before),
+		string(a.After),
+		string(a.Diff),
+		a.IP,
+		a.RequestID,
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x1f")))
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditVerification is the outcome of walking the chain
+type AuditVerification struct {
+	Valid    bool   `json:"valid"`
+	Checked  int64  `json:"checked"`
+	BrokenAt *uint  `json:"broken_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// VerifyAuditChain recomputes every hash in id order and checks each row
+// links to the one before it
+func VerifyAuditChain(db *gorm.DB) (AuditVerification, error) {
+	result := AuditVerification{Valid: true}
+	prevHash := ""
+
+	var batch []models.AuditLog
+	err := db.Order("id ASC").FindInBatches(&batch, 500, func(_ *gorm.DB, _ int) error {
+		for i := range batch {
+			if !result.check(&batch[i], &prevHash) {
+				return errStopVerify
+			}
+		}
+		return nil
+	}).Error
+	if err != nil && err != errStopVerify {
+		return result, err
+	}
+	return result, nil
+}
+
+// VerifyAuditLogs checks a chain already loaded in id order
+func VerifyAuditLogs(rows []models.AuditLog) AuditVerification {
+	result := AuditVerification{Valid: true}
+	prevHash := ""
+	for i := range rows {
+		if !result.check(&rows[i], &prevHash) {
+			break
+		}
+	}
+	return result
+}
+
+var errStopVerify = fmt.Errorf("audit chain broken")
+
+// check verifies one row against the hash of the row before it
+func (v *AuditVerification) check(row *models.AuditLog, prevHash *string) bool {
+	v.Checked++
+	if row.PrevHash != *prevHash {
+		v.fail(row.ID, "previous hash does not match, a row was removed or reordered")
+		return false
+	}
+	if AuditHash(row) != row.Hash {
+		v.fail(row.ID, "content does not match its hash, the row was modified")
+		return false
+	}
+	*prevHash = row.Hash
+	return true
+}
+
+func (v *AuditVerification) fail(id uint, reason string) {
+	v.Valid = false
+	v.BrokenAt = &id
+	v.Reason = reason
+}
+
+func marshalAudit(v interface{}) (json.RawMessage, error) {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+// auditDiff lists the top level fields that changed as {"field": {"from": x, "to": y}}
+func auditDiff(before, after json.RawMessage) (json.RawMessage, error) {
+	var from, to map[string]interface{}
+	if len(before) > 0 {
+		if err := json.Unmarshal(before, &from); err != nil {
+			return nil, err
+		}
+	}
+	if len(after) > 0 {
+		if err := json.Unmarshal(after, &to); err != nil {
+			return nil, err
+		}
+	}
+
+	diff := make(map[string]map[string]interface{})
+	for key, value := range to {
+		if old, ok := from[key]; !ok || !reflect.DeepEqual(old, value) {
+			diff[key] = map[string]interface{}{"from": from[key], "to": value}
+		}
+	}
+	for key, value := range from {
+		if _, ok := to[key]; !ok {
+			diff[key] = map[string]interface{}{"from": value, "to": nil}
+		}
+	}
+	// Timestamps change on every save and only add noise
+	delete(diff, "updated_at")
+
+	if len(diff) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(diff)
+}
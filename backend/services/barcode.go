@@ -0,0 +1,196 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// BarcodeValidator checks scanned values of one symbology
+type BarcodeValidator interface {
+	Name() string
+	// Accepts reports whether the value looks like this symbology at all
+	Accepts(code string) bool
+	// Validate checks the value in detail and extracts structured fields
+	Validate(code string) (*BarcodeInfo, error)
+}
+
+// BarcodeInfo is what a validator learned about a scanned value
+type BarcodeInfo struct {
+	Symbology string
+	GTIN      string
+	Lot       string
+	Serial    string
+	Expiry    *time.Time
+}
+
+// BarcodeRegistry runs scanned values through the enabled validators in order.
+// The first validator that accepts a value decides whether it is valid.
+type BarcodeRegistry struct {
+	validators   []BarcodeValidator
+	typePatterns map[string]*regexp.Regexp
+}
+
+var builtinValidators = map[string]func() BarcodeValidator{
+	"gs1":     func() BarcodeValidator { return gs1Validator{} },
+	"ean13":   func() BarcodeValidator { return checkDigitValidator{name: "ean13", length: 13} },
+	"upca":    func() BarcodeValidator { return checkDigitValidator{name: "upca", length: 12} },
+	"ean8":    func() BarcodeValidator { return checkDigitValidator{name: "ean8", length: 8} },
+	"code128": func() BarcodeValidator { return code128Validator{} },
+}
+
+// NewBarcodeRegistry enables the named built-in formats and compiles the
+// regex rules keyed by unit type.
+func NewBarcodeRegistry(formats []string, typePatterns map[string]string) (*BarcodeRegistry, error) {
+	r := &BarcodeRegistry{typePatterns: make(map[string]*regexp.Regexp)}
+
+	for _, name := range formats {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || name == "none" {
+			continue
+		}
+		factory, ok := builtinValidators[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown barcode format %q", name)
+		}
+		r.Register(factory())
+	}
+
+	for unitType, pattern := range typePatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid barcode pattern for unit type %q: %w", unitType, err)
+		}
+		r.typePatterns[unitType] = re
+	}
+
+	return r, nil
+}
+
+// Register appends a validator, giving it lower priority than those already registered
+func (r *BarcodeRegistry) Register(v BarcodeValidator) {
+	r.validators = append(r.validators, v)
+}
+
+// HasTypePattern reports whether a regex rule is configured for the unit type
+func (r *BarcodeRegistry) HasTypePattern(unitType string) bool {
+	_, ok := r.typePatterns[unitType]
+	return ok && unitType != ""
+}
+
+// Validate checks a scanned value. When the unit type has a regex rule the
+// value must match it; otherwise it must pass the first accepting validator.
+func (r *BarcodeRegistry) Validate(code, unitType string) (*BarcodeInfo, error) {
+	if r.HasTypePattern(unitType) {
+		if !r.typePatterns[unitType].MatchString(code) {
+			return nil, fmt.Errorf("does not match the format for unit type %s", unitType)
+		}
+		return &BarcodeInfo{Symbology: "pattern:" + unitType}, nil
+	}
+
+	if len(r.validators) == 0 {
+		return &BarcodeInfo{}, nil
+	}
+
+	for _, v := range r.validators {
+		if v.Accepts(code) {
+			return v.Validate(code)
+		}
+	}
+	return nil, errors.New("unsupported barcode format")
+}
+
+// checkDigitValidator handles the all-digit EAN/UPC family
+type checkDigitValidator struct {
+	name   string
+	length int
+}
+
+func (v checkDigitValidator) Name() string { return v.name }
+
+func (v checkDigitValidator) Accepts(code string) bool {
+	return len(code) == v.length && isDigits(code)
+}
+
+func (v checkDigitValidator) Validate(code string) (*BarcodeInfo, error) {
+	if err := verifyCheckDigit(code); err != nil {
+		return nil, fmt.Errorf("%s %w", strings.ToUpper(v.name), err)
+	}
+	return &BarcodeInfo{
+		Symbology: v.name,
+		GTIN:      strings.Repeat("0", 14-len(code)) + code,
+	}, nil
+}
+
+// code128Validator accepts anything printable in the Code 128 character set
+type code128Validator struct{}
+
+func (code128Validator) Name() string { return "code128" }
+
+func (code128Validator) Accepts(code string) bool { return true }
+
+func (code128Validator) Validate(code string) (*BarcodeInfo, error) {
+	for i, ch := range code {
+		if ch < 0x20 || ch > 0x7e {
+			return nil, fmt.Errorf("character %q at position %d is not valid in Code 128", ch, i+1)
+		}
+	}
+	return &BarcodeInfo{Symbology: "code128"}, nil
+}
+
+// gs1Validator handles GS1-128, GS1 DataMatrix and GS1 QR element strings
+type gs1Validator struct{}
+
+func (gs1Validator) Name() string { return "gs1" }
+
+func (gs1Validator) Accepts(code string) bool {
+	return LooksLikeGS1(code)
+}
+
+func (gs1Validator) Validate(code string) (*BarcodeInfo, error) {
+	data, err := ParseGS1(code)
+	if err != nil {
+		return nil, fmt.Errorf("GS1 %w", err)
+	}
+	return &BarcodeInfo{
+		Symbology: "gs1",
+		GTIN:      data.GTIN,
+		Lot:       data.Lot,
+		Serial:    data.Serial,
+		Expiry:    data.Expiry,
+	}, nil
+}
+
+// verifyCheckDigit validates the GS1 mod-10 check digit used by EAN, UPC, GTIN and SSCC
+func verifyCheckDigit(code string) error {
+	if !isDigits(code) {
+		return errors.New("must contain digits only")
+	}
+	sum := 0
+	for i := len(code) - 2; i >= 0; i-- {
+		d := int(code[i] - '0')
+		if (len(code)-2-i)%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	expected := (10 - sum%10) % 10
+	if got := int(code[len(code)-1] - '0'); got != expected {
+		return fmt.Errorf("check digit mismatch (expected %d, got %d)", expected, got)
+	}
+	return nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
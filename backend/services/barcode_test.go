@@ -0,0 +1,83 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifyCheckDigit(t *testing.T) {
+	tests := []struct {
+		code string
+		ok   bool
+	}{
+		{"4006381333931", true},
+		{"4006381333932", false},
+		{"036000291452", true},
+		{"036000291453", false},
+		{"96385074", true},
+		{"96385075", false},
+		{"09506000134352", true},
+		{"009506000134350004", true},
+		{"40063813339A1", false},
+	}
+	for _, tt := range tests {
+		if err := verifyCheckDigit(tt.code); (err == nil) != tt.ok {
+			t.Fatalf("verifyCheckDigit(%s) = %v, want ok %v", tt.code, err, tt.ok)
+		}
+	}
+}
+
+func TestBarcodeRegistry(t *testing.T) {
+	registry, err := NewBarcodeRegistry([]string{"gs1", "ean13", "upca", "ean8", "code128"}, map[string]string{"pallet": `^PLT-\d{3}$`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		code      string
+		unitType  string
+		symbology string
+		gtin      string
+		err       string
+	}{
+		{"EAN-13", "4006381333931", "", "ean13", "04006381333931", ""},
+		{"EAN-13 check digit", "4006381333932", "", "", "", "EAN13 check digit mismatch"},
+		{"UPC-A", "036000291452", "", "upca", "00036000291452", ""},
+		{"EAN-8", "96385074", "", "ean8", "00000096385074", ""},
+		{"GS1", "(01)09506000134352(10)LOT", "", "gs1", "09506000134352", ""},
+		{"GS1 error", "(01)09506000134353", "", "", "", "GS1 AI (01) check digit mismatch"},
+		{"Code 128", "BOX-17/A", "", "code128", "", ""},
+		{"Code 128 control character", "BOX\x0117", "", "", "", "position 4 is not valid in Code 128"},
+		{"unit type pattern", "PLT-001", "pallet", "pattern:pallet", "", ""},
+		{"unit type pattern mismatch", "4006381333931", "pallet", "", "", "format for unit type pallet"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := registry.Validate(tt.code, tt.unitType)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Symbology != tt.symbology || info.GTIN != tt.gtin {
+				t.Fatalf("unexpected info %+v", info)
+			}
+		})
+	}
+
+	if _, err := NewBarcodeRegistry([]string{"qr"}, nil); err == nil {
+		t.Fatal("unknown format accepted")
+	}
+	none, err := NewBarcodeRegistry([]string{"none"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := none.Validate("anything \x01", ""); err != nil {
+		t.Fatalf("registry without validators rejected a value: %v", err)
+	}
+}
@@ -1,18 +1,21 @@
 package services
 
 import (
+	"scandata/i18n"
 	"scandata/models"
 
 	"github.com/xuri/excelize/v2"
 )
 
-func GenerateExcel(scans []models.ScanLog) (*excelize.File, error) {
+// GenerateExcel writes the scan report with sheet name, headers and match
+// labels in locale
+func GenerateExcel(scans []models.ScanLog, locale i18n.Locale) (*excelize.File, error) {
 	f := excelize.NewFile()
-	sheetName := "Scan Report"
+	sheetName := i18n.Translate(locale, "Scan Report")
 	f.SetSheetName("Sheet1", sheetName)
 
 	// Set headers
-	headers := []string{"No", "Tanggal", "Waktu", "Unit", "QR Code", "Lokasi", "Scanner", "Sesuai", "Catatan"}
+	headers := translateAll(locale, "No", "Date", "Time", "Unit", "QR Code", "Location", "Scanner", "Match", "Notes")
 	for i, header := range headers {
 		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
 		f.SetCellValue(sheetName, cell, header)
@@ -33,9 +36,9 @@ func GenerateExcel(scans []models.ScanLog) (*excelize.File, error) {
 	for i, scan := range scans {
 		row := i + 2
 
-		isMatchText := "Tidak Sesuai"
+		isMatchText := i18n.Translate(locale, "Mismatch")
 		if scan.IsMatch {
-			isMatchText = "Sesuai"
+			isMatchText = i18n.Translate(locale, "Match")
 		}
 
 		f.SetCellValue(sheetName, cellName(1, row), i+1)
@@ -62,3 +65,53 @@ func cellName(col, row int) string {
 	name, _ := excelize.CoordinatesToCellName(col, row)
 	return name
 }
+
+func translateAll(locale i18n.Locale, messages ...string) []string {
+	for i, message := range messages {
+		messages[i] = i18n.Translate(locale, message)
+	}
+	return messages
+}
+
+func GenerateAuditExcel(entries []models.AuditLog, locale i18n.Locale) (*excelize.File, error) {
+	f := excelize.NewFile()
+	sheetName := i18n.Translate(locale, "Audit Log")
+	f.SetSheetName("Sheet1", sheetName)
+
+	headers := translateAll(locale, "ID", "Time", "Actor", "Action", "Entity", "Entity ID", "Changes", "IP", "Request ID", "Hash")
+	for i, header := range headers {
+		f.SetCellValue(sheetName, cellName(i+1, 1), header)
+	}
+
+	headerStyle, _ := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true, Color: "FFFFFF"},
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"4472C4"}, Pattern: 1},
+		Alignment: &excelize.Alignment{
+			Horizontal: "center",
+			Vertical:   "center",
+		},
+	})
+	f.SetRowStyle(sheetName, 1, 1, headerStyle)
+
+	for i, entry := range entries {
+		row := i + 2
+		f.SetCellValue(sheetName, cellName(1, row), entry.ID)
+		f.SetCellValue(sheetName, cellName(2, row), entry.CreatedAt.Format("2006-01-02 15:04:05"))
+		f.SetCellValue(sheetName, cellName(3, row), entry.ActorName)
+		f.SetCellValue(sheetName, cellName(4, row), entry.Action)
+		f.SetCellValue(sheetName, cellName(5, row), entry.EntityType)
+		f.SetCellValue(sheetName, cellName(6, row), entry.EntityID)
+		f.SetCellValue(sheetName, cellName(7, row), string(entry.Diff))
+		f.SetCellValue(sheetName, cellName(8, row), entry.IP)
+		f.SetCellValue(sheetName, cellName(9, row), entry.RequestID)
+		f.SetCellValue(sheetName, cellName(10, row), entry.Hash)
+	}
+
+	for i := 1; i <= len(headers); i++ {
+		colName, _ := excelize.ColumnNumberToName(i)
+		f.SetColWidth(sheetName, colName, colName, 15)
+	}
+	f.SetColWidth(sheetName, "G", "G", 60)
+
+	return f, nil
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GS1GroupSeparator (ASCII GS) is how scanners transmit FNC1 between variable-length elements
+const GS1GroupSeparator = "\x1d"
+
+// gs1Symbologies are AIM symbology identifiers scanners prepend to GS1 data
+var gs1Symbologies = []string{"]C1", "]e0", "]d2", "]Q3", "]J1"}
+
+var gs1Bracketed = regexp.MustCompile(`^(\(\d{2,4}\)[^()]+)+$`)
+var gs1BracketedElement = regexp.MustCompile(`\((\d{2,4})\)([^()]+)`)
+
+// gs1AI describes an Application Identifier. Entries are keyed by the shortest
+// prefix that identifies them; length is the number of digits in the full AI.
+type gs1AI struct {
+	length int
+	fixed  int // data length for fixed-length AIs
+	max    int // maximum data length for variable-length AIs
+	title  string
+}
+
+var gs1AIs = map[string]gs1AI{
+	"00":   {length: 2, fixed: 18, title: "SSCC"},
+	"01":   {length: 2, fixed: 14, title: "GTIN"},
+	"02":   {length: 2, fixed: 14, title: "CONTENT"},
+	"10":   {length: 2, max: 20, title: "BATCH/LOT"},
+	"11":   {length: 2, fixed: 6, title: "PROD DATE"},
+	"12":   {length: 2, fixed: 6, title: "DUE DATE"},
+	"13":   {length: 2, fixed: 6, title: "PACK DATE"},
+	"15":   {length: 2, fixed: 6, title: "BEST BEFORE"},
+	"16":   {length: 2, fixed: 6, title: "SELL BY"},
+	"17":   {length: 2, fixed: 6, title: "USE BY OR EXPIRY"},
+	"20":   {length: 2, fixed: 2, title: "VARIANT"},
+	"21":   {length: 2, max: 20, title: "SERIAL"},
+	"22":   {length: 2, max: 20, title: "CPV"},
+	"30":   {length: 2, max: 8, title: "VAR. COUNT"},
+	"37":   {length: 2, max: 8, title: "COUNT"},
+	"240":  {length: 3, max: 30, title: "ADDITIONAL ID"},
+	"241":  {length: 3, max: 30, title: "CUST. PART No."},
+	"250":  {length: 3, max: 30, title: "SECONDARY SERIAL"},
+	"400":  {length: 3, max: 30, title: "ORDER NUMBER"},
+	"401":  {length: 3, max: 30, title: "GINC"},
+	"402":  {length: 3, fixed: 17, title: "GSIN"},
+	"410":  {length: 3, fixed: 13, title: "SHIP TO LOC"},
+	"411":  {length: 3, fixed: 13, title: "BILL TO"},
+	"412":  {length: 3, fixed: 13, title: "PURCHASE FROM"},
+	"413":  {length: 3, fixed: 13, title: "SHIP FOR LOC"},
+	"414":  {length: 3, fixed: 13, title: "LOC No."},
+	"415":  {length: 3, fixed: 13, title: "PAY TO"},
+	"420":  {length: 3, max: 20, title: "SHIP TO POST"},
+	"422":  {length: 3, fixed: 3, title: "ORIGIN"},
+	"7003": {length: 4, fixed: 10, title: "EXPIRY TIME"},
+	"8005": {length: 4, fixed: 6, title: "PRICE PER UNIT"},
+	"8020": {length: 4, max: 25, title: "REF No."},
+}
+
+func init() {
+	// Trade measures (310n-369n) are four-digit AIs with six digits of data
+	for prefix := 310; prefix <= 369; prefix++ {
+		gs1AIs[strconv.Itoa(prefix)] = gs1AI{length: 4, fixed: 6, title: "MEASURE"}
+	}
+	// Amounts (390n-393n) are variable length
+	for prefix := 390; prefix <= 393; prefix++ {
+		gs1AIs[strconv.Itoa(prefix)] = gs1AI{length: 4, max: 18, title: "AMOUNT"}
+	}
+}
+
+// GS1Data holds the parsed element string. Elements maps every AI to its raw value.
+type GS1Data struct {
+	GTIN     string
+	Lot      string
+	Serial   string
+	Expiry   *time.Time
+	Elements map[string]string
+}
+
+// LooksLikeGS1 reports whether a value is a GS1 element string, either in the
+// human readable "(01)...(10)..." form or as transmitted by a scanner with a
+// symbology identifier or FNC1 separators.
+func LooksLikeGS1(code string) bool {
+	if gs1Bracketed.MatchString(code) {
+		return true
+	}
+	for _, prefix := range gs1Symbologies {
+		if strings.HasPrefix(code, prefix) {
+			return true
+		}
+	}
+	return strings.Contains(code, GS1GroupSeparator)
+}
+
+// ParseGS1 parses a GS1 element string into its Application Identifiers
+func ParseGS1(code string) (*GS1Data, error) {
+	elements := make(map[string]string)
+	var order []string
+
+	add := func(ai, value string) error {
+		if _, dup := elements[ai]; dup {
+			return fmt.Errorf("AI (%s) appears more than once", ai)
+		}
+		elements[ai] = value
+		order = append(order, ai)
+		return nil
+	}
+
+	if gs1Bracketed.MatchString(code) {
+		for _, m := range gs1BracketedElement.FindAllStringSubmatch(code, -1) {
+			def, ok := lookupAI(m[1])
+			if !ok || def.length != len(m[1]) {
+				return nil, fmt.Errorf("unknown application identifier (%s)", m[1])
+			}
+			if err := checkAILength(m[1], def, m[2]); err != nil {
+				return nil, err
+			}
+			if err := add(m[1], m[2]); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		rest := code
+		for _, prefix := range gs1Symbologies {
+			rest = strings.TrimPrefix(rest, prefix)
+		}
+		rest = strings.TrimPrefix(rest, GS1GroupSeparator)
+
+		for rest != "" {
+			def, ok := lookupAI(rest)
+			if !ok {
+				return nil, fmt.Errorf("unknown application identifier at %q", truncate(rest, 4))
+			}
+			ai := rest[:def.length]
+			rest = rest[def.length:]
+
+			var value string
+			if def.fixed > 0 {
+				if len(rest) < def.fixed {
+					return nil, fmt.Errorf("AI (%s) needs %d characters, got %d", ai, def.fixed, len(rest))
+				}
+				value, rest = rest[:def.fixed], rest[def.fixed:]
+			} else if i := strings.Index(rest, GS1GroupSeparator); i >= 0 {
+				value, rest = rest[:i], rest[i:]
+			} else {
+				value, rest = rest, ""
+			}
+			rest = strings.TrimPrefix(rest, GS1GroupSeparator)
+
+			if err := checkAILength(ai, def, value); err != nil {
+				return nil, err
+			}
+			if err := add(ai, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(order) == 0 {
+		return nil, errors.New("contains no application identifiers")
+	}
+
+	data := &GS1Data{Elements: elements}
+	for _, ai := range order {
+		value := elements[ai]
+		switch ai {
+		case "00", "01", "02":
+			if err := verifyCheckDigit(value); err != nil {
+				return nil, fmt.Errorf("AI (%s) %w", ai, err)
+			}
+			if ai == "01" {
+				data.GTIN = value
+			}
+		case "10":
+			data.Lot = value
+		case "21":
+			data.Serial = value
+		case "11", "12", "13", "15", "16", "17":
+			date, err := parseGS1Date(value, time.Now())
+			if err != nil {
+				return nil, fmt.Errorf("AI (%s) %w", ai, err)
+			}
+			if ai == "17" {
+				data.Expiry = &date
+			}
+		}
+	}
+
+	return data, nil
+}
+
+func lookupAI(code string) (gs1AI, bool) {
+	for n := 2; n <= 4 && n <= len(code); n++ {
+		if def, ok := gs1AIs[code[:n]]; ok {
+			if len(code) < def.length || !isDigits(code[:def.length]) {
+				return gs1AI{}, false
+			}
+			return def, true
+		}
+	}
+	return gs1AI{}, false
+}
+
+func checkAILength(ai string, def gs1AI, value string) error {
+	if def.fixed > 0 && len(value) != def.fixed {
+		return fmt.Errorf("AI (%s) %s must be %d characters, got %d", ai, def.title, def.fixed, len(value))
+	}
+	if def.max > 0 && (len(value) == 0 || len(value) > def.max) {
+		return fmt.Errorf("AI (%s) %s must be 1-%d characters, got %d", ai, def.title, def.max, len(value))
+	}
+	return nil
+}
+
+// parseGS1Date parses YYMMDD; a day of 00 means the last day of the month.
+// The century follows the GS1 sliding window around now.
+func parseGS1Date(value string, now time.Time) (time.Time, error) {
+	if len(value) != 6 || !isDigits(value) {
+		return time.Time{}, errors.New("date must be YYMMDD")
+	}
+	year, _ := strconv.Atoi(value[0:2])
+	month, _ := strconv.Atoi(value[2:4])
+	day, _ := strconv.Atoi(value[4:6])
+	if month < 1 || month > 12 {
+		return time.Time{}, fmt.Errorf("invalid month in date %s", value)
+	}
+
+	t := time.Date(gs1Year(year, now), time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	if day == 0 {
+		return t.AddDate(0, 1, -1), nil
+	}
+	if day > t.AddDate(0, 1, -1).Day() {
+		return time.Time{}, fmt.Errorf("invalid day in date %s", value)
+	}
+	return t.AddDate(0, 0, day-1), nil
+}
+
+// gs1Year expands a two-digit year to the one at most 49 years before or 50
+// years after now, as the GS1 General Specifications require
+func gs1Year(yy int, now time.Time) int {
+	century := now.Year() / 100 * 100
+	switch diff := yy - now.Year()%100; {
+	case diff >= 51:
+		return century - 100 + yy
+	case diff <= -50:
+		return century + 100 + yy
+	}
+	return century + yy
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseGS1(t *testing.T) {
+	const gtin = "09506000134352"
+
+	tests := []struct {
+		name     string
+		code     string
+		gtin     string
+		lot      string
+		serial   string
+		expiry   string
+		elements map[string]string
+		err      string
+	}{
+		{
+			name: "bracketed", code: "(01)" + gtin + "(17)261231(10)LOT42",
+			gtin: gtin, lot: "LOT42", expiry: "2026-12-31",
+		},
+		{
+			name: "FNC1 separators", code: GS1GroupSeparator + "01" + gtin + "10LOT42" + GS1GroupSeparator + "21SER9",
+			gtin: gtin, lot: "LOT42", serial: "SER9",
+		},
+		{
+			name: "symbology identifier", code: "]C101" + gtin + "1726120010ABC",
+			gtin: gtin, lot: "ABC", expiry: "2026-12-31",
+		},
+		{
+			name: "DataMatrix identifier", code: "]d2" + "21SER9" + GS1GroupSeparator + "01" + gtin,
+			gtin: gtin, serial: "SER9",
+		},
+		{
+			name: "trade measure", code: "]C101" + gtin + "310300125010LOT",
+			gtin: gtin, lot: "LOT", elements: map[string]string{"3103": "001250"},
+		},
+		{
+			name: "bracketed trade measure", code: "(01)" + gtin + "(3922)1999",
+			gtin: gtin, elements: map[string]string{"3922": "1999"},
+		},
+		{name: "SSCC", code: "(00)009506000134350004", elements: map[string]string{"00": "009506000134350004"}},
+		{name: "duplicate AI", code: "(10)A(10)B", err: "more than once"},
+		{name: "duplicate AI with separators", code: "]C110A" + GS1GroupSeparator + "10B", err: "more than once"},
+		{name: "bad check digit", code: "(01)09506000134353", err: "check digit mismatch"},
+		{name: "bad SSCC check digit", code: "(00)009506000134350005", err: "check digit mismatch"},
+		{name: "invalid day", code: "(01)" + gtin + "(17)260231", err: "invalid day"},
+		{name: "invalid month", code: "(17)261301", err: "invalid month"},
+		{name: "short fixed element", code: "(01)0950600013435", err: "must be 14 characters"},
+		{name: "truncated fixed element", code: "]C1010950600013", err: "needs 14 characters"},
+		{name: "variable element too long", code: "(10)" + strings.Repeat("X", 21), err: "must be 1-20 characters"},
+		{name: "unknown AI", code: "(99)X", err: "unknown application identifier"},
+		{name: "unknown AI with separators", code: "]C199X", err: "unknown application identifier"},
+		{name: "no elements", code: "]C1", err: "no application identifiers"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := ParseGS1(tt.code)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data.GTIN != tt.gtin || data.Lot != tt.lot || data.Serial != tt.serial {
+				t.Fatalf("unexpected fields %+v", data)
+			}
+			expiry := ""
+			if data.Expiry != nil {
+				expiry = data.Expiry.Format("2006-01-02")
+			}
+			if expiry != tt.expiry {
+				t.Fatalf("expiry %q, want %q", expiry, tt.expiry)
+			}
+			for ai, value := range tt.elements {
+				if data.Elements[ai] != value {
+					t.Fatalf("AI (%s) = %q, want %q", ai, data.Elements[ai], value)
+				}
+			}
+		})
+	}
+}
+
+func TestLookupAI(t *testing.T) {
+	tests := []struct {
+		code   string
+		length int
+		ok     bool
+	}{
+		{"0109506000134352", 2, true},
+		{"10LOT", 2, true},
+		{"240X", 3, true},
+		{"3103001250", 4, true},
+		{"3929", 4, true},
+		{"7003", 4, true},
+		{"310", 0, false},
+		{"31A3", 0, false},
+		{"99", 0, false},
+		{"0", 0, false},
+	}
+	for _, tt := range tests {
+		def, ok := lookupAI(tt.code)
+		if ok != tt.ok || def.length != tt.length {
+			t.Fatalf("lookupAI(%q) = %d %v, want %d %v", tt.code, def.length, ok, tt.length, tt.ok)
+		}
+	}
+}
+
+func TestParseGS1Date(t *testing.T) {
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		now   time.Time
+		want  string
+	}{
+		{"261019", now, "2026-10-19"},
+		{"260200", now, "2026-02-28"},
+		{"240200", now, "2024-02-29"},
+		{"761231", now, "2076-12-31"},
+		{"770101", now, "1977-01-01"},
+		{"000101", now, "2000-01-01"},
+		// Near the end of a century the window reaches into the next one
+		{"300101", time.Date(2080, 1, 1, 0, 0, 0, 0, time.UTC), "2130-01-01"},
+		{"310101", time.Date(2080, 1, 1, 0, 0, 0, 0, time.UTC), "2031-01-01"},
+	}
+	for _, tt := range tests {
+		got, err := parseGS1Date(tt.value, tt.now)
+		if err != nil {
+			t.Fatalf("parseGS1Date(%s): %v", tt.value, err)
+		}
+		if got.Format("2006-01-02") != tt.want {
+			t.Fatalf("parseGS1Date(%s) = %s, want %s", tt.value, got.Format("2006-01-02"), tt.want)
+		}
+	}
+
+	for _, value := range []string{"26101", "2610AB", "261032", "261300"} {
+		if _, err := parseGS1Date(value, now); err == nil {
+			t.Fatalf("parseGS1Date(%s) accepted an invalid date", value)
+		}
+	}
+}
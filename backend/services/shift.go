@@ -0,0 +1,109 @@
+package services
+
+import (
+	"math"
+	"scandata/models"
+	"sort"
+	"time"
+)
+
+// ResolveShift returns the active shift covering t and the calendar date
+// that shift started on, so a night shift keeps a single date across midnight.
+// Shift clock times are read in loc.
+func ResolveShift(shifts []models.Shift, t time.Time, loc *time.Location) (*models.Shift, time.Time, bool) {
+	t = t.In(loc)
+	today := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+
+	// Check shifts starting today first, then ones carried over from yesterday
+	for _, date := range []time.Time{today, today.AddDate(0, 0, -1)} {
+		for i := range shifts {
+			shift := &shifts[i]
+			if !shift.IsActive || !shift.RunsOn(date.Weekday()) {
+				continue
+			}
+			start := shift.StartOn(date)
+			if !t.Before(start) && t.Before(start.Add(shift.Duration())) {
+				return shift, date, true
+			}
+		}
+	}
+	return nil, time.Time{}, false
+}
+
+// ShiftEvent is a scan reduced to what shift statistics need
+type ShiftEvent struct {
+	ShiftID   uint
+	ShiftDate time.Time
+	UserID    uint
+	ScannedAt time.Time
+	IsMatch   bool
+}
+
+type ShiftStats struct {
+	Sessions      int     `json:"sessions"`
+	Total         int64   `json:"total"`
+	Match         int64   `json:"match"`
+	NotMatch      int64   `json:"not_match"`
+	MismatchRate  float64 `json:"mismatch_rate"`
+	ScansPerHour  float64 `json:"scans_per_hour"`
+	IdleMinutes   float64 `json:"idle_minutes"`
+	MaxGapMinutes float64 `json:"max_gap_minutes"`
+}
+
+// SummarizeShift aggregates events belonging to a single shift.
+// A session is one shift date; scans per hour divide by the scheduled hours of
+// every session worked. Gaps between consecutive scans of the same user in the
+// same session longer than idleThreshold count as idle time.
+func SummarizeShift(events []ShiftEvent, duration, idleThreshold time.Duration) ShiftStats {
+	var stats ShiftStats
+
+	type sessionKey struct {
+		userID uint
+		date   int64
+	}
+	sessions := make(map[int64]struct{})
+	times := make(map[sessionKey][]time.Time)
+
+	for _, e := range events {
+		stats.Total++
+		if e.IsMatch {
+			stats.Match++
+		} else {
+			stats.NotMatch++
+		}
+		sessions[e.ShiftDate.Unix()] = struct{}{}
+		key := sessionKey{userID: e.UserID, date: e.ShiftDate.Unix()}
+		times[key] = append(times[key], e.ScannedAt)
+	}
+
+	var maxGap time.Duration
+	var idle time.Duration
+	for _, ts := range times {
+		sort.Slice(ts, func(a, b int) bool { return ts[a].Before(ts[b]) })
+		for i := 1; i < len(ts); i++ {
+			gap := ts[i].Sub(ts[i-1])
+			if gap > maxGap {
+				maxGap = gap
+			}
+			if gap > idleThreshold {
+				idle += gap
+			}
+		}
+	}
+
+	stats.Sessions = len(sessions)
+	stats.IdleMinutes = round2(idle.Minutes())
+	stats.MaxGapMinutes = round2(maxGap.Minutes())
+	if stats.Total > 0 {
+		stats.MismatchRate = round2(float64(stats.NotMatch) / float64(stats.Total) * 100)
+	}
+	if hours := float64(stats.Sessions) * duration.Hours(); hours > 0 {
+		stats.ScansPerHour = round2(float64(stats.Total) / hours)
+	}
+
+	return stats
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
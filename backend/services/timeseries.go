@@ -0,0 +1,180 @@
+package services
+
+import (
+	"sort"
+	"time"
+)
+
+type Interval string
+
+const (
+	IntervalHour  Interval = "hour"
+	IntervalDay   Interval = "day"
+	IntervalWeek  Interval = "week"
+	IntervalMonth Interval = "month"
+)
+
+// MaxTimeSeriesBuckets limits how many points a single series may contain
+const MaxTimeSeriesBuckets = 2000
+
+func ParseInterval(s string) (Interval, bool) {
+	switch Interval(s) {
+	case IntervalHour, IntervalDay, IntervalWeek, IntervalMonth:
+		return Interval(s), true
+	}
+	return "", false
+}
+
+// BucketStart returns the start of the bucket containing t.
+// Weeks start on Sunday, matching the weekly figures in the summary report.
+func (i Interval) BucketStart(t time.Time) time.Time {
+	switch i {
+	case IntervalHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case IntervalWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		return day.AddDate(0, 0, -int(day.Weekday()))
+	case IntervalMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+}
+
+// Next returns the start of the bucket following the one starting at t
+func (i Interval) Next(t time.Time) time.Time {
+	switch i {
+	case IntervalHour:
+		return t.Add(time.Hour)
+	case IntervalWeek:
+		return t.AddDate(0, 0, 7)
+	case IntervalMonth:
+		return t.AddDate(0, 1, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+// CountBuckets returns how many buckets cover [start, end)
+func (i Interval) CountBuckets(start, end time.Time) int {
+	count := 0
+	for b := i.BucketStart(start); b.Before(end); b = i.Next(b) {
+		count++
+		if count > MaxTimeSeriesBuckets {
+			break
+		}
+	}
+	return count
+}
+
+type TimeSeriesPoint struct {
+	Bucket        time.Time `json:"bucket"`
+	Total         int64     `json:"total"`
+	Match         int64     `json:"match"`
+	NotMatch      int64     `json:"not_match"`
+	DistinctUnits int64     `json:"distinct_units"`
+}
+
+type TimeSeries struct {
+	Key    string            `json:"key"`
+	Label  string            `json:"label"`
+	Points []TimeSeriesPoint `json:"points"`
+}
+
+// ScanSample is a single scan event reduced to what a time series needs
+type ScanSample struct {
+	ScannedAt  time.Time
+	Barcode    string
+	IsMatch    bool
+	GroupKey   string
+	GroupLabel string
+}
+
+// ZeroTimeSeries returns a series covering [start, end) with every bucket empty
+func ZeroTimeSeries(key, label string, start, end time.Time, interval Interval) TimeSeries {
+	series := TimeSeries{Key: key, Label: label, Points: []TimeSeriesPoint{}}
+	for b := interval.BucketStart(start); b.Before(end); b = interval.Next(b) {
+		series.Points = append(series.Points, TimeSeriesPoint{Bucket: b})
+	}
+	return series
+}
+
+// TimeSeriesBuilder buckets samples into one series per group key as they
+// arrive, so a report never holds every scan of its range at once. Every
+// series covers [start, end) with empty buckets filled with zeros.
+type TimeSeriesBuilder struct {
+	start    time.Time
+	interval Interval
+	template TimeSeries
+	index    map[int64]int
+	barcodes map[seenBarcode]struct{}
+	series   map[string]*TimeSeries
+}
+
+// seenBarcode marks a barcode already counted in a bucket of a series
+type seenBarcode struct {
+	key     string
+	bucket  int
+	barcode string
+}
+
+func NewTimeSeriesBuilder(start, end time.Time, interval Interval) *TimeSeriesBuilder {
+	template := ZeroTimeSeries("", "", start, end, interval)
+	index := make(map[int64]int, len(template.Points))
+	for i, p := range template.Points {
+		index[p.Bucket.Unix()] = i
+	}
+	return &TimeSeriesBuilder{
+		start:    start,
+		interval: interval,
+		template: template,
+		index:    index,
+		barcodes: make(map[seenBarcode]struct{}),
+		series:   make(map[string]*TimeSeries),
+	}
+}
+
+// Add counts a sample in its bucket; samples outside [start, end) are ignored
+func (b *TimeSeriesBuilder) Add(s ScanSample) {
+	i, ok := b.index[b.interval.BucketStart(s.ScannedAt.In(b.start.Location())).Unix()]
+	if !ok {
+		return
+	}
+
+	series, exists := b.series[s.GroupKey]
+	if !exists {
+		series = &TimeSeries{
+			Key:    s.GroupKey,
+			Label:  s.GroupLabel,
+			Points: append([]TimeSeriesPoint(nil), b.template.Points...),
+		}
+		b.series[s.GroupKey] = series
+	}
+
+	point := &series.Points[i]
+	point.Total++
+	if s.IsMatch {
+		point.Match++
+	} else {
+		point.NotMatch++
+	}
+	if _, ok := b.barcodes[seenBarcode{s.GroupKey, i, s.Barcode}]; !ok {
+		b.barcodes[seenBarcode{s.GroupKey, i, s.Barcode}] = struct{}{}
+		point.DistinctUnits++
+	}
+}
+
+// Series returns the series built so far ordered by label, then key
+func (b *TimeSeriesBuilder) Series() []TimeSeries {
+	result := make([]TimeSeries, 0, len(b.series))
+	for _, series := range b.series {
+		result = append(result, *series)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Label != result[j].Label {
+			return result[i].Label < result[j].Label
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeSeriesBuilder(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 3)
+	builder := NewTimeSeriesBuilder(start, end, IntervalDay)
+
+	at := func(day, hour int) time.Time { return start.AddDate(0, 0, day).Add(time.Duration(hour) * time.Hour) }
+	for _, s := range []ScanSample{
+		{ScannedAt: at(0, 8), Barcode: "A", IsMatch: true, GroupKey: "1", GroupLabel: "Budi"},
+		{ScannedAt: at(0, 9), Barcode: "A", IsMatch: false, GroupKey: "1", GroupLabel: "Budi"},
+		{ScannedAt: at(2, 9), Barcode: "B", IsMatch: true, GroupKey: "1", GroupLabel: "Budi"},
+		{ScannedAt: at(1, 9), Barcode: "A", IsMatch: true, GroupKey: "2", GroupLabel: "Ani"},
+		// Outside the range
+		{ScannedAt: at(3, 0), Barcode: "C", IsMatch: true, GroupKey: "3", GroupLabel: "Citra"},
+	} {
+		builder.Add(s)
+	}
+
+	series := builder.Series()
+	if len(series) != 2 || series[0].Label != "Ani" || series[1].Label != "Budi" {
+		t.Fatalf("unexpected series %+v", series)
+	}
+	budi := series[1].Points
+	if len(budi) != 3 {
+		t.Fatalf("got %d points, want one per day", len(budi))
+	}
+	if p := budi[0]; p.Total != 2 || p.Match != 1 || p.NotMatch != 1 || p.DistinctUnits != 1 {
+		t.Fatalf("unexpected first day %+v", p)
+	}
+	if p := budi[1]; p.Total != 0 {
+		t.Fatalf("empty day not zero: %+v", p)
+	}
+	if p := series[0].Points[1]; p.Total != 1 || p.DistinctUnits != 1 {
+		t.Fatalf("unexpected second day of the other series %+v", p)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build embedfrontend
+
+package web
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// frontend is a copy of ../frontend, made before the build (see the package
+// comment); go:embed cannot reach outside the module
+//
+//go:embed all:frontend
+var frontend embed.FS
+
+func init() {
+	Files, _ = fs.Sub(frontend, "frontend")
+}
@@ -0,0 +1,189 @@
+// Package web serves the frontend from the backend itself, for single
+// binary deployments without nginx or Caddy. The frontend is only embedded
+// when built with the embedfrontend tag (see embed.go):
+//
+//	mkdir -p web/frontend && cp -r ../frontend/index.html ../frontend/css ../frontend/js web/frontend/
+//	go build -tags embedfrontend
+package web
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"scandata/apierror"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Files is the embedded frontend directory, nil unless built with the
+// embedfrontend tag
+var Files fs.FS
+
+// ContentSecurityPolicy replaces the API policy of SecurityHeadersMiddleware
+// (default-src 'self') on frontend responses. The page loads its scanner and
+// chart libraries and fonts from CDNs and uses inline event handlers; the
+// camera stream of the scanner needs blob: and mediastream:.
+const ContentSecurityPolicy = "default-src 'self'; " +
+	"script-src 'self' 'unsafe-inline' https://unpkg.com https://cdn.jsdelivr.net; " +
+	"style-src 'self' 'unsafe-inline' https://fonts.googleapis.com; " +
+	"font-src 'self' https://fonts.gstatic.com; " +
+	"img-src 'self' data: blob:; " +
+	"media-src 'self' blob: mediastream:; " +
+	"connect-src 'self'; " +
+	"object-src 'none'; base-uri 'self'; form-action 'self'; frame-ancestors 'none'"
+
+// Cache policies: index.html is revalidated on every load and refers to the
+// other files with a ?v=<hash> query, so those can be cached for good
+const (
+	cacheRevalidate = "no-cache"
+	cacheImmutable  = "public, max-age=31536000, immutable"
+)
+
+// localRef matches the local href and src attributes of index.html
+var localRef = regexp.MustCompile(`(href|src)="([^":?#]+)"`)
+
+// Frontend serves the files of a frontend directory, held in memory
+type Frontend struct {
+	files map[string]*file
+}
+
+type file struct {
+	name    string
+	content []byte
+	gzipped []byte // nil when compressing does not pay off
+	hash    string
+}
+
+// New loads every file of files; index.html is required
+func New(files fs.FS) (*Frontend, error) {
+	f := &Frontend{files: make(map[string]*file)}
+	err := fs.WalkDir(files, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := fs.ReadFile(files, name)
+		if err != nil {
+			return err
+		}
+		f.files[name] = newFile(name, content)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	index, ok := f.files["index.html"]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	// Point index.html at the current version of each file. The links are
+	// made root-absolute, as the same page answers nested paths such as
+	// /scans/today, where a relative link would resolve to /scans/js/app.js.
+	content := localRef.ReplaceAllFunc(index.content, func(ref []byte) []byte {
+		m := localRef.FindSubmatch(ref)
+		name := strings.TrimPrefix(path.Clean("/"+string(m[2])), "/")
+		target, ok := f.files[name]
+		if !ok {
+			return ref
+		}
+		return []byte(string(m[1]) + `="/` + name + "?v=" + target.hash + `"`)
+	})
+	f.files["index.html"] = newFile("index.html", content)
+	return f, nil
+}
+
+func newFile(name string, content []byte) *file {
+	sum := sha256.Sum256(content)
+	f := &file{name: name, content: content, hash: hex.EncodeToString(sum[:8])}
+
+	if compressible(name) {
+		var buf bytes.Buffer
+		zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+		zw.Write(content)
+		zw.Close()
+		if buf.Len() < len(content) {
+			f.gzipped = buf.Bytes()
+		}
+	}
+	return f
+}
+
+func compressible(name string) bool {
+	switch path.Ext(name) {
+	case ".html", ".css", ".js", ".json", ".svg", ".txt", ".webmanifest":
+		return true
+	}
+	return false
+}
+
+// Serve answers requests no route matched: GET and HEAD requests outside
+// the API get the file of their path, or index.html for paths without an
+// extension so the page can route them itself. Everything else answers in
+// the API error format, like apierror.NoRoute.
+func (f *Frontend) Serve(c *gin.Context) {
+	p := c.Request.URL.Path
+	if (c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead) || isBackendPath(p) {
+		apierror.NoRoute(c)
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean(p), "/")
+	target, ok := f.files[name]
+	switch {
+	case ok && name != "index.html":
+	case name == "" || name == "index.html" || path.Ext(name) == "":
+		target = f.files["index.html"]
+	default:
+		apierror.NoRoute(c)
+		return
+	}
+
+	h := c.Writer.Header()
+	h.Set("Content-Security-Policy", ContentSecurityPolicy)
+	if target.name != "index.html" && c.Query("v") == target.hash {
+		h.Set("Cache-Control", cacheImmutable)
+	} else {
+		h.Set("Cache-Control", cacheRevalidate)
+	}
+	if ct := mime.TypeByExtension(path.Ext(target.name)); ct != "" {
+		h.Set("Content-Type", ct)
+	}
+
+	content, etag := target.content, `"`+target.hash+`"`
+	if target.gzipped != nil {
+		h.Add("Vary", "Accept-Encoding")
+		if acceptsGzip(c.GetHeader("Accept-Encoding")) {
+			content, etag = target.gzipped, `"`+target.hash+`-gz"`
+			h.Set("Content-Encoding", "gzip")
+		}
+	}
+	h.Set("ETag", etag)
+	http.ServeContent(c.Writer, c.Request, target.name, time.Time{}, bytes.NewReader(content))
+}
+
+func isBackendPath(p string) bool {
+	for _, prefix := range []string{"/api", "/health", "/metrics"} {
+		if p == prefix || strings.HasPrefix(p, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			return strings.ReplaceAll(strings.TrimSpace(params), " ", "") != "q=0"
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package web
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestFrontend(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	script := strings.Repeat("console.log('scan');\n", 50)
+	frontend, err := New(fstest.MapFS{
+		"index.html":     {Data: []byte(`<link href="css/styles.css"><script src="https://cdn.example/lib.js"></script><script src="js/app.js"></script>`)},
+		"css/styles.css": {Data: []byte("body{}")},
+		"js/app.js":      {Data: []byte(script)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.NoRoute(frontend.Serve)
+
+	get := func(path string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// index.html links the current version of each local file
+	w := get("/")
+	appJS := frontend.files["js/app.js"]
+	if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != cacheRevalidate ||
+		!strings.Contains(w.Body.String(), `src="/js/app.js?v=`+appJS.hash+`"`) ||
+		!strings.Contains(w.Body.String(), `src="https://cdn.example/lib.js"`) {
+		t.Fatalf("unexpected index: %d %q %s", w.Code, w.Header().Get("Cache-Control"), w.Body.String())
+	}
+	if w.Header().Get("Content-Security-Policy") != ContentSecurityPolicy {
+		t.Fatalf("unexpected CSP %q", w.Header().Get("Content-Security-Policy"))
+	}
+
+	// Paths of the page itself fall back to index.html, missing files do not
+	w = get("/scans/today")
+	if w.Code != http.StatusOK {
+		t.Fatalf("no SPA fallback: %d", w.Code)
+	}
+	// and their links resolve to the files, not below the nested path
+	m := regexp.MustCompile(`src="([^"]*app\.js[^"]*)"`).FindStringSubmatch(w.Body.String())
+	if m == nil {
+		t.Fatalf("no app.js in fallback: %s", w.Body.String())
+	}
+	asset, err := url.Parse("/scans/today")
+	if err != nil {
+		t.Fatal(err)
+	}
+	asset, err = asset.Parse(m[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := get(asset.RequestURI()); w.Code != http.StatusOK || w.Body.String() != script {
+		t.Fatalf("%s from the fallback: %d", asset.RequestURI(), w.Code)
+	}
+	for _, path := range []string{"/js/missing.js", "/api/v1/nope", "/health/nope"} {
+		if w := get(path); w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "route_not_found") {
+			t.Fatalf("%s: %d %s", path, w.Code, w.Body.String())
+		}
+	}
+
+	// Versioned files are cached for good, others revalidated by ETag
+	w = get("/js/app.js?v=" + appJS.hash)
+	if w.Header().Get("Cache-Control") != cacheImmutable || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/javascript") || w.Body.String() != script {
+		t.Fatalf("unexpected versioned file: %q %q", w.Header().Get("Cache-Control"), w.Header().Get("Content-Type"))
+	}
+	w = get("/js/app.js")
+	if w.Header().Get("Cache-Control") != cacheRevalidate {
+		t.Fatalf("unexpected Cache-Control %q", w.Header().Get("Cache-Control"))
+	}
+	if w := get("/js/app.js", "If-None-Match", w.Header().Get("ETag")); w.Code != http.StatusNotModified {
+		t.Fatalf("expected 304, got %d", w.Code)
+	}
+
+	// Compressed when the client accepts gzip and it pays off
+	w = get("/js/app.js", "Accept-Encoding", "br, gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatal("not compressed")
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(zr); string(body) != script {
+		t.Fatal("compressed body differs")
+	}
+	if w := get("/css/styles.css", "Accept-Encoding", "gzip"); w.Header().Get("Content-Encoding") != "" {
+		t.Fatal("tiny file compressed")
+	}
+}
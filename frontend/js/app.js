@@ -1,8 +1,8 @@
 // ===== Configuration =====
 // Use relative URL when served via nginx (Docker), otherwise use localhost for dev
 const API_BASE = window.location.hostname === 'localhost' && window.location.port === '3000'
-    ? 'http://localhost:8080/api'
-    : '/api';
+    ? 'http://localhost:8080/api/v1'
+    : '/api/v1';
 let currentUser = null;
 let token = null;
 let qrScanner = null;
@@ -79,7 +79,8 @@ function setupEventListeners() {
     document.getElementById('btnTidakSesuai')?.addEventListener('click', () => submitScan(false));
 
     // History filter
-    document.getElementById('btnFilterHistory')?.addEventListener('click', loadHistory);
+    document.getElementById('btnFilterHistory')?.addEventListener('click', () => loadHistory());
+    document.getElementById('btnLoadMoreHistory')?.addEventListener('click', () => loadHistory(historyCursor));
 
     // Add unit button
     document.getElementById('btnAddUnit')?.addEventListener('click', showAddUnitModal);
@@ -452,20 +453,25 @@ async function submitScan(isMatch) {
 }
 
 // ===== History =====
-async function loadHistory() {
+// Cursor of the next page of the current filter, null on the last page
+let historyCursor = null;
+
+// loadHistory shows the first page of the filter, or appends the page at cursor
+async function loadHistory(cursor) {
     const date = document.getElementById('filterDate').value;
     const isMatch = document.getElementById('filterMatch').value;
 
     let params = new URLSearchParams();
     if (date) params.append('date', date);
     if (isMatch) params.append('is_match', isMatch);
+    if (cursor) params.append('cursor', cursor);
 
     try {
         const response = await apiFetch(`/scans?${params.toString()}`);
         const scans = await response.json();
 
         const tbody = document.getElementById('historyTableBody');
-        tbody.innerHTML = scans.map(scan => `
+        const rows = scans.map(scan => `
             <tr>
                 <td>${formatDateTime(scan.scanned_at)}</td>
                 <td>${scan.barcode || '-'}</td>
@@ -478,6 +484,14 @@ async function loadHistory() {
                 <td>${scan.notes || '-'}</td>
             </tr>
         `).join('');
+        if (cursor) {
+            tbody.insertAdjacentHTML('beforeend', rows);
+        } else {
+            tbody.innerHTML = rows;
+        }
+
+        historyCursor = response.headers.get('X-Next-Cursor');
+        document.getElementById('btnLoadMoreHistory').classList.toggle('hidden', !historyCursor);
 
     } catch (error) {
         console.error('Error loading history:', error);